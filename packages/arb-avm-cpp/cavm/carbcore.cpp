@@ -243,11 +243,13 @@ ByteSliceArrayResult arbCoreGetMessages(CArbCore* arbcore_ptr,
 
 ByteSliceArrayResult arbCoreGetSequencerBatchItems(
     CArbCore* arbcore_ptr,
-    const void* start_index_ptr) {
+    const void* start_index_ptr,
+    uint64_t max_count) {
     try {
         auto messages =
             static_cast<const ArbCore*>(arbcore_ptr)
-                ->getSequencerBatchItems(receiveUint256(start_index_ptr));
+                ->getSequencerBatchItems(receiveUint256(start_index_ptr),
+                                         max_count);
         if (!messages.status.ok()) {
             return {{}, false};
         }
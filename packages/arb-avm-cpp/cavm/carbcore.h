@@ -61,7 +61,8 @@ ByteSliceArrayResult arbCoreGetMessages(CArbCore* arbcore_ptr,
                                         const void* count_ptr);
 
 ByteSliceArrayResult arbCoreGetSequencerBatchItems(CArbCore* arbcore_ptr,
-                                                   const void* start_index_ptr);
+                                                   const void* start_index_ptr,
+                                                   uint64_t max_count);
 
 Uint256Result arbCoreGetSequencerBlockNumberAt(CArbCore* arbcore_ptr,
                                                const void* seq_num_ptr);
@@ -15,6 +15,7 @@
  */
 
 #include "carbstorage.h"
+#include "utils.hpp"
 
 #include <data_storage/aggregator.hpp>
 #include <data_storage/arbstorage.hpp>
@@ -164,6 +165,27 @@ int cleanupValidator(CArbStorage* storage_ptr) {
     }
 }
 
+ByteSliceResult arbStorageGetValue(CArbStorage* storage_ptr,
+                                   const void* hash_ptr) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    auto hash = receiveUint256(hash_ptr);
+    try {
+        ValueCache cache{1, 0};
+        auto result = storage->getValue(hash, cache);
+        if (std::holds_alternative<rocksdb::Status>(result)) {
+            return {{}, false};
+        }
+        std::vector<unsigned char> data;
+        marshal_value(std::get<CountedData<Value>>(result).data, data,
+                      nullptr);
+        return {returnCharVector(data), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Exception loading value " << hash << ": " << e.what()
+                  << std::endl;
+        return {{}, false};
+    }
+}
+
 int arbStorageInitialized(CArbStorage* storage_ptr) {
     return static_cast<ArbStorage*>(storage_ptr)->initialized();
 }
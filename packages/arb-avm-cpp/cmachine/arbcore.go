@@ -212,7 +212,7 @@ func unmarshalLog(marshaled []byte) (core.ValueAndInbox, error) {
 	var inboxAccumulator common.Hash
 	copy(inboxAccumulator[:], inboxData[32:])
 
-	val, err := value.UnmarshalValue(reader)
+	val, err := value.UnmarshalValueWithConfig(reader, value.DefaultDecoderConfig)
 	if err != nil {
 		return core.ValueAndInbox{}, err
 	}
@@ -274,10 +274,14 @@ func (ac *ArbCore) GetMessages(startIndex *big.Int, count *big.Int) ([]inbox.Inb
 }
 
 func (ac *ArbCore) GetSequencerBatchItems(startIndex *big.Int) ([]inbox.SequencerBatchItem, error) {
+	return ac.GetSequencerBatchItemsLimited(startIndex, math.MaxUint64)
+}
+
+func (ac *ArbCore) GetSequencerBatchItemsLimited(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error) {
 	defer runtime.KeepAlive(ac)
 	startIndexData := math.U256Bytes(startIndex)
 
-	result := C.arbCoreGetSequencerBatchItems(ac.c, unsafeDataPointer(startIndexData))
+	result := C.arbCoreGetSequencerBatchItems(ac.c, unsafeDataPointer(startIndexData), C.uint64_t(maxCount))
 	if result.found == 0 {
 		return nil, errors.New("failed to get messages")
 	}
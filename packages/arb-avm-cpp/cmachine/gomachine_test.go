@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmachine
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gomachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gotest"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// Limit on the number of instructions stepped through in each opcode test
+const maxDifferentialSteps = 1000000
+
+func compareValues(t *testing.T, kind string, cVals, goVals []value.Value) {
+	t.Helper()
+	if len(cVals) != len(goVals) {
+		t.Fatalf("C++ machine produced %v %v but Go machine produced %v", len(cVals), kind, len(goVals))
+	}
+	// Codepoints come back from the C++ machine as stubs, so compare by hash
+	for i := range cVals {
+		if cVals[i].Hash() != goVals[i].Hash() {
+			t.Fatalf("%v %v differs: C++ %v, Go %v", kind, i, cVals[i], goVals[i])
+		}
+	}
+}
+
+func compareAssertions(t *testing.T, cAssertion, goAssertion *protocol.ExecutionAssertion) {
+	t.Helper()
+	if cAssertion.NumGas != goAssertion.NumGas {
+		t.Fatalf("C++ machine used %v gas but Go machine used %v", cAssertion.NumGas, goAssertion.NumGas)
+	}
+	if cAssertion.InboxMessagesConsumed != goAssertion.InboxMessagesConsumed {
+		t.Fatalf(
+			"C++ machine read %v messages but Go machine read %v",
+			cAssertion.InboxMessagesConsumed,
+			goAssertion.InboxMessagesConsumed,
+		)
+	}
+	if len(cAssertion.Sends) != len(goAssertion.Sends) {
+		t.Fatalf("C++ machine produced %v sends but Go machine produced %v", len(cAssertion.Sends), len(goAssertion.Sends))
+	}
+	for i := range cAssertion.Sends {
+		if !bytes.Equal(cAssertion.Sends[i], goAssertion.Sends[i]) {
+			t.Fatalf("send %v differs: C++ %x, Go %x", i, cAssertion.Sends[i], goAssertion.Sends[i])
+		}
+	}
+	compareValues(t, "logs", cAssertion.Logs, goAssertion.Logs)
+}
+
+func compareMachines(t *testing.T, step int, cMach machine.Machine, goMach machine.Machine) {
+	t.Helper()
+	if cMach.Hash() != goMach.Hash() {
+		t.Fatalf("machines differ after %v steps\nC++: %v\nGo: %v", step, cMach, goMach)
+	}
+	if cMach.CurrentStatus() != goMach.CurrentStatus() {
+		t.Fatalf("C++ machine has status %v but Go machine has %v", cMach.CurrentStatus(), goMach.CurrentStatus())
+	}
+	if cMach.CurrentStatus() != machine.Extensive {
+		return
+	}
+	cProof, cBufferProof, err := cMach.MarshalForProof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	goProof, goBufferProof, err := goMach.MarshalForProof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cProof, goProof) {
+		t.Fatalf("proofs differ after %v steps\nC++: %x\nGo: %x", step, cProof, goProof)
+	}
+	if !bytes.Equal(cBufferProof, goBufferProof) {
+		t.Fatalf("buffer proofs differ after %v steps\nC++: %x\nGo: %x", step, cBufferProof, goBufferProof)
+	}
+}
+
+// TestGoMachineOpcodeVectors steps the C++ and Go machines through each
+// opcode test one instruction at a time, checking that their hashes,
+// assertions and proofs agree after every step
+func TestGoMachineOpcodeVectors(t *testing.T) {
+	files, err := gotest.OpCodeTestFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			cMach, err := New(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			goMach, err := gomachine.New(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for step := 0; step < maxDifferentialSteps; step++ {
+				compareMachines(t, step, cMach, goMach)
+				if cMach.IsBlocked(false) != nil {
+					if goMach.IsBlocked(false) == nil {
+						t.Fatal("C++ machine is blocked but Go machine isn't")
+					}
+					return
+				}
+
+				cAssertion, cDebugPrints, cSteps, err := cMach.ExecuteAssertion(ctx, 1, true, nil, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				goAssertion, goDebugPrints, goSteps, err := goMach.ExecuteAssertion(ctx, 1, true, nil, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				compareAssertions(t, cAssertion, goAssertion)
+				compareValues(t, "debug prints", cDebugPrints, goDebugPrints)
+				if cSteps != goSteps {
+					t.Fatalf("C++ machine ran %v steps but Go machine ran %v", cSteps, goSteps)
+				}
+				if cSteps == 0 {
+					return
+				}
+			}
+		})
+	}
+}
+
+// TestGoMachineArbOSVectors runs ArbOS on each test vector's inbox with both
+// machines, checking that they agree with each other and with the vector
+func TestGoMachineArbOSVectors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping ArbOS differential test in short mode")
+	}
+	arbosFile, err := gotest.ArbOSFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(arbosFile); err != nil {
+		t.Skip("ArbOS isn't built:", err)
+	}
+	files, err := gotest.ArbOSTestFiles()
+	if err != nil {
+		t.Skip("no ArbOS test vectors:", err)
+	}
+	ctx := context.Background()
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".json") {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, logs, sends, err := inbox.LoadTestVector(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cMach, err := New(arbosFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			goMach, err := gomachine.New(arbosFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cAssertion, _, cSteps, err := cMach.ExecuteAssertion(ctx, 0, false, messages, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			goAssertion, _, goSteps, err := goMach.ExecuteAssertion(ctx, 0, false, messages, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareAssertions(t, cAssertion, goAssertion)
+			if cSteps != goSteps {
+				t.Fatalf("C++ machine ran %v steps but Go machine ran %v", cSteps, goSteps)
+			}
+			compareMachines(t, int(cSteps), cMach, goMach)
+
+			compareValues(t, "expected logs", logs, goAssertion.Logs)
+			if len(sends) != len(goAssertion.Sends) {
+				t.Fatalf("expected %v sends but Go machine produced %v", len(sends), len(goAssertion.Sends))
+			}
+			for i := range sends {
+				if !bytes.Equal(sends[i], goAssertion.Sends[i]) {
+					t.Fatalf("send %v differs from test vector", i)
+				}
+			}
+		})
+	}
+}
@@ -23,14 +23,17 @@ package cmachine
 */
 import "C"
 import (
+	"bytes"
 	"runtime"
 	"unsafe"
 
 	"github.com/pkg/errors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
 )
 
 type ArbStorage struct {
@@ -184,6 +187,18 @@ func (s *ArbStorage) GetArbCore() core.ArbCore {
 	return NewArbCore(ac, s)
 }
 
+// GetValue loads the value with the given hash from the database. Values are
+// saved as part of machine checkpoints, so this can resolve the stubs in a
+// marshaled machine.
+func (s *ArbStorage) GetValue(hash common.Hash) (value.Value, error) {
+	defer runtime.KeepAlive(s)
+	res := C.arbStorageGetValue(s.c, unsafeDataPointer(hash.Bytes()))
+	if res.found == 0 {
+		return nil, &machine.ValueNotFoundError{HashValue: hash}
+	}
+	return value.UnmarshalValue(bytes.NewReader(receiveByteSlice(res.slice)))
+}
+
 func (s *ArbStorage) GetNodeStore() machine.NodeStore {
 	defer runtime.KeepAlive(s)
 	as := C.createAggregatorStore(s.c)
@@ -425,7 +425,7 @@ class ArbCore {
     [[nodiscard]] ValueResult<std::vector<std::vector<unsigned char>>>
     getMessages(uint256_t index, uint256_t count) const;
     [[nodiscard]] ValueResult<std::vector<std::vector<unsigned char>>>
-    getSequencerBatchItems(uint256_t index) const;
+    getSequencerBatchItems(uint256_t index, uint64_t max_count) const;
     [[nodiscard]] ValueResult<uint256_t> getSequencerBlockNumberAt(
         uint256_t sequence_number) const;
     [[nodiscard]] ValueResult<std::vector<unsigned char>> genInboxProof(
@@ -2181,7 +2181,7 @@ rocksdb::Status ArbCore::saveSends(
 }
 
 ValueResult<std::vector<std::vector<unsigned char>>>
-ArbCore::getSequencerBatchItems(uint256_t index) const {
+ArbCore::getSequencerBatchItems(uint256_t index, uint64_t max_count) const {
     ReadTransaction tx(data_storage);
 
     std::vector<unsigned char> first_key_vec;
@@ -2191,7 +2191,7 @@ ArbCore::getSequencerBatchItems(uint256_t index) const {
     it->Seek(first_key_slice);
 
     std::vector<std::vector<unsigned char>> ret;
-    while (it->Valid()) {
+    while (it->Valid() && ret.size() < max_count) {
         auto key_ptr = reinterpret_cast<const unsigned char*>(it->key().data());
         auto value_ptr =
             reinterpret_cast<const unsigned char*>(it->value().data());
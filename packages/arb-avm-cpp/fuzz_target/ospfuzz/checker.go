@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gomachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/proofmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// MaxSteps bounds how long a program runs, since jumps to codepoints pushed
+// by pcpush can loop forever
+const MaxSteps = 2000
+
+// Failure describes a step whose proof was rejected
+type Failure struct {
+	Step   uint64   `json:"step"`
+	Opcode uint8    `json:"opcode"`
+	Errors []string `json:"errors"`
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf(
+		"proof of step %v (%v 0x%x) failed: %v",
+		f.Step,
+		gomachine.OpcodeName(value.Opcode(f.Opcode)),
+		f.Opcode,
+		strings.Join(f.Errors, "; "),
+	)
+}
+
+// Checker runs programs on the C++ machine, checking the proof of every step
+// against the one step proof contracts
+type Checker struct {
+	proofChecker *proofmachine.ProofChecker
+}
+
+func NewChecker(proofChecker *proofmachine.ProofChecker) *Checker {
+	return &Checker{proofChecker: proofChecker}
+}
+
+type stepState struct {
+	gasUsed      uint64
+	messagesRead uint64
+	sendAcc      common.Hash
+	logAcc       common.Hash
+}
+
+func (s stepState) executionState(machineHash common.Hash) *core.ExecutionState {
+	return &core.ExecutionState{
+		MachineHash:       machineHash,
+		TotalMessagesRead: new(big.Int).SetUint64(s.messagesRead),
+		TotalGasConsumed:  new(big.Int).SetUint64(s.gasUsed),
+		SendAcc:           s.sendAcc,
+		LogAcc:            s.logAcc,
+	}
+}
+
+// Check runs p one step at a time until the machine blocks or MaxSteps is
+// reached, returning the first step whose proof is rejected, or nil if they
+// were all accepted.
+//
+// Inbox instructions are executed but their proofs aren't checked. The one
+// step proof of an inbox instruction proves the message against the bridge
+// contracts, which a standalone program's inbox was never delivered to.
+func (c *Checker) Check(ctx context.Context, p *Program) (*Failure, error) {
+	file, err := ioutil.TempFile("", "ospfuzz-*.mexe")
+	if err != nil {
+		return nil, err
+	}
+	filename := file.Name()
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	defer os.Remove(filename)
+	if err := p.WriteExecutable(filename); err != nil {
+		return nil, err
+	}
+	mach, err := cmachine.New(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var state stepState
+	for step := uint64(0); step < MaxSteps; step++ {
+		beforeHash := mach.Hash()
+		proof, bufferProof, err := mach.MarshalForProof()
+		if err != nil {
+			return nil, err
+		}
+		assertion, _, steps, err := mach.ExecuteAssertion(ctx, 1, true, p.Inbox[state.messagesRead:], false)
+		if err != nil {
+			return nil, err
+		}
+		if steps == 0 {
+			return nil, nil
+		}
+
+		before := state.executionState(beforeHash)
+		state.gasUsed += assertion.NumGas
+		state.messagesRead += assertion.InboxMessagesConsumed
+		for _, send := range assertion.Sends {
+			state.sendAcc = hashing.SoliditySHA3(hashing.Bytes32(state.sendAcc), hashing.Bytes32(hashing.SoliditySHA3(send)))
+		}
+		for _, avmLog := range assertion.Logs {
+			state.logAcc = hashing.SoliditySHA3(hashing.Bytes32(state.logAcc), hashing.Bytes32(avmLog.Hash()))
+		}
+
+		if value.Opcode(proof[0]) == gomachine.INBOX {
+			continue
+		}
+		proofErrors := c.proofChecker.CheckProof(&proofmachine.ProofData{
+			Assertion: &core.Assertion{
+				Before: before,
+				After:  state.executionState(mach.Hash()),
+			},
+			Proof:       proof,
+			BufferProof: bufferProof,
+		})
+		if len(proofErrors) > 0 {
+			failure := &Failure{Step: step, Opcode: proof[0]}
+			for _, err := range proofErrors {
+				failure.Errors = append(failure.Errors, err.Error())
+			}
+			return failure, nil
+		}
+	}
+	return nil, nil
+}
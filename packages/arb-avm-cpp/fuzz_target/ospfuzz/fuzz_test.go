@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+import (
+	"testing"
+)
+
+// FuzzOneStepProofs checks the proof of every step of generated programs. Run
+// it with "go test -run XXX -fuzz FuzzOneStepProofs". Failing cases are
+// minimized and saved to testdata/vectors.
+func FuzzOneStepProofs(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{16, 2, 0x00, 0x10, 0x20, 0x30, 0x40, 0x50, 0x60, 0x70, 0x80, 0x90, 0xa0, 0xb0, 0xc0, 0xd0, 0xe0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkProgram(t, GenerateProgram(data), true)
+	})
+}
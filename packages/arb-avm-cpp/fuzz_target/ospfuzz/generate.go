@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gomachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+const (
+	maxInstructions  = 64
+	maxInboxMessages = 4
+	maxValueDepth    = 3
+	maxBufferLength  = 96
+)
+
+// validOpcodes lists every instruction, so that most generated opcodes are
+// valid and all three provers get exercised
+var validOpcodes []uint8
+
+func init() {
+	for op := 0; op < 256; op++ {
+		if gomachine.IsValidOpcode(value.Opcode(op)) {
+			validOpcodes = append(validOpcodes, uint8(op))
+		}
+	}
+}
+
+// Ints around the boundaries which signed and unsigned arithmetic treat
+// specially
+var interestingInts = []*big.Int{
+	big.NewInt(0),
+	big.NewInt(1),
+	big.NewInt(31),
+	big.NewInt(32),
+	big.NewInt(1024),
+	new(big.Int).Sub(math.BigPow(2, 64), big.NewInt(1)),
+	math.BigPow(2, 64),
+	math.BigPow(2, 255),
+	new(big.Int).Sub(math.BigPow(2, 255), big.NewInt(1)),
+	math.MaxBig256,
+}
+
+// generator builds a program from fuzzer input. Once the input runs out every
+// read returns zero, so any input produces a valid program.
+type generator struct {
+	data []byte
+}
+
+func (g *generator) byte() uint8 {
+	if len(g.data) == 0 {
+		return 0
+	}
+	b := g.data[0]
+	g.data = g.data[1:]
+	return b
+}
+
+func (g *generator) bytes(n int) []byte {
+	ret := make([]byte, n)
+	copied := copy(ret, g.data)
+	g.data = g.data[copied:]
+	return ret
+}
+
+func (g *generator) uint64() uint64 {
+	return binary.BigEndian.Uint64(g.bytes(8))
+}
+
+func (g *generator) bigInt() *big.Int {
+	switch g.byte() % 4 {
+	case 0:
+		return big.NewInt(int64(g.byte()))
+	case 1:
+		return new(big.Int).SetUint64(g.uint64())
+	case 2:
+		return new(big.Int).SetBytes(g.bytes(32))
+	default:
+		return interestingInts[int(g.byte())%len(interestingInts)]
+	}
+}
+
+// value generates a value whose codepoints reference instructions in the
+// range [minLabel, maxLabel]
+func (g *generator) value(depth int, minLabel, maxLabel uint64) Value {
+	switch g.byte() % 8 {
+	case 4, 5:
+		if depth < maxValueDepth {
+			size := int(g.byte() % 9)
+			items := make([]Value, 0, size)
+			for i := 0; i < size; i++ {
+				items = append(items, g.value(depth+1, minLabel, maxLabel))
+			}
+			return Value{Tuple: &items}
+		}
+	case 6:
+		data := hex.EncodeToString(g.bytes(int(g.byte()) % maxBufferLength))
+		return Value{Buffer: &data}
+	case 7:
+		label := minLabel + uint64(g.byte())%(maxLabel-minLabel+1)
+		return Value{CodePoint: &CodePointLabel{Internal: label}}
+	}
+	intStr := g.bigInt().Text(16)
+	return Value{Int: &intStr}
+}
+
+func (g *generator) opcode() uint8 {
+	b := g.byte()
+	if b < 0x40 {
+		// A nop with an immediate pushes it, which gives the instructions
+		// that follow something to work on
+		return uint8(gomachine.NOP)
+	}
+	if b >= 0xf0 {
+		// Occasionally generate arbitrary opcodes, which may be invalid
+		return g.byte()
+	}
+	return validOpcodes[int(b)%len(validOpcodes)]
+}
+
+func (g *generator) inboxMessage(seqNum int64) inbox.InboxMessage {
+	var sender common.Address
+	copy(sender[:], g.bytes(20))
+	return inbox.InboxMessage{
+		Kind:        inbox.Type(g.byte()),
+		Sender:      sender,
+		InboxSeqNum: big.NewInt(seqNum),
+		GasPrice:    new(big.Int).SetUint64(g.uint64()),
+		Data:        g.bytes(int(g.byte()) % maxBufferLength),
+		ChainTime: inbox.ChainTime{
+			BlockNum:  common.NewTimeBlocks(new(big.Int).SetUint64(g.uint64())),
+			Timestamp: new(big.Int).SetUint64(g.uint64()),
+		},
+	}
+}
+
+// GenerateProgram deterministically builds a program and inbox from data
+func GenerateProgram(data []byte) *Program {
+	g := &generator{data: data}
+	codeLength := 1 + int(g.byte())%maxInstructions
+	messageCount := int(g.byte()) % (maxInboxMessages + 1)
+
+	code := make([]Instruction, 0, codeLength)
+	for i := 0; i < codeLength; i++ {
+		insn := Instruction{Opcode: g.opcode()}
+		// Other instructions get an immediate less often, since they'd
+		// otherwise never see values computed by earlier instructions
+		if insn.Opcode == uint8(gomachine.NOP) || g.byte()%4 == 0 {
+			imm := g.value(0, uint64(i+1), uint64(codeLength))
+			insn.Immediate = &imm
+		}
+		code = append(code, insn)
+	}
+	messages := make([]inbox.InboxMessage, 0, messageCount)
+	for i := 0; i < messageCount; i++ {
+		messages = append(messages, g.inboxMessage(int64(i)))
+	}
+	return &Program{
+		Code:      code,
+		StaticVal: g.value(0, 0, uint64(codeLength)),
+		Inbox:     messages,
+	}
+}
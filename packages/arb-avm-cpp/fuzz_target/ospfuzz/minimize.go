@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+func (p *Program) removeInstruction(index int) *Program {
+	ret := p.Clone()
+	ret.Code = append(ret.Code[:index], ret.Code[index+1:]...)
+	for _, insn := range ret.Code {
+		if insn.Immediate != nil {
+			insn.Immediate.relabel(uint64(index))
+		}
+	}
+	ret.StaticVal.relabel(uint64(index))
+	return ret
+}
+
+func (p *Program) removeImmediate(index int) *Program {
+	ret := p.Clone()
+	ret.Code[index].Immediate = nil
+	return ret
+}
+
+func (p *Program) removeMessage(index int) *Program {
+	ret := p.Clone()
+	ret.Inbox = append(ret.Inbox[:index], ret.Inbox[index+1:]...)
+	return ret
+}
+
+func (p *Program) clearStaticVal() *Program {
+	ret := p.Clone()
+	ret.StaticVal = Value{Tuple: &[]Value{}}
+	return ret
+}
+
+// Minimize shrinks p while fails keeps reporting that it fails. It tries
+// removing each instruction, immediate and inbox message in turn, along with
+// the static value, until no single removal preserves the failure.
+func Minimize(p *Program, fails func(*Program) bool) *Program {
+	for {
+		var candidates []*Program
+		for i := range p.Code {
+			candidates = append(candidates, p.removeInstruction(i))
+			if p.Code[i].Immediate != nil {
+				candidates = append(candidates, p.removeImmediate(i))
+			}
+		}
+		for i := range p.Inbox {
+			candidates = append(candidates, p.removeMessage(i))
+		}
+		if p.StaticVal.Tuple == nil || len(*p.StaticVal.Tuple) > 0 {
+			candidates = append(candidates, p.clearStaticVal())
+		}
+
+		shrunk := false
+		for _, candidate := range candidates {
+			if fails(candidate) {
+				p = candidate
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			return p
+		}
+	}
+}
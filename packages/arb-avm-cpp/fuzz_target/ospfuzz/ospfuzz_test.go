@@ -0,0 +1,146 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gomachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/proofmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+// Failing cases found by the fuzzer are saved here, and replayed by
+// TestSavedVectors once they've been fixed
+var vectorDir = filepath.Join("testdata", "vectors")
+
+var checker *Checker
+var checkerOnce sync.Once
+
+func getChecker(t *testing.T) *Checker {
+	checkerOnce.Do(func() {
+		backend, auths := test.SimulatedBackend(t)
+		client := &ethutils.SimulatedEthClient{SimulatedBackend: backend}
+		proofChecker, err := proofmachine.NewProofChecker(auths[0], client)
+		test.FailIfError(t, err)
+		checker = NewChecker(proofChecker)
+	})
+	if checker == nil {
+		t.Fatal("failed to create proof checker")
+	}
+	return checker
+}
+
+// checkProgram fails the test if any step of p has an invalid proof, saving a
+// minimized copy of p as a test vector if save is set
+func checkProgram(t *testing.T, p *Program, save bool) {
+	t.Helper()
+	ctx := context.Background()
+	c := getChecker(t)
+	failure, err := c.Check(ctx, p)
+	test.FailIfError(t, err)
+	if failure == nil {
+		return
+	}
+	if !save {
+		t.Fatal(failure)
+	}
+	minimized := Minimize(p, func(candidate *Program) bool {
+		candidateFailure, err := c.Check(ctx, candidate)
+		if err != nil || candidateFailure == nil {
+			return false
+		}
+		failure = candidateFailure
+		return true
+	})
+	filename, err := SaveVector(vectorDir, NewVector(minimized, failure))
+	test.FailIfError(t, err)
+	t.Fatalf("%v\nsaved minimized case with %v instructions to %v", failure, len(minimized.Code), filename)
+}
+
+func TestSavedVectors(t *testing.T) {
+	files, err := ioutil.ReadDir(vectorDir)
+	if os.IsNotExist(err) {
+		t.Skip("no saved vectors")
+	}
+	test.FailIfError(t, err)
+	for _, file := range files {
+		t.Run(file.Name(), func(t *testing.T) {
+			vector, err := LoadVector(filepath.Join(vectorDir, file.Name()))
+			test.FailIfError(t, err)
+			p, err := vector.Program()
+			test.FailIfError(t, err)
+			checkProgram(t, p, false)
+		})
+	}
+}
+
+func TestRandomPrograms(t *testing.T) {
+	count := 100
+	if testing.Short() {
+		count = 10
+	}
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < count; i++ {
+		data := make([]byte, 1024)
+		rng.Read(data)
+		checkProgram(t, GenerateProgram(data), false)
+	}
+}
+
+// TestMinimize checks that removing instructions keeps codepoint labels
+// pointing at the same code, which the Go machine's loader validates
+func TestMinimize(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "ospfuzz")
+	test.FailIfError(t, err)
+	defer os.RemoveAll(dir)
+	for i := 0; i < 20; i++ {
+		data := make([]byte, 1024)
+		rng.Read(data)
+		p := GenerateProgram(data)
+		target := p.Code[len(p.Code)-1].Opcode
+		minimized := Minimize(p, func(candidate *Program) bool {
+			filename := filepath.Join(dir, "program.mexe")
+			if err := candidate.WriteExecutable(filename); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := gomachine.New(filename); err != nil {
+				t.Fatal(err)
+			}
+			for _, insn := range candidate.Code {
+				if insn.Opcode == target {
+					return true
+				}
+			}
+			return false
+		})
+		if len(minimized.Code) != 1 || minimized.Code[0].Opcode != target || minimized.Code[0].Immediate != nil {
+			t.Errorf("program wasn't fully minimized: %+v", minimized.Code)
+		}
+		if len(minimized.Inbox) != 0 {
+			t.Errorf("minimized program kept %v inbox messages", len(minimized.Inbox))
+		}
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ospfuzz
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// ErrorLabel is the codepoint label the compiler uses for the error codepoint
+const ErrorLabel = math.MaxUint64
+
+// CodePointLabel references the instruction at index Internal in a program
+type CodePointLabel struct {
+	Internal uint64
+}
+
+// Value is an AVM value in the mexe encoding. It mirrors inbox.JSONValue, but
+// can also hold codepoints.
+type Value struct {
+	Tuple     *[]Value        `json:"Tuple,omitempty"`
+	Int       *string         `json:"Int,omitempty"`
+	Buffer    *string         `json:"Buffer,omitempty"`
+	CodePoint *CodePointLabel `json:"CodePoint,omitempty"`
+}
+
+// Instruction is a single operation of a program, with an optional immediate
+type Instruction struct {
+	Opcode    uint8  `json:"opcode"`
+	Immediate *Value `json:"immediate"`
+}
+
+// Program is an AVM executable along with the inbox messages it's run on.
+//
+// The loaders only allow an instruction's immediate to reference code after
+// it, so every codepoint label in the immediate of instruction i must be
+// greater than i. A label equal to the length of the code, or ErrorLabel,
+// references the error codepoint.
+type Program struct {
+	Code      []Instruction
+	StaticVal Value
+	Inbox     []inbox.InboxMessage
+}
+
+type executable struct {
+	Code      []Instruction `json:"code"`
+	StaticVal Value         `json:"static_val"`
+}
+
+// WriteExecutable saves the program's code and static value as a mexe file
+func (p *Program) WriteExecutable(filename string) error {
+	data, err := json.Marshal(executable{Code: p.Code, StaticVal: p.StaticVal})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// Clone returns a copy of the program which can be modified independently
+func (p *Program) Clone() *Program {
+	code := make([]Instruction, 0, len(p.Code))
+	for _, insn := range p.Code {
+		if insn.Immediate != nil {
+			imm := insn.Immediate.clone()
+			insn.Immediate = &imm
+		}
+		code = append(code, insn)
+	}
+	return &Program{
+		Code:      code,
+		StaticVal: p.StaticVal.clone(),
+		Inbox:     append([]inbox.InboxMessage(nil), p.Inbox...),
+	}
+}
+
+func (v Value) clone() Value {
+	if v.Tuple != nil {
+		items := make([]Value, 0, len(*v.Tuple))
+		for _, item := range *v.Tuple {
+			items = append(items, item.clone())
+		}
+		v.Tuple = &items
+	}
+	if v.CodePoint != nil {
+		label := *v.CodePoint
+		v.CodePoint = &label
+	}
+	return v
+}
+
+// relabel updates the codepoint labels in v after the instruction at index
+// removed has been deleted. Labels referencing it now reference the
+// instruction which followed it.
+func (v Value) relabel(removed uint64) {
+	if v.Tuple != nil {
+		for _, item := range *v.Tuple {
+			item.relabel(removed)
+		}
+	}
+	if v.CodePoint != nil && v.CodePoint.Internal != ErrorLabel && v.CodePoint.Internal > removed {
+		v.CodePoint.Internal--
+	}
+}
+
+// Vector is a saved failing case. The code and static value are stored in the
+// mexe encoding, so a vector can be loaded directly as an executable.
+type Vector struct {
+	Code      []Instruction   `json:"code"`
+	StaticVal Value           `json:"static_val"`
+	Inbox     []hexutil.Bytes `json:"inbox"`
+	Failure   *Failure        `json:"failure"`
+}
+
+// NewVector records that p fails with failure
+func NewVector(p *Program, failure *Failure) *Vector {
+	messages := make([]hexutil.Bytes, 0, len(p.Inbox))
+	for _, msg := range p.Inbox {
+		messages = append(messages, msg.ToBytes())
+	}
+	return &Vector{
+		Code:      p.Code,
+		StaticVal: p.StaticVal,
+		Inbox:     messages,
+		Failure:   failure,
+	}
+}
+
+// Program returns the program the vector was recorded from
+func (v *Vector) Program() (*Program, error) {
+	messages := make([]inbox.InboxMessage, 0, len(v.Inbox))
+	for i, data := range v.Inbox {
+		msg, err := inbox.NewInboxMessageFromData(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid inbox message %v", i)
+		}
+		messages = append(messages, msg)
+	}
+	return &Program{
+		Code:      v.Code,
+		StaticVal: v.StaticVal,
+		Inbox:     messages,
+	}, nil
+}
+
+// SaveVector writes v to dir, naming the file by the hash of its contents so
+// that saving the same case twice doesn't create duplicates
+func SaveVector(dir string, v *Vector) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	hash := hashing.SoliditySHA3(data)
+	filename := filepath.Join(dir, "osp-"+hexutil.Encode(hash[:8])[2:]+".json")
+	return filename, ioutil.WriteFile(filename, data, 0644)
+}
+
+// LoadVector reads a vector saved by SaveVector
+func LoadVector(filename string) (*Vector, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	v := new(Vector)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %v", filename)
+	}
+	return v, nil
+}
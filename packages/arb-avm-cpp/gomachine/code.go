@@ -0,0 +1,207 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// ErrorCodePoint is the codepoint which every chain of code ends in. Jumping
+// to it, or setting it as the error handler, means an error halts the machine.
+var ErrorCodePoint = value.CodePointValue{Op: value.BasicOperation{Op: 0}}
+
+var errorCodePointHash = ErrorCodePoint.Hash()
+
+// codeStore holds every codepoint the machine can reach, indexed by hash.
+// Since codepoints reference the following instruction by hash, this is all
+// that's needed to advance the pc. Codepoints are only ever added, so a store
+// is shared between clones of a machine.
+type codeStore struct {
+	sync.RWMutex
+	points map[common.Hash]value.CodePointValue
+}
+
+func newCodeStore() *codeStore {
+	return &codeStore{
+		points: map[common.Hash]value.CodePointValue{
+			errorCodePointHash: ErrorCodePoint,
+		},
+	}
+}
+
+func (c *codeStore) add(cp value.CodePointValue) value.CodePointValue {
+	h := cp.Hash()
+	c.Lock()
+	defer c.Unlock()
+	c.points[h] = cp
+	return cp
+}
+
+func (c *codeStore) next(cp value.CodePointValue) (value.CodePointValue, error) {
+	c.RLock()
+	defer c.RUnlock()
+	next, ok := c.points[cp.NextHash]
+	if !ok {
+		return value.CodePointValue{}, errors.Errorf("missing codepoint %v", cp.NextHash)
+	}
+	return next, nil
+}
+
+type mexeOperation struct {
+	Opcode    json.RawMessage `json:"opcode"`
+	Immediate json.RawMessage `json:"immediate"`
+}
+
+type mexeFile struct {
+	Code      []mexeOperation `json:"code"`
+	StaticVal json.RawMessage `json:"static_val"`
+}
+
+// loadExecutable parses a compiled .mexe file, adding its code to store and
+// returning the initial codepoint and static value
+func loadExecutable(filename string, store *codeStore) (value.CodePointValue, value.Value, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return value.CodePointValue{}, nil, err
+	}
+	var exe mexeFile
+	if err := json.Unmarshal(data, &exe); err != nil {
+		return value.CodePointValue{}, nil, errors.Wrapf(err, "error parsing %v", filename)
+	}
+
+	// Each instruction refers to the one after it, so the code is built
+	// backwards from the error codepoint
+	codePoints := make([]value.CodePointValue, len(exe.Code)+1)
+	codePoints[len(exe.Code)] = ErrorCodePoint
+	loader := &mexeValueLoader{codePoints: codePoints}
+	for i := len(exe.Code) - 1; i >= 0; i-- {
+		loader.loaded = i + 1
+		op, err := loader.operation(exe.Code[i])
+		if err != nil {
+			return value.CodePointValue{}, nil, errors.Wrapf(err, "error loading instruction %v", i)
+		}
+		codePoints[i] = store.add(value.CodePointValue{Op: op, NextHash: codePoints[i+1].Hash()})
+	}
+	loader.loaded = 0
+	staticVal, err := loader.value(exe.StaticVal)
+	if err != nil {
+		return value.CodePointValue{}, nil, errors.Wrap(err, "error loading static value")
+	}
+	return codePoints[0], staticVal, nil
+}
+
+type mexeValueLoader struct {
+	codePoints []value.CodePointValue
+	// Index of the first instruction which has been loaded
+	loaded int
+}
+
+func (l *mexeValueLoader) operation(op mexeOperation) (value.Operation, error) {
+	var opcode uint8
+	if err := json.Unmarshal(op.Opcode, &opcode); err != nil {
+		var wrapped struct {
+			AVMOpcode uint8
+		}
+		if err := json.Unmarshal(op.Opcode, &wrapped); err != nil {
+			return nil, errors.Errorf("invalid opcode %s", op.Opcode)
+		}
+		opcode = wrapped.AVMOpcode
+	}
+	if len(op.Immediate) == 0 || string(op.Immediate) == "null" {
+		return value.BasicOperation{Op: value.Opcode(opcode)}, nil
+	}
+	imm, err := l.value(op.Immediate)
+	if err != nil {
+		return nil, err
+	}
+	return value.ImmediateOperation{Op: value.Opcode(opcode), Val: imm}, nil
+}
+
+func (l *mexeValueLoader) value(data json.RawMessage) (value.Value, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if raw, ok := fields["Int"]; ok {
+		var hexStr string
+		if err := json.Unmarshal(raw, &hexStr); err != nil {
+			return nil, err
+		}
+		val, ok := new(big.Int).SetString(strings.TrimPrefix(hexStr, "0x"), 16)
+		if !ok || val.Sign() < 0 || val.BitLen() > 256 {
+			return nil, errors.Errorf("invalid int %v", hexStr)
+		}
+		return value.NewIntValue(val), nil
+	}
+	if raw, ok := fields["Tuple"]; ok {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		vals := make([]value.Value, 0, len(items))
+		for _, item := range items {
+			val, err := l.value(item)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return value.NewTupleFromSlice(vals)
+	}
+	if raw, ok := fields["Buffer"]; ok {
+		var hexStr string
+		if err := json.Unmarshal(raw, &hexStr); err != nil {
+			return nil, err
+		}
+		bufData, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, err
+		}
+		return value.NewBuffer(bufData), nil
+	}
+	if raw, ok := fields["CodePoint"]; ok {
+		var label struct {
+			Internal *uint64
+		}
+		if err := json.Unmarshal(raw, &label); err != nil {
+			return nil, err
+		}
+		if label.Internal == nil {
+			return nil, errors.New("codepoint must have an internal label")
+		}
+		// The compiler marks the error codepoint with the max label
+		if *label.Internal == math.MaxUint64 {
+			return ErrorCodePoint, nil
+		}
+		if *label.Internal < uint64(l.loaded) || *label.Internal >= uint64(len(l.codePoints)) {
+			return nil, errors.Errorf("codepoint label %v references code which isn't loaded", *label.Internal)
+		}
+		return l.codePoints[*label.Internal], nil
+	}
+	return nil, errors.New("invalid value type")
+}
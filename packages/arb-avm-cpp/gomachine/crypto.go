@@ -0,0 +1,393 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"math/big"
+	"math/bits"
+
+	ethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	bn256 "github.com/ethereum/go-ethereum/crypto/bn256/cloudflare"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [24]int{
+	1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44,
+}
+
+var keccakPiLanes = [24]int{
+	10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1,
+}
+
+// keccakF1600 applies the keccak-f[1600] permutation to st
+func keccakF1600(st *[25]uint64) {
+	var bc [5]uint64
+	for round := 0; round < 24; round++ {
+		// Theta
+		for i := 0; i < 5; i++ {
+			bc[i] = st[i] ^ st[i+5] ^ st[i+10] ^ st[i+15] ^ st[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				st[j+i] ^= t
+			}
+		}
+
+		// Rho and pi
+		t := st[1]
+		for i := 0; i < 24; i++ {
+			j := keccakPiLanes[i]
+			bc[0] = st[j]
+			st[j] = bits.RotateLeft64(t, keccakRotations[i])
+			t = bc[0]
+		}
+
+		// Chi
+		for j := 0; j < 25; j += 5 {
+			for i := 0; i < 5; i++ {
+				bc[i] = st[j+i]
+			}
+			for i := 0; i < 5; i++ {
+				st[j+i] ^= ^bc[(i+1)%5] & bc[(i+2)%5]
+			}
+		}
+
+		// Iota
+		st[0] ^= keccakRoundConstants[round]
+	}
+}
+
+// The keccak state is passed as a 7-tuple of ints. The first six each hold
+// four 64 bit lanes, least significant first, and the last holds lane 24.
+func encodeKeccakState(tup *value.TupleValue) ([25]uint64, error) {
+	var state [25]uint64
+	if tup.Len() != 7 {
+		return state, errBadPopType
+	}
+	for i, item := range tup.Contents() {
+		intVal, err := assumeInt(item)
+		if err != nil {
+			return state, err
+		}
+		words := ethmath.PaddedBigBytes(intVal, 32)
+		if i == 6 {
+			state[24] = bigEndianUint64(words[24:])
+			continue
+		}
+		for j := 0; j < 4; j++ {
+			state[i*4+j] = bigEndianUint64(words[24-8*j:])
+		}
+	}
+	return state, nil
+}
+
+func decodeKeccakState(state [25]uint64) (*value.TupleValue, error) {
+	vals := make([]value.Value, 0, 7)
+	for i := 0; i < 6; i++ {
+		intVal := new(big.Int)
+		for j := 3; j >= 0; j-- {
+			intVal.Lsh(intVal, 64)
+			intVal.Or(intVal, new(big.Int).SetUint64(state[i*4+j]))
+		}
+		vals = append(vals, value.NewIntValue(intVal))
+	}
+	vals = append(vals, value.NewIntValue(new(big.Int).SetUint64(state[24])))
+	return value.NewTupleFromSlice(vals)
+}
+
+func bigEndianUint64(data []byte) uint64 {
+	var ret uint64
+	for _, b := range data[:8] {
+		ret = ret<<8 | uint64(b)
+	}
+	return ret
+}
+
+func (m *Machine) keccakF() error {
+	tup, err := assumeTuple(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	state, err := encodeKeccakState(tup)
+	if err != nil {
+		return err
+	}
+	keccakF1600(&state)
+	res, err := decodeKeccakState(state)
+	if err != nil {
+		return err
+	}
+	m.stack.set(0, res)
+	return m.advance()
+}
+
+var sha256RoundConstants = [64]uint32{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+// sha256Block applies the sha256 compression function to a single 64 byte
+// block without any padding
+func sha256Block(digest *[8]uint32, block []byte) {
+	var w [64]uint32
+	for i := 0; i < 16; i++ {
+		j := i * 4
+		w[i] = uint32(block[j])<<24 | uint32(block[j+1])<<16 | uint32(block[j+2])<<8 | uint32(block[j+3])
+	}
+	for i := 16; i < 64; i++ {
+		v1 := w[i-2]
+		t1 := bits.RotateLeft32(v1, -17) ^ bits.RotateLeft32(v1, -19) ^ (v1 >> 10)
+		v2 := w[i-15]
+		t2 := bits.RotateLeft32(v2, -7) ^ bits.RotateLeft32(v2, -18) ^ (v2 >> 3)
+		w[i] = t1 + w[i-7] + t2 + w[i-16]
+	}
+
+	a, b, c, d, e, f, g, h := digest[0], digest[1], digest[2], digest[3], digest[4], digest[5], digest[6], digest[7]
+	for i := 0; i < 64; i++ {
+		t1 := h + (bits.RotateLeft32(e, -6) ^ bits.RotateLeft32(e, -11) ^ bits.RotateLeft32(e, -25)) +
+			((e & f) ^ (^e & g)) + sha256RoundConstants[i] + w[i]
+		t2 := (bits.RotateLeft32(a, -2) ^ bits.RotateLeft32(a, -13) ^ bits.RotateLeft32(a, -22)) +
+			((a & b) ^ (a & c) ^ (b & c))
+		h = g
+		g = f
+		f = e
+		e = d + t1
+		d = c
+		c = b
+		b = a
+		a = t1 + t2
+	}
+
+	digest[0] += a
+	digest[1] += b
+	digest[2] += c
+	digest[3] += d
+	digest[4] += e
+	digest[5] += f
+	digest[6] += g
+	digest[7] += h
+}
+
+func (m *Machine) sha256F() error {
+	digestInt, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	first, err := assumeInt(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	second, err := assumeInt(m.stack.peek(2))
+	if err != nil {
+		return err
+	}
+
+	// The digest is packed with its first word most significant
+	var digest [8]uint32
+	digestBytes := ethmath.PaddedBigBytes(digestInt, 32)
+	for i := range digest {
+		j := i * 4
+		digest[i] = uint32(digestBytes[j])<<24 | uint32(digestBytes[j+1])<<16 | uint32(digestBytes[j+2])<<8 | uint32(digestBytes[j+3])
+	}
+	block := append(ethmath.PaddedBigBytes(first, 32), ethmath.PaddedBigBytes(second, 32)...)
+	sha256Block(&digest, block)
+
+	res := new(big.Int)
+	for _, word := range digest {
+		res.Lsh(res, 32)
+		res.Or(res, new(big.Int).SetUint64(uint64(word)))
+	}
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, value.NewIntValue(res))
+	return m.advance()
+}
+
+// ecRecoverAddress returns the address which signed message, or 0 if the
+// signature is invalid
+func ecRecoverAddress(r, s, recoveryID, message *big.Int) *big.Int {
+	if recoveryID.Cmp(big.NewInt(1)) > 0 {
+		return big.NewInt(0)
+	}
+	sig := append(ethmath.PaddedBigBytes(r, 32), ethmath.PaddedBigBytes(s, 32)...)
+	sig = append(sig, byte(recoveryID.Uint64()))
+	pubKey, err := crypto.Ecrecover(ethmath.PaddedBigBytes(message, 32), sig)
+	if err != nil {
+		return big.NewInt(0)
+	}
+	hash := crypto.Keccak256(pubKey[1:])
+	return new(big.Int).SetBytes(hash[12:])
+}
+
+func (m *Machine) ecRecover() error {
+	args := make([]*big.Int, 0, 4)
+	for i := 0; i < 4; i++ {
+		arg, err := assumeInt(m.stack.peek(i))
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	res := ecRecoverAddress(args[0], args[1], args[2], args[3])
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, value.NewIntValue(res))
+	return m.advance()
+}
+
+func g1FromInts(x, y *big.Int) (*bn256.G1, error) {
+	point := new(bn256.G1)
+	data := append(ethmath.PaddedBigBytes(x, 32), ethmath.PaddedBigBytes(y, 32)...)
+	if _, err := point.Unmarshal(data); err != nil {
+		return nil, avmError("invalid g1 point")
+	}
+	return point, nil
+}
+
+// g2FromInts parses a G2 point given as the real and imaginary parts of each
+// coordinate
+func g2FromInts(x0, x1, y0, y1 *big.Int) (*bn256.G2, error) {
+	point := new(bn256.G2)
+	var data []byte
+	for _, coord := range []*big.Int{x1, x0, y1, y0} {
+		data = append(data, ethmath.PaddedBigBytes(coord, 32)...)
+	}
+	if _, err := point.Unmarshal(data); err != nil {
+		return nil, avmError("invalid g2 point")
+	}
+	return point, nil
+}
+
+func g1ToInts(point *bn256.G1) (*big.Int, *big.Int) {
+	data := point.Marshal()
+	return new(big.Int).SetBytes(data[:32]), new(big.Int).SetBytes(data[32:])
+}
+
+func (m *Machine) ecAdd() error {
+	args := make([]*big.Int, 0, 4)
+	for i := 0; i < 4; i++ {
+		arg, err := assumeInt(m.stack.peek(i))
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	a, err := g1FromInts(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	b, err := g1FromInts(args[2], args[3])
+	if err != nil {
+		return err
+	}
+	x, y := g1ToInts(new(bn256.G1).Add(a, b))
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, value.NewIntValue(x))
+	m.stack.set(1, value.NewIntValue(y))
+	return m.advance()
+}
+
+func (m *Machine) ecMul() error {
+	args := make([]*big.Int, 0, 3)
+	for i := 0; i < 3; i++ {
+		arg, err := assumeInt(m.stack.peek(i))
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	point, err := g1FromInts(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	x, y := g1ToInts(new(bn256.G1).ScalarMult(point, args[2]))
+	m.stack.pop()
+	m.stack.set(0, value.NewIntValue(x))
+	m.stack.set(1, value.NewIntValue(y))
+	return m.advance()
+}
+
+// ecPairing checks a pairing given as a linked list of (point, rest) pairs,
+// where each point is a 6-tuple holding a G1 point followed by a G2 point
+func (m *Machine) ecPairing() error {
+	val, err := assumeTuple(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	var g1Points []*bn256.G1
+	var g2Points []*bn256.G2
+	for i := 0; i < maxECPairingPoints && val.Len() != 0; i++ {
+		if val.Len() != 2 {
+			return errBadPopType
+		}
+		contents := val.Contents()
+		next, err := assumeTuple(contents[0])
+		if err != nil {
+			return err
+		}
+		val, err = assumeTuple(contents[1])
+		if err != nil {
+			return err
+		}
+		if next.Len() != 6 {
+			return errBadPopType
+		}
+		coords := make([]*big.Int, 0, 6)
+		for _, item := range next.Contents() {
+			coord, err := assumeInt(item)
+			if err != nil {
+				return err
+			}
+			coords = append(coords, coord)
+		}
+		g1, err := g1FromInts(coords[0], coords[1])
+		if err != nil {
+			return err
+		}
+		g2, err := g2FromInts(coords[2], coords[3], coords[4], coords[5])
+		if err != nil {
+			return err
+		}
+		g1Points = append(g1Points, g1)
+		g2Points = append(g2Points, g2)
+	}
+	if val.Len() != 0 {
+		return errBadPopType
+	}
+	m.stack.set(0, boolValue(bn256.PairingCheck(g1Points, g2Points)))
+	return m.advance()
+}
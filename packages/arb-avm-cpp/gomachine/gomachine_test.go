@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gotest"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+type valueTestCase struct {
+	Value      string `json:"value"`
+	ProofValue string `json:"proof_value"`
+	Hash       string `json:"hash"`
+	Name       string `json:"name"`
+}
+
+func TestValueTestCases(t *testing.T) {
+	data, err := ioutil.ReadFile("../../arb-util/value/test_cases.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []valueTestCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			valData, err := hex.DecodeString(tc.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			val, err := value.UnmarshalValue(bytes.NewReader(valData))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hash := hex.EncodeToString(val.Hash().Bytes()); hash != tc.Hash {
+				t.Errorf("wrong hash %v, expected %v", hash, tc.Hash)
+			}
+			proofData, err := marshalValueForProof(nil, val, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof := hex.EncodeToString(proofData); proof != tc.ProofValue {
+				t.Errorf("wrong proof value %v, expected %v", proof, tc.ProofValue)
+			}
+		})
+	}
+}
+
+func hashFromDecimal(t *testing.T, str string) common.Hash {
+	t.Helper()
+	val, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		t.Fatal("invalid hash", str)
+	}
+	var h common.Hash
+	valBytes := val.Bytes()
+	copy(h[32-len(valBytes):], valBytes)
+	return h
+}
+
+// The same vector is checked against the C++ implementation in
+// tests/machine.cpp
+func TestMachineHash(t *testing.T) {
+	mach, err := New("../tests/contract.mexe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyTupleHash := hashFromDecimal(t, "42512909751185556122923115391154208487752310613213055089416300774052282720344")
+	checks := []struct {
+		name     string
+		hash     common.Hash
+		expected common.Hash
+	}{
+		{"pc", mach.pc.Hash(), hashFromDecimal(t, "94370651106686220754648249265079798778273932128194559331492955050019282050496")},
+		{"stack", mach.stack.hash(), emptyTupleHash},
+		{"aux stack", mach.auxStack.hash(), emptyTupleHash},
+		{"register", mach.register.Hash(), emptyTupleHash},
+		{"static", mach.static.Hash(), hashFromDecimal(t, "113182352889449210665994027227588754290969798016938687372921424809289618385856")},
+		{"error pc", mach.errPC.Hash(), hashFromDecimal(t, "81755589384323691266272576345129881657705914621008081459572116739688988488432")},
+		{"machine", mach.Hash(), hashFromDecimal(t, "56208326812724912066026123588383649819390601658448049319166841561743369815863")},
+	}
+	for _, check := range checks {
+		if check.hash != check.expected {
+			t.Errorf("wrong %v hash %v, expected %v", check.name, check.hash, check.expected)
+		}
+	}
+}
+
+func TestMachineTestVectors(t *testing.T) {
+	testDir, err := gotest.OpCodeTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		"opcodetestarbgas", "opcodetestdup", "opcodetestecops",
+		"opcodetestethhash2", "opcodetesthash", "opcodetestlogic",
+		"opcodetestmath", "opcodeteststack", "opcodetesttuple",
+		"opcodetestcode", "opcodetestkeccakf", "opcodetestsha256f",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			mach, err := New(filepath.Join(testDir, file+".mexe"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for mach.IsBlocked(false) == nil {
+				_, _, steps, err := mach.ExecuteAssertion(context.Background(), 0, false, nil, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if steps == 0 {
+					break
+				}
+			}
+			if mach.CurrentStatus() != machine.Halt {
+				t.Errorf("machine ended with status %v", mach.CurrentStatus())
+			}
+		})
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	testDir, err := gotest.OpCodeTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mach, err := New(filepath.Join(testDir, "opcodetestmath.mexe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := mach.Clone()
+	startHash := clone.Hash()
+	if _, _, _, err := mach.ExecuteAssertion(context.Background(), 1000, false, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if mach.Hash() == startHash {
+		t.Fatal("machine didn't change")
+	}
+	if clone.Hash() != startHash {
+		t.Error("running a machine changed its clone")
+	}
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gomachine is a pure Go implementation of the AVM. It follows the
+// C++ implementation in arb-avm-cpp instruction for instruction, producing
+// the same machine hashes, assertions and one step proofs, but without
+// needing cgo. It's much slower, and is meant for tests and for checking the
+// C++ implementation against an independent one rather than for running a
+// chain.
+package gomachine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// ErrBufferTooLarge is returned when a program writes further into a buffer
+// than this implementation supports. The C++ implementation stores buffers
+// as sparse trees and has no such limit.
+var ErrBufferTooLarge = errors.New("buffer write exceeds supported size")
+
+// How many instructions to run between checks for cancellation
+const cancelCheckInterval = 10000
+
+// The hash of a machine in the error state is 1
+var errorMachineHash = common.Hash{31: 1}
+
+var maxGasRemaining = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// avmError is returned by an instruction which puts the machine into the
+// error state, as opposed to a failure of the interpreter itself
+type avmError string
+
+func (e avmError) Error() string {
+	return string(e)
+}
+
+const (
+	errBadPopType    = avmError("bad pop type")
+	errIntOutOfRange = avmError("int out of bounds")
+	errTupleIndex    = avmError("bad tuple index")
+	errDivideByZero  = avmError("divide by zero")
+)
+
+// sideloadBlocked is returned when the machine is waiting for a sideload
+// which wasn't provided
+type sideloadBlocked struct{}
+
+func (b sideloadBlocked) String() string {
+	return "SideloadBlocked"
+}
+
+func (b sideloadBlocked) IsBlocked(machine.Machine, bool) bool {
+	return false
+}
+
+func (b sideloadBlocked) Equals(a machine.BlockReason) bool {
+	_, ok := a.(sideloadBlocked)
+	return ok
+}
+
+type assertionContext struct {
+	inbox            []inbox.InboxMessage
+	sideloads        []inbox.InboxMessage
+	stopOnSideload   bool
+	stopOnBreakpoint bool
+	firstInstruction bool
+
+	messagesRead uint64
+	sends        [][]byte
+	logs         []value.Value
+	debugPrints  []value.Value
+}
+
+type Machine struct {
+	code *codeStore
+
+	pc           value.CodePointValue
+	errPC        value.CodePointValue
+	stack        *stack
+	auxStack     *stack
+	register     value.Value
+	static       value.Value
+	gasRemaining *big.Int
+	status       machine.Status
+
+	gasUsed    uint64
+	totalSteps uint64
+
+	context *assertionContext
+}
+
+// New loads a machine from a compiled .mexe file
+func New(codeFile string) (*Machine, error) {
+	code := newCodeStore()
+	pc, static, err := loadExecutable(codeFile, code)
+	if err != nil {
+		return nil, err
+	}
+	return &Machine{
+		code:         code,
+		pc:           pc,
+		errPC:        ErrorCodePoint,
+		stack:        &stack{},
+		auxStack:     &stack{},
+		register:     value.NewEmptyTuple(),
+		static:       static,
+		gasRemaining: new(big.Int).Set(maxGasRemaining),
+		status:       machine.Extensive,
+		context:      &assertionContext{},
+	}, nil
+}
+
+func (m *Machine) Hash() common.Hash {
+	switch m.status {
+	case machine.Halt:
+		return common.Hash{}
+	case machine.ErrorStop:
+		return errorMachineHash
+	}
+	return hashing.SoliditySHA3(
+		hashing.Bytes32(m.pc.Hash()),
+		hashing.Bytes32(m.stack.hash()),
+		hashing.Bytes32(m.auxStack.hash()),
+		hashing.Bytes32(m.register.Hash()),
+		hashing.Bytes32(m.static.Hash()),
+		hashing.Uint256(m.gasRemaining),
+		hashing.Bytes32(m.errPC.Hash()),
+	)
+}
+
+func (m *Machine) CodePointHash() common.Hash {
+	return m.pc.Hash()
+}
+
+func (m *Machine) Clone() machine.Machine {
+	return &Machine{
+		code:         m.code,
+		pc:           m.pc,
+		errPC:        m.errPC,
+		stack:        m.stack.clone(),
+		auxStack:     m.auxStack.clone(),
+		register:     m.register,
+		static:       m.static,
+		gasRemaining: new(big.Int).Set(m.gasRemaining),
+		status:       m.status,
+		gasUsed:      m.gasUsed,
+		totalSteps:   m.totalSteps,
+		context:      &assertionContext{},
+	}
+}
+
+func (m *Machine) CurrentStatus() machine.Status {
+	return m.status
+}
+
+func (m *Machine) IsBlocked(newMessages bool) machine.BlockReason {
+	switch m.status {
+	case machine.ErrorStop:
+		return machine.ErrorBlocked{}
+	case machine.Halt:
+		return machine.HaltBlocked{}
+	}
+	if m.pc.Op.GetOp() == INBOX && !newMessages {
+		return machine.InboxBlocked{}
+	}
+	return nil
+}
+
+func (m *Machine) String() string {
+	return fmt.Sprintf(
+		"Machine(status: %v, pc: %v, op: %v, stack: %v, aux stack: %v, gas remaining: %v)",
+		m.status,
+		m.pc.Hash(),
+		m.pc.Op,
+		m.stack.size(),
+		m.auxStack.size(),
+		m.gasRemaining,
+	)
+}
+
+// Steps returns the total number of instructions the machine has executed
+func (m *Machine) Steps() uint64 {
+	return m.totalSteps
+}
+
+func (m *Machine) ExecuteAssertion(
+	ctx context.Context,
+	maxGas uint64,
+	goOverGas bool,
+	messages []inbox.InboxMessage,
+	trace bool,
+) (*protocol.ExecutionAssertion, []value.Value, uint64, error) {
+	return m.ExecuteAssertionAdvanced(
+		ctx,
+		maxGas,
+		goOverGas,
+		messages,
+		nil,
+		false,
+		false,
+		trace,
+	)
+}
+
+func (m *Machine) ExecuteAssertionAdvanced(
+	ctx context.Context,
+	maxGas uint64,
+	goOverGas bool,
+	messages []inbox.InboxMessage,
+	sideloads []inbox.InboxMessage,
+	stopOnSideload bool,
+	stopOnBreakpoint bool,
+	trace bool,
+) (*protocol.ExecutionAssertion, []value.Value, uint64, error) {
+	m.context = &assertionContext{
+		inbox:            messages,
+		sideloads:        append([]inbox.InboxMessage(nil), sideloads...),
+		stopOnSideload:   stopOnSideload,
+		stopOnBreakpoint: stopOnBreakpoint,
+		firstInstruction: true,
+	}
+	startGas := m.gasUsed
+	startSteps := m.totalSteps
+
+	// Like the C++ implementation, the gas limit is relative to the gas used
+	// so far, and a machine which has used gas can't run without a limit
+	gasLimit := maxGas + m.gasUsed
+	if gasLimit < maxGas {
+		gasLimit = math.MaxUint64
+	}
+	hasGasLimit := gasLimit != 0
+
+	for i := 0; ; i++ {
+		if i%cancelCheckInterval == 0 && ctx.Err() != nil {
+			m.status = machine.ErrorStop
+			return nil, nil, 0, ctx.Err()
+		}
+		if hasGasLimit {
+			if !goOverGas {
+				nextGas := m.nextGasCost() + m.gasUsed
+				if nextGas < m.gasUsed || nextGas > gasLimit {
+					break
+				}
+			} else if m.gasUsed >= gasLimit {
+				break
+			}
+		}
+		blockReason, err := m.runOne()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if blockReason != nil {
+			break
+		}
+	}
+
+	assertion := &protocol.ExecutionAssertion{
+		NumGas:                m.gasUsed - startGas,
+		InboxMessagesConsumed: m.context.messagesRead,
+		Sends:                 m.context.sends,
+		Logs:                  m.context.logs,
+	}
+	var debugPrints []value.Value
+	if trace {
+		debugPrints = m.context.debugPrints
+	}
+	return assertion, debugPrints, m.totalSteps - startSteps, nil
+}
+
+func (m *Machine) nextGasCost() uint64 {
+	info, ok := opcodes[m.pc.Op.GetOp()]
+	if !ok {
+		return 0
+	}
+	return m.gasCost(m.pc.Op.GetOp(), info)
+}
+
+func (m *Machine) gasCost(op value.Opcode, info opcodeInfo) uint64 {
+	if op == ECPAIRING {
+		return info.gas + m.ecPairingVariableGasCost()
+	}
+	return info.gas
+}
+
+func (m *Machine) chargeErrorGas() {
+	if m.gasRemaining.Cmp(big.NewInt(errorGasCost)) < 0 {
+		m.gasRemaining = new(big.Int).Set(maxGasRemaining)
+	} else {
+		m.gasRemaining = new(big.Int).Sub(m.gasRemaining, big.NewInt(errorGasCost))
+	}
+	m.gasUsed += errorGasCost
+}
+
+// runOne executes the current instruction. It returns a non-nil BlockReason
+// if the instruction couldn't run, in which case the machine is unchanged.
+func (m *Machine) runOne() (machine.BlockReason, error) {
+	switch m.status {
+	case machine.ErrorStop:
+		return machine.ErrorBlocked{}, nil
+	case machine.Halt:
+		return machine.HaltBlocked{}, nil
+	}
+
+	op := m.pc.Op.GetOp()
+	immOp, hasImmediate := m.pc.Op.(value.ImmediateOperation)
+
+	// The immediate is pushed even if the instruction errors
+	if hasImmediate {
+		m.stack.push(immOp.Val)
+	}
+	startStackSize := m.stack.size()
+	startAuxStackSize := m.auxStack.size()
+
+	info, valid := opcodes[op]
+	stackArgCount := len(info.stackPops)
+	auxStackArgCount := len(info.auxStackPops)
+
+	var blockReason machine.BlockReason
+	if stackArgCount > startStackSize || auxStackArgCount > startAuxStackSize {
+		m.status = machine.ErrorStop
+		m.chargeErrorGas()
+	} else {
+		gasCost := uint64(errorGasCost)
+		if valid {
+			gasCost = m.gasCost(op, info)
+		}
+		if m.gasRemaining.Cmp(new(big.Int).SetUint64(gasCost)) < 0 {
+			// Running out of gas is an error which also resets the gas
+			// remaining to the max
+			m.gasUsed += errorGasCost
+			m.gasRemaining = new(big.Int).Set(maxGasRemaining)
+			m.status = machine.ErrorStop
+		} else {
+			m.gasRemaining = new(big.Int).Sub(m.gasRemaining, new(big.Int).SetUint64(gasCost))
+			m.gasUsed += gasCost
+			if !valid {
+				m.status = machine.ErrorStop
+			} else {
+				var err error
+				blockReason, err = m.runOp(op)
+				if _, ok := err.(avmError); ok {
+					m.status = machine.ErrorStop
+				} else if err != nil {
+					return nil, err
+				}
+				if blockReason != nil {
+					// Undo the instruction since it didn't run
+					m.gasRemaining = new(big.Int).Add(m.gasRemaining, new(big.Int).SetUint64(gasCost))
+					m.gasUsed -= gasCost
+					if hasImmediate {
+						m.stack.pop()
+					}
+				}
+			}
+		}
+	}
+
+	if blockReason == nil {
+		m.totalSteps++
+	}
+
+	if m.status == machine.ErrorStop {
+		// Remove the instruction's arguments from the stacks
+		if m.stack.size() <= startStackSize {
+			m.stack.truncate(clearedSize(m.stack.size(), startStackSize, stackArgCount))
+		}
+		if m.auxStack.size() <= startAuxStackSize {
+			m.auxStack.truncate(clearedSize(m.auxStack.size(), startAuxStackSize, auxStackArgCount))
+		}
+		// Jump to the error handler if one is set
+		if m.errPC.Hash() != errorCodePointHash {
+			m.pc = m.errPC
+			m.status = machine.Extensive
+		}
+	}
+
+	m.context.firstInstruction = false
+	return blockReason, nil
+}
+
+// clearedSize returns the size of a stack after removing what's left of an
+// instruction's arguments, given its current size and its size when the
+// instruction started
+func clearedSize(size, startSize, argCount int) int {
+	target := startSize - argCount
+	if target < 0 {
+		target = 0
+	}
+	if size < target {
+		return size
+	}
+	return target
+}
+
+// advance moves the pc to the next instruction
+func (m *Machine) advance() error {
+	next, err := m.code.next(m.pc)
+	if err != nil {
+		return err
+	}
+	m.pc = next
+	return nil
+}
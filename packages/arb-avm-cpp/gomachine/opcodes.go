@@ -0,0 +1,213 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import "github.com/offchainlabs/arbitrum/packages/arb-util/value"
+
+const (
+	ADD           value.Opcode = 0x01
+	MUL           value.Opcode = 0x02
+	SUB           value.Opcode = 0x03
+	DIV           value.Opcode = 0x04
+	SDIV          value.Opcode = 0x05
+	MOD           value.Opcode = 0x06
+	SMOD          value.Opcode = 0x07
+	ADDMOD        value.Opcode = 0x08
+	MULMOD        value.Opcode = 0x09
+	EXP           value.Opcode = 0x0a
+	SIGNEXTEND    value.Opcode = 0x0b
+	LT            value.Opcode = 0x10
+	GT            value.Opcode = 0x11
+	SLT           value.Opcode = 0x12
+	SGT           value.Opcode = 0x13
+	EQ            value.Opcode = 0x14
+	ISZERO        value.Opcode = 0x15
+	AND           value.Opcode = 0x16
+	OR            value.Opcode = 0x17
+	XOR           value.Opcode = 0x18
+	NOT           value.Opcode = 0x19
+	BYTE          value.Opcode = 0x1a
+	SHL           value.Opcode = 0x1b
+	SHR           value.Opcode = 0x1c
+	SAR           value.Opcode = 0x1d
+	HASH          value.Opcode = 0x20
+	TYPE          value.Opcode = 0x21
+	ETHHASH2      value.Opcode = 0x22
+	KECCAKF       value.Opcode = 0x23
+	SHA256F       value.Opcode = 0x24
+	POP           value.Opcode = 0x30
+	SPUSH         value.Opcode = 0x31
+	RPUSH         value.Opcode = 0x32
+	RSET          value.Opcode = 0x33
+	JUMP          value.Opcode = 0x34
+	CJUMP         value.Opcode = 0x35
+	STACKEMPTY    value.Opcode = 0x36
+	PCPUSH        value.Opcode = 0x37
+	AUXPUSH       value.Opcode = 0x38
+	AUXPOP        value.Opcode = 0x39
+	AUXSTACKEMPTY value.Opcode = 0x3a
+	NOP           value.Opcode = 0x3b
+	ERRPUSH       value.Opcode = 0x3c
+	ERRSET        value.Opcode = 0x3d
+	DUP0          value.Opcode = 0x40
+	DUP1          value.Opcode = 0x41
+	DUP2          value.Opcode = 0x42
+	SWAP1         value.Opcode = 0x43
+	SWAP2         value.Opcode = 0x44
+	TGET          value.Opcode = 0x50
+	TSET          value.Opcode = 0x51
+	TLEN          value.Opcode = 0x52
+	XGET          value.Opcode = 0x53
+	XSET          value.Opcode = 0x54
+	BREAKPOINT    value.Opcode = 0x60
+	LOG           value.Opcode = 0x61
+	SEND          value.Opcode = 0x70
+	INBOX         value.Opcode = 0x72
+	ERROR         value.Opcode = 0x73
+	HALT          value.Opcode = 0x74
+	SETGAS        value.Opcode = 0x75
+	PUSHGAS       value.Opcode = 0x76
+	ERRCODEPOINT  value.Opcode = 0x77
+	PUSHINSN      value.Opcode = 0x78
+	PUSHINSNIMM   value.Opcode = 0x79
+	SIDELOAD      value.Opcode = 0x7b
+	ECRECOVER     value.Opcode = 0x80
+	ECADD         value.Opcode = 0x81
+	ECMUL         value.Opcode = 0x82
+	ECPAIRING     value.Opcode = 0x83
+	DEBUGPRINT    value.Opcode = 0x90
+	NEWBUFFER     value.Opcode = 0xa0
+	GETBUFFER8    value.Opcode = 0xa1
+	GETBUFFER64   value.Opcode = 0xa2
+	GETBUFFER256  value.Opcode = 0xa3
+	SETBUFFER8    value.Opcode = 0xa4
+	SETBUFFER64   value.Opcode = 0xa5
+	SETBUFFER256  value.Opcode = 0xa6
+)
+
+// opcodeInfo describes an instruction. The entries of stackPops and
+// auxStackPops are the levels each popped value is marshalled at in a one
+// step proof, and their lengths are the number of arguments taken.
+type opcodeInfo struct {
+	name         string
+	gas          uint64
+	stackPops    []int
+	auxStackPops []int
+}
+
+var opcodes = map[value.Opcode]opcodeInfo{
+	ADD:           {"add", 3, []int{1, 1}, nil},
+	MUL:           {"mul", 3, []int{1, 1}, nil},
+	SUB:           {"sub", 3, []int{1, 1}, nil},
+	DIV:           {"div", 4, []int{1, 1}, nil},
+	SDIV:          {"sdiv", 7, []int{1, 1}, nil},
+	MOD:           {"mod", 4, []int{1, 1}, nil},
+	SMOD:          {"smod", 7, []int{1, 1}, nil},
+	ADDMOD:        {"addmod", 4, []int{1, 1, 1}, nil},
+	MULMOD:        {"mulmod", 4, []int{1, 1, 1}, nil},
+	EXP:           {"exp", 25, []int{1, 1}, nil},
+	SIGNEXTEND:    {"signextend", 7, []int{1, 1}, nil},
+	LT:            {"lt", 2, []int{1, 1}, nil},
+	GT:            {"gt", 2, []int{1, 1}, nil},
+	SLT:           {"slt", 2, []int{1, 1}, nil},
+	SGT:           {"sgt", 2, []int{1, 1}, nil},
+	EQ:            {"eq", 2, []int{0, 0}, nil},
+	ISZERO:        {"iszero", 1, []int{1}, nil},
+	AND:           {"and", 2, []int{1, 1}, nil},
+	OR:            {"or", 2, []int{1, 1}, nil},
+	XOR:           {"xor", 2, []int{1, 1}, nil},
+	NOT:           {"not", 1, []int{1}, nil},
+	BYTE:          {"byte", 4, []int{1, 1}, nil},
+	SHL:           {"shl", 4, []int{1, 1}, nil},
+	SHR:           {"shr", 4, []int{1, 1}, nil},
+	SAR:           {"sar", 4, []int{1, 1}, nil},
+	HASH:          {"hash", 7, []int{0}, nil},
+	TYPE:          {"type", 3, []int{1}, nil},
+	ETHHASH2:      {"ethhash2", 8, []int{1, 1}, nil},
+	KECCAKF:       {"keccakf", 600, []int{1}, nil},
+	SHA256F:       {"sha256f", 250, []int{1, 1, 1}, nil},
+	POP:           {"pop", 1, []int{0}, nil},
+	SPUSH:         {"spush", 1, nil, nil},
+	RPUSH:         {"rpush", 1, nil, nil},
+	RSET:          {"rset", 2, []int{0}, nil},
+	JUMP:          {"jump", 4, []int{0}, nil},
+	CJUMP:         {"cjump", 4, []int{1, 1}, nil},
+	STACKEMPTY:    {"stackempty", 2, nil, nil},
+	PCPUSH:        {"pcpush", 1, nil, nil},
+	AUXPUSH:       {"auxpush", 1, []int{0}, nil},
+	AUXPOP:        {"auxpop", 1, nil, []int{0}},
+	AUXSTACKEMPTY: {"auxstackempty", 2, nil, nil},
+	NOP:           {"nop", 1, nil, nil},
+	ERRPUSH:       {"errpush", 1, nil, nil},
+	ERRSET:        {"errset", 1, []int{1}, nil},
+	DUP0:          {"dup0", 1, []int{0}, nil},
+	DUP1:          {"dup1", 1, []int{0, 0}, nil},
+	DUP2:          {"dup2", 1, []int{0, 0, 0}, nil},
+	SWAP1:         {"swap1", 1, []int{0, 0}, nil},
+	SWAP2:         {"swap2", 1, []int{0, 0, 0}, nil},
+	TGET:          {"tget", 2, []int{1, 1}, nil},
+	TSET:          {"tset", 40, []int{1, 1, 0}, nil},
+	TLEN:          {"tlen", 2, []int{1}, nil},
+	XGET:          {"xget", 3, []int{1}, []int{1}},
+	XSET:          {"xset", 41, []int{1, 0}, []int{1}},
+	BREAKPOINT:    {"breakpoint", 100, nil, nil},
+	LOG:           {"log", 100, []int{0}, nil},
+	SEND:          {"send", 100, []int{1, 1}, nil},
+	INBOX:         {"inbox", 40, nil, nil},
+	ERROR:         {"error", 5, nil, nil},
+	HALT:          {"halt", 10, nil, nil},
+	SETGAS:        {"setgas", 1, []int{1}, nil},
+	PUSHGAS:       {"pushgas", 1, nil, nil},
+	ERRCODEPOINT:  {"errcodepoint", 25, nil, nil},
+	PUSHINSN:      {"pushinsn", 25, []int{1, 1}, nil},
+	PUSHINSNIMM:   {"pushinsnimm", 25, []int{1, 0, 1}, nil},
+	SIDELOAD:      {"sideload", 10, []int{1}, nil},
+	ECRECOVER:     {"ecrecover", 20000, []int{1, 1, 1, 1}, nil},
+	ECADD:         {"ecadd", 3500, []int{1, 1, 1, 1}, nil},
+	ECMUL:         {"ecmul", 82000, []int{1, 1, 1}, nil},
+	ECPAIRING:     {"ecpairing", 1000, []int{32}, nil},
+	DEBUGPRINT:    {"debugprint", 1, []int{0}, nil},
+	NEWBUFFER:     {"newbuffer", 1, nil, nil},
+	GETBUFFER8:    {"getbuffer8", 10, []int{1, 1}, nil},
+	GETBUFFER64:   {"getbuffer64", 10, []int{1, 1}, nil},
+	GETBUFFER256:  {"getbuffer256", 10, []int{1, 1}, nil},
+	SETBUFFER8:    {"setbuffer8", 100, []int{1, 1, 1}, nil},
+	SETBUFFER64:   {"setbuffer64", 100, []int{1, 1, 1}, nil},
+	SETBUFFER256:  {"setbuffer256", 100, []int{1, 1, 1}, nil},
+}
+
+const (
+	// Cost charged when an instruction puts the machine into the error state
+	errorGasCost = 5
+
+	sendSizeLimit       = 10000
+	maxECPairingPoints  = 30
+	ecPairingPointCost  = 500000
+	maxBufferDataLength = 1 << 28
+)
+
+// OpcodeName returns the assembly name of op, or an empty string if it isn't
+// a valid instruction
+func OpcodeName(op value.Opcode) string {
+	return opcodes[op].name
+}
+
+// IsValidOpcode returns whether op is an instruction the machine can execute
+func IsValidOpcode(op value.Opcode) bool {
+	_, ok := opcodes[op]
+	return ok
+}
@@ -0,0 +1,809 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"math"
+	"math/big"
+
+	ethmath "github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+var (
+	two256    = new(big.Int).Lsh(big.NewInt(1), 256)
+	maxUint64 = new(big.Int).SetUint64(math.MaxUint64)
+)
+
+// Every operation checks all of its arguments before modifying the machine,
+// so an operation which fails leaves its arguments on the stack for runOne
+// to clear.
+
+func assumeInt(val value.Value) (*big.Int, error) {
+	intVal, ok := val.(value.IntValue)
+	if !ok {
+		return nil, errBadPopType
+	}
+	return intVal.BigInt(), nil
+}
+
+func assumeUint64(val value.Value) (uint64, error) {
+	intVal, err := assumeInt(val)
+	if err != nil {
+		return 0, err
+	}
+	if intVal.Cmp(maxUint64) > 0 {
+		return 0, errIntOutOfRange
+	}
+	return intVal.Uint64(), nil
+}
+
+func assumeTuple(val value.Value) (*value.TupleValue, error) {
+	tup, ok := val.(*value.TupleValue)
+	if !ok {
+		return nil, errBadPopType
+	}
+	return tup, nil
+}
+
+func assumeCodePoint(val value.Value) (value.CodePointValue, error) {
+	cp, ok := val.(value.CodePointValue)
+	if !ok {
+		return value.CodePointValue{}, errBadPopType
+	}
+	return cp, nil
+}
+
+func assumeBuffer(val value.Value) (*value.Buffer, error) {
+	buf, ok := val.(*value.Buffer)
+	if !ok {
+		return nil, errBadPopType
+	}
+	return buf, nil
+}
+
+func intValue(x *big.Int) value.IntValue {
+	return value.NewIntValue(ethmath.U256(x))
+}
+
+func hashValue(h common.Hash) value.IntValue {
+	return value.NewIntValue(new(big.Int).SetBytes(h.Bytes()))
+}
+
+func boolValue(b bool) value.IntValue {
+	if b {
+		return value.NewInt64Value(1)
+	}
+	return value.NewInt64Value(0)
+}
+
+func (m *Machine) runOp(op value.Opcode) (machine.BlockReason, error) {
+	var err error
+	switch op {
+	case ADD:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Add(a, b), nil
+		})
+	case MUL:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Mul(a, b), nil
+		})
+	case SUB:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Sub(a, b), nil
+		})
+	case DIV:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Div(a, b), nil
+		})
+	case SDIV:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Quo(ethmath.S256(a), ethmath.S256(b)), nil
+		})
+	case MOD:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Mod(a, b), nil
+		})
+	case SMOD:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Rem(ethmath.S256(a), ethmath.S256(b)), nil
+		})
+	case ADDMOD:
+		err = m.ternaryOp(func(a, b, c *big.Int) (*big.Int, error) {
+			if c.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Mod(a.Add(a, b), c), nil
+		})
+	case MULMOD:
+		err = m.ternaryOp(func(a, b, c *big.Int) (*big.Int, error) {
+			if c.Sign() == 0 {
+				return nil, errDivideByZero
+			}
+			return a.Mod(a.Mul(a, b), c), nil
+		})
+	case EXP:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Exp(a, b, two256), nil
+		})
+	case SIGNEXTEND:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if a.Cmp(big.NewInt(31)) >= 0 {
+				return b, nil
+			}
+			signBit := uint(a.Uint64()*8 + 7)
+			valueMask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), signBit), big.NewInt(1))
+			if b.Bit(int(signBit)) == 1 {
+				return b.Or(b, new(big.Int).Xor(ethmath.MaxBig256, valueMask)), nil
+			}
+			return b.And(b, valueMask), nil
+		})
+	case LT:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return boolValue(a.Cmp(b) < 0).BigInt(), nil
+		})
+	case GT:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return boolValue(a.Cmp(b) > 0).BigInt(), nil
+		})
+	case SLT:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return boolValue(ethmath.S256(a).Cmp(ethmath.S256(b)) < 0).BigInt(), nil
+		})
+	case SGT:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return boolValue(ethmath.S256(a).Cmp(ethmath.S256(b)) > 0).BigInt(), nil
+		})
+	case EQ:
+		equal := valuesEqual(m.stack.peek(0), m.stack.peek(1))
+		m.stack.pop()
+		m.stack.set(0, boolValue(equal))
+		err = m.advance()
+	case ISZERO:
+		err = m.unaryOp(func(a *big.Int) (*big.Int, error) {
+			return boolValue(a.Sign() == 0).BigInt(), nil
+		})
+	case AND:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.And(a, b), nil
+		})
+	case OR:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Or(a, b), nil
+		})
+	case XOR:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return a.Xor(a, b), nil
+		})
+	case NOT:
+		err = m.unaryOp(func(a *big.Int) (*big.Int, error) {
+			return a.Xor(a, ethmath.MaxBig256), nil
+		})
+	case BYTE:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if a.Cmp(big.NewInt(32)) >= 0 {
+				return big.NewInt(0), nil
+			}
+			b.Rsh(b, uint(248-8*a.Uint64()))
+			return b.And(b, big.NewInt(255)), nil
+		})
+	case SHL:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if a.Cmp(big.NewInt(256)) >= 0 {
+				return big.NewInt(0), nil
+			}
+			return b.Lsh(b, uint(a.Uint64())), nil
+		})
+	case SHR:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if a.Cmp(big.NewInt(256)) >= 0 {
+				return big.NewInt(0), nil
+			}
+			return b.Rsh(b, uint(a.Uint64())), nil
+		})
+	case SAR:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			if a.Cmp(big.NewInt(256)) >= 0 {
+				if b.Bit(255) == 1 {
+					return new(big.Int).Set(ethmath.MaxBig256), nil
+				}
+				return big.NewInt(0), nil
+			}
+			return b.Rsh(ethmath.S256(b), uint(a.Uint64())), nil
+		})
+	case HASH:
+		m.stack.set(0, hashValue(m.stack.peek(0).Hash()))
+		err = m.advance()
+	case TYPE:
+		m.stack.set(0, value.NewInt64Value(int64(valueType(m.stack.peek(0)))))
+		err = m.advance()
+	case ETHHASH2:
+		err = m.binaryOp(func(a, b *big.Int) (*big.Int, error) {
+			return hashValue(hashing.SoliditySHA3(hashing.Uint256(a), hashing.Uint256(b))).BigInt(), nil
+		})
+	case KECCAKF:
+		err = m.keccakF()
+	case SHA256F:
+		err = m.sha256F()
+	case POP:
+		m.stack.pop()
+		err = m.advance()
+	case SPUSH:
+		m.stack.push(m.static)
+		err = m.advance()
+	case RPUSH:
+		m.stack.push(m.register)
+		err = m.advance()
+	case RSET:
+		m.register = m.stack.pop()
+		err = m.advance()
+	case JUMP:
+		var target value.CodePointValue
+		target, err = assumeCodePoint(m.stack.peek(0))
+		if err == nil {
+			m.stack.pop()
+			m.pc = target
+		}
+	case CJUMP:
+		err = m.cjump()
+	case STACKEMPTY:
+		m.stack.push(boolValue(m.stack.size() == 0))
+		err = m.advance()
+	case PCPUSH:
+		m.stack.push(m.pc)
+		err = m.advance()
+	case AUXPUSH:
+		m.auxStack.push(m.stack.pop())
+		err = m.advance()
+	case AUXPOP:
+		m.stack.push(m.auxStack.pop())
+		err = m.advance()
+	case AUXSTACKEMPTY:
+		m.stack.push(boolValue(m.auxStack.size() == 0))
+		err = m.advance()
+	case NOP:
+		err = m.advance()
+	case ERRPUSH:
+		m.stack.push(m.errPC)
+		err = m.advance()
+	case ERRSET:
+		var target value.CodePointValue
+		target, err = assumeCodePoint(m.stack.peek(0))
+		if err == nil {
+			m.stack.pop()
+			m.errPC = target
+			err = m.advance()
+		}
+	case DUP0:
+		m.stack.push(m.stack.peek(0))
+		err = m.advance()
+	case DUP1:
+		m.stack.push(m.stack.peek(1))
+		err = m.advance()
+	case DUP2:
+		m.stack.push(m.stack.peek(2))
+		err = m.advance()
+	case SWAP1:
+		top, second := m.stack.peek(0), m.stack.peek(1)
+		m.stack.set(0, second)
+		m.stack.set(1, top)
+		err = m.advance()
+	case SWAP2:
+		top, third := m.stack.peek(0), m.stack.peek(2)
+		m.stack.set(0, third)
+		m.stack.set(2, top)
+		err = m.advance()
+	case TGET:
+		err = m.tget()
+	case TSET:
+		err = m.tset()
+	case TLEN:
+		var tup *value.TupleValue
+		tup, err = assumeTuple(m.stack.peek(0))
+		if err == nil {
+			m.stack.set(0, value.NewInt64Value(tup.Len()))
+			err = m.advance()
+		}
+	case XGET:
+		err = m.xget()
+	case XSET:
+		err = m.xset()
+	case BREAKPOINT:
+		if m.context.stopOnBreakpoint && !m.context.firstInstruction {
+			return machine.BreakpointBlocked{}, nil
+		}
+		err = m.advance()
+	case LOG:
+		m.context.logs = append(m.context.logs, m.stack.pop())
+		err = m.advance()
+	case DEBUGPRINT:
+		m.context.debugPrints = append(m.context.debugPrints, m.stack.pop())
+		err = m.advance()
+	case SEND:
+		err = m.send()
+	case INBOX:
+		if len(m.context.inbox) == 0 {
+			return machine.InboxBlocked{}, nil
+		}
+		msg := m.context.inbox[0]
+		m.context.inbox = m.context.inbox[1:]
+		m.context.messagesRead++
+		m.stack.push(msg.AsValue())
+		err = m.advance()
+	case ERROR:
+		err = avmError("error instruction")
+	case HALT:
+		m.status = machine.Halt
+	case SETGAS:
+		var gas *big.Int
+		gas, err = assumeInt(m.stack.peek(0))
+		if err == nil {
+			m.stack.pop()
+			m.gasRemaining = gas
+			err = m.advance()
+		}
+	case PUSHGAS:
+		m.stack.push(value.NewIntValue(new(big.Int).Set(m.gasRemaining)))
+		err = m.advance()
+	case ERRCODEPOINT:
+		m.stack.push(ErrorCodePoint)
+		err = m.advance()
+	case PUSHINSN:
+		err = m.pushInsn()
+	case PUSHINSNIMM:
+		err = m.pushInsnImm()
+	case SIDELOAD:
+		return m.sideload()
+	case NEWBUFFER:
+		m.stack.push(value.NewBuffer(nil))
+		err = m.advance()
+	case GETBUFFER8:
+		err = m.getBuffer(1)
+	case GETBUFFER64:
+		err = m.getBuffer(8)
+	case GETBUFFER256:
+		err = m.getBuffer(32)
+	case SETBUFFER8:
+		err = m.setBuffer(1)
+	case SETBUFFER64:
+		err = m.setBuffer(8)
+	case SETBUFFER256:
+		err = m.setBuffer(32)
+	case ECRECOVER:
+		err = m.ecRecover()
+	case ECADD:
+		err = m.ecAdd()
+	case ECMUL:
+		err = m.ecMul()
+	case ECPAIRING:
+		err = m.ecPairing()
+	default:
+		err = avmError("unhandled opcode")
+	}
+	return nil, err
+}
+
+func (m *Machine) unaryOp(f func(a *big.Int) (*big.Int, error)) error {
+	a, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	res, err := f(a)
+	if err != nil {
+		return err
+	}
+	m.stack.set(0, intValue(res))
+	return m.advance()
+}
+
+func (m *Machine) binaryOp(f func(a, b *big.Int) (*big.Int, error)) error {
+	a, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	b, err := assumeInt(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	res, err := f(a, b)
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.set(0, intValue(res))
+	return m.advance()
+}
+
+func (m *Machine) ternaryOp(f func(a, b, c *big.Int) (*big.Int, error)) error {
+	a, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	b, err := assumeInt(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	c, err := assumeInt(m.stack.peek(2))
+	if err != nil {
+		return err
+	}
+	res, err := f(a, b, c)
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, intValue(res))
+	return m.advance()
+}
+
+// valueType returns the type code instructions see for val, which treats a
+// HashPreImage as the tuple it stands in for
+func valueType(val value.Value) uint8 {
+	switch val.(type) {
+	case value.IntValue:
+		return value.TypeCodeInt
+	case value.CodePointValue:
+		return value.TypeCodeCodePoint
+	case *value.Buffer:
+		return value.TypeCodeBuffer
+	default:
+		return value.TypeCodeTuple
+	}
+}
+
+func valuesEqual(a, b value.Value) bool {
+	aInt, aIsInt := a.(value.IntValue)
+	bInt, bIsInt := b.(value.IntValue)
+	if aIsInt && bIsInt {
+		return aInt.BigInt().Cmp(bInt.BigInt()) == 0
+	}
+	aTup, aIsTup := a.(*value.TupleValue)
+	bTup, bIsTup := b.(*value.TupleValue)
+	if aIsTup && bIsTup && aTup.Len() != bTup.Len() {
+		return false
+	}
+	if valueType(a) != valueType(b) {
+		return false
+	}
+	return a.Hash() == b.Hash()
+}
+
+func (m *Machine) cjump() error {
+	target, err := assumeCodePoint(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	cond, err := assumeInt(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.pop()
+	if cond.Sign() != 0 {
+		m.pc = target
+		return nil
+	}
+	return m.advance()
+}
+
+// tupleWithElement returns a copy of tup with the item at index replaced
+func tupleWithElement(tup *value.TupleValue, index uint64, val value.Value) (*value.TupleValue, error) {
+	if index >= uint64(tup.Len()) {
+		return nil, errTupleIndex
+	}
+	var contents [value.MaxTupleSize]value.Value
+	copy(contents[:], tup.Contents())
+	contents[index] = val
+	return value.NewTupleOfSizeWithContents(contents, int8(tup.Len()))
+}
+
+func tupleElement(tup *value.TupleValue, index uint64) (value.Value, error) {
+	if index >= uint64(tup.Len()) {
+		return nil, errTupleIndex
+	}
+	return tup.GetByInt64(int64(index))
+}
+
+func (m *Machine) tget() error {
+	index, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	tup, err := assumeTuple(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	val, err := tupleElement(tup, index)
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.set(0, val)
+	return m.advance()
+}
+
+func (m *Machine) tset() error {
+	index, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	tup, err := assumeTuple(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	newTup, err := tupleWithElement(tup, index, m.stack.peek(2))
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, newTup)
+	return m.advance()
+}
+
+func (m *Machine) xget() error {
+	index, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	tup, err := assumeTuple(m.auxStack.peek(0))
+	if err != nil {
+		return err
+	}
+	val, err := tupleElement(tup, index)
+	if err != nil {
+		return err
+	}
+	m.stack.set(0, val)
+	return m.advance()
+}
+
+func (m *Machine) xset() error {
+	index, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	tup, err := assumeTuple(m.auxStack.peek(0))
+	if err != nil {
+		return err
+	}
+	newTup, err := tupleWithElement(tup, index, m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	m.auxStack.set(0, newTup)
+	m.stack.pop()
+	m.stack.pop()
+	return m.advance()
+}
+
+func (m *Machine) send() error {
+	size, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	buf, err := assumeBuffer(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	if size > sendSizeLimit || bufferLastIndex(buf) >= size || size == 0 {
+		return avmError("invalid send size")
+	}
+	data := make([]byte, size)
+	copy(data, buf.Data())
+	m.context.sends = append(m.context.sends, data)
+	m.stack.pop()
+	m.stack.pop()
+	return m.advance()
+}
+
+func (m *Machine) pushInsn() error {
+	op, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	target, err := assumeCodePoint(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	cp := m.code.add(value.CodePointValue{
+		Op:       value.BasicOperation{Op: value.Opcode(op.Uint64())},
+		NextHash: target.Hash(),
+	})
+	m.stack.pop()
+	m.stack.set(0, cp)
+	return m.advance()
+}
+
+func (m *Machine) pushInsnImm() error {
+	op, err := assumeInt(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	target, err := assumeCodePoint(m.stack.peek(2))
+	if err != nil {
+		return err
+	}
+	cp := m.code.add(value.CodePointValue{
+		Op:       value.ImmediateOperation{Op: value.Opcode(op.Uint64()), Val: m.stack.peek(1)},
+		NextHash: target.Hash(),
+	})
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, cp)
+	return m.advance()
+}
+
+func (m *Machine) sideload() (machine.BlockReason, error) {
+	if _, err := assumeInt(m.stack.peek(0)); err != nil {
+		return nil, err
+	}
+	if len(m.context.sideloads) > 0 {
+		last := len(m.context.sideloads) - 1
+		m.stack.set(0, m.context.sideloads[last].AsValue())
+		m.context.sideloads = m.context.sideloads[:last]
+	} else {
+		if m.context.stopOnSideload && !m.context.firstInstruction {
+			return sideloadBlocked{}, nil
+		}
+		m.stack.set(0, value.NewEmptyTuple())
+	}
+	return nil, m.advance()
+}
+
+// bufferPackedLength returns the length of buf without trailing zeros
+func bufferPackedLength(buf *value.Buffer) uint64 {
+	data := buf.Data()
+	length := len(data)
+	for length > 0 && data[length-1] == 0 {
+		length--
+	}
+	return uint64(length)
+}
+
+// bufferLastIndex returns the index of the last nonzero byte of buf, or 0 if
+// it's empty
+func bufferLastIndex(buf *value.Buffer) uint64 {
+	if length := bufferPackedLength(buf); length > 0 {
+		return length - 1
+	}
+	return 0
+}
+
+func bufferGet(buf *value.Buffer, offset uint64, length uint64) []byte {
+	ret := make([]byte, length)
+	data := buf.Data()
+	if offset < uint64(len(data)) {
+		copy(ret, data[offset:])
+	}
+	return ret
+}
+
+// bufferSet returns a copy of buf with data written at offset
+func bufferSet(buf *value.Buffer, offset uint64, data []byte) (*value.Buffer, error) {
+	oldData := buf.Data()
+	end := offset + uint64(len(data))
+	for end > offset && data[end-offset-1] == 0 && end > uint64(len(oldData)) {
+		// Zeros written past the end of the buffer don't change it
+		end--
+	}
+	if end <= offset && offset >= uint64(len(oldData)) {
+		return buf, nil
+	}
+	if end > maxBufferDataLength {
+		return nil, ErrBufferTooLarge
+	}
+	newLength := uint64(len(oldData))
+	if end > newLength {
+		newLength = end
+	}
+	newData := make([]byte, newLength)
+	copy(newData, oldData)
+	copy(newData[offset:end], data)
+	return value.NewBuffer(newData), nil
+}
+
+func (m *Machine) getBuffer(wordSize uint64) error {
+	offset, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	buf, err := assumeBuffer(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	if offset+wordSize-1 < offset {
+		return errIntOutOfRange
+	}
+	res := new(big.Int).SetBytes(bufferGet(buf, offset, wordSize))
+	m.stack.pop()
+	m.stack.set(0, value.NewIntValue(res))
+	return m.advance()
+}
+
+func (m *Machine) setBuffer(wordSize uint64) error {
+	offset, err := assumeUint64(m.stack.peek(0))
+	if err != nil {
+		return err
+	}
+	val, err := assumeInt(m.stack.peek(1))
+	if err != nil {
+		return err
+	}
+	switch wordSize {
+	case 1:
+		if val.Cmp(big.NewInt(255)) > 0 {
+			return errIntOutOfRange
+		}
+	case 8:
+		if val.Cmp(maxUint64) > 0 {
+			return errIntOutOfRange
+		}
+	}
+	buf, err := assumeBuffer(m.stack.peek(2))
+	if err != nil {
+		return err
+	}
+	if offset+wordSize-1 < offset {
+		return errIntOutOfRange
+	}
+	newBuf, err := bufferSet(buf, offset, ethmath.PaddedBigBytes(val, int(wordSize)))
+	if err != nil {
+		return err
+	}
+	m.stack.pop()
+	m.stack.pop()
+	m.stack.set(0, newBuf)
+	return m.advance()
+}
+
+func (m *Machine) ecPairingVariableGasCost() uint64 {
+	if m.stack.size() == 0 {
+		return 0
+	}
+	cost := uint64(0)
+	val := m.stack.peek(0)
+	for i := 0; i < maxECPairingPoints; i++ {
+		tup, ok := val.(*value.TupleValue)
+		if !ok || tup.Len() == 0 {
+			break
+		}
+		if tup.Len() != 2 {
+			break
+		}
+		val = tup.Contents()[1]
+		cost += ecPairingPointCost
+	}
+	return cost
+}
@@ -0,0 +1,339 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func childLevel(level int) int {
+	if level > 0 {
+		return level - 1
+	}
+	return 0
+}
+
+func marshalPreImageForProof(data []byte, preImage value.HashPreImage) []byte {
+	data = append(data, value.TypeCodeHashPreImage)
+	return marshalPreImage(data, preImage)
+}
+
+func marshalPreImage(data []byte, preImage value.HashPreImage) []byte {
+	inner := preImage.GetInnerHash()
+	data = append(data, inner[:]...)
+	return append(data, hashing.Uint256(big.NewInt(preImage.Size()))...)
+}
+
+// marshalValueForProof appends the proof encoding of val to data. Tuples are
+// expanded level deep, below which they're replaced by their HashPreImage.
+func marshalValueForProof(data []byte, val value.Value, level int) ([]byte, error) {
+	switch val := val.(type) {
+	case value.IntValue:
+		data = append(data, value.TypeCodeInt)
+		return append(data, hashing.Uint256(val.BigInt())...), nil
+	case *value.TupleValue:
+		if level == 0 {
+			return marshalPreImageForProof(data, val.GetPreImage()), nil
+		}
+		data = append(data, value.TypeCodeTuple+uint8(val.Len()))
+		for _, item := range val.Contents() {
+			var err error
+			data, err = marshalValueForProof(data, item, childLevel(level))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	case value.HashPreImage:
+		return marshalPreImageForProof(data, val), nil
+	case value.CodePointValue:
+		data = append(data, value.TypeCodeCodePoint)
+		switch op := val.Op.(type) {
+		case value.ImmediateOperation:
+			data = append(data, 1, uint8(op.Op))
+			var err error
+			data, err = marshalValueForProof(data, op.Val, childLevel(level))
+			if err != nil {
+				return nil, err
+			}
+		default:
+			data = append(data, 0, uint8(op.GetOp()))
+		}
+		return append(data, val.NextHash[:]...), nil
+	case *value.Buffer:
+		data = append(data, value.TypeCodeBuffer)
+		root := val.RootHash()
+		return append(data, root[:]...), nil
+	default:
+		return nil, errors.Errorf("can't marshal %T for proof", val)
+	}
+}
+
+func (m *Machine) marshalState(
+	data []byte,
+	nextHash common.Hash,
+	stackPreImage value.HashPreImage,
+	auxStackPreImage value.HashPreImage,
+) ([]byte, error) {
+	data = append(data, nextHash[:]...)
+	data = marshalPreImage(data, stackPreImage)
+	data = marshalPreImage(data, auxStackPreImage)
+	var err error
+	data, err = marshalValueForProof(data, m.register, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err = marshalValueForProof(data, m.static, 0)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, hashing.Uint256(m.gasRemaining)...)
+	errPCHash := m.errPC.Hash()
+	return append(data, errPCHash[:]...), nil
+}
+
+// MarshalState returns the full machine state in the encoding used by one
+// step proofs
+func (m *Machine) MarshalState() ([]byte, error) {
+	return m.marshalState(nil, m.pc.Hash(), m.stack.preImage(), m.auxStack.preImage())
+}
+
+// MarshalForProof returns the one step proof of the current instruction,
+// split into the standard proof and the buffer proof
+func (m *Machine) MarshalForProof() ([]byte, []byte, error) {
+	op := m.pc.Op.GetOp()
+	immOp, hasImmediate := m.pc.Op.(value.ImmediateOperation)
+	info, ok := opcodes[op]
+	if !ok {
+		info = opcodes[ERROR]
+	}
+	stackPops := info.stackPops
+	immediateLevel := 0
+	if hasImmediate && len(stackPops) > 0 {
+		immediateLevel = stackPops[0]
+		stackPops = stackPops[1:]
+	}
+
+	stackData, stackBottom, stackCount, err := m.stack.marshalForProof(stackPops)
+	if err != nil {
+		return nil, nil, err
+	}
+	auxData, auxBottom, auxCount, err := m.auxStack.marshalForProof(info.auxStackPops)
+	if err != nil {
+		return nil, nil, err
+	}
+	underflowed := stackCount < len(stackPops) || auxCount < len(info.auxStackPops)
+
+	immCount := uint8(0)
+	if hasImmediate {
+		immCount = 1
+	}
+	proof := []byte{uint8(op), uint8(stackCount) + immCount, uint8(auxCount)}
+	proof = append(proof, stackData...)
+	if hasImmediate {
+		proof, err = marshalValueForProof(proof, immOp.Val, immediateLevel)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	proof = append(proof, auxData...)
+	proof, err = m.marshalState(proof, m.pc.NextHash, stackBottom, auxBottom)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof = append(proof, immCount)
+
+	var bufferProof []byte
+	if !underflowed {
+		// A buffer proof isn't needed if the instruction underflows
+		proof, bufferProof, err = m.marshalBufferProof(proof)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return proof, bufferProof, nil
+}
+
+// insertSizes appends the header of a buffer proof, which gives the offset in
+// words of each of its sections
+func insertSizes(data []byte, sizes ...int) []byte {
+	header := make([]byte, 32)
+	acc := 1
+	header[0] = uint8(acc)
+	for i, size := range sizes {
+		acc += size / 32
+		header[i+1] = uint8(acc)
+	}
+	return append(data, header...)
+}
+
+// operandArg returns the value of an instruction's first operand, which is
+// the immediate if it has one, and the stack item at index otherwise. Later
+// operands are at index-1 if there's an immediate.
+func (m *Machine) operandArg(index int) value.Value {
+	if immOp, ok := m.pc.Op.(value.ImmediateOperation); ok {
+		if index == 0 {
+			return immOp.Val
+		}
+		index--
+	}
+	if index >= m.stack.size() {
+		return nil
+	}
+	return m.stack.peek(index)
+}
+
+func (m *Machine) marshalBufferProof(proof []byte) ([]byte, []byte, error) {
+	op := m.pc.Op.GetOp()
+	if (op < GETBUFFER8 || op > SETBUFFER256) && op != SEND {
+		return proof, nil, nil
+	}
+	var bufferProof []byte
+	emptyProof := insertSizes(nil, 0, 0, 0, 0)
+
+	if op == SEND {
+		buf, ok := m.operandArg(1).(*value.Buffer)
+		if !ok {
+			return proof, nil, nil
+		}
+		size, ok := m.operandArg(0).(value.IntValue)
+		if !ok {
+			return proof, nil, nil
+		}
+		// The C++ implementation truncates the size to 64 bits here
+		loc := new(big.Int).And(size.BigInt(), maxUint64).Uint64()
+		data := buf.Data()
+		packedLength := bufferPackedLength(buf)
+		if loc > sendSizeLimit {
+			return proof, nil, nil
+		} else if loc < packedLength {
+			// loc must be at or past the last nonzero byte in the buffer
+			leafProof := buf.MakeProof(loc)
+			bufferProof = insertSizes(bufferProof, len(leafProof), 0, 0, 0)
+			bufferProof = append(bufferProof, leafProof...)
+		} else {
+			proof = append(proof, data[:packedLength]...)
+			proof = append(proof, make([]byte, loc-packedLength)...)
+		}
+		return proof, bufferProof, nil
+	}
+
+	isGet := op == GETBUFFER8 || op == GETBUFFER64 || op == GETBUFFER256
+	bufferIndex := 2
+	if isGet {
+		bufferIndex = 1
+	}
+	buf, ok := m.operandArg(bufferIndex).(*value.Buffer)
+	if !ok {
+		return proof, emptyProof, nil
+	}
+	offset, ok := m.operandArg(0).(value.IntValue)
+	if !ok || offset.BigInt().Cmp(maxUint64) > 0 {
+		return proof, emptyProof, nil
+	}
+	loc := offset.BigInt().Uint64()
+
+	if isGet {
+		leafProof := buf.MakeProof(loc)
+		if op == GETBUFFER8 {
+			bufferProof = insertSizes(bufferProof, len(leafProof), 0, 0, 0)
+			return proof, append(bufferProof, leafProof...), nil
+		}
+		lastByte := loc + 7
+		if op == GETBUFFER256 {
+			lastByte = loc + 31
+		}
+		leafProof2 := buf.MakeProof(lastByte)
+		bufferProof = insertSizes(bufferProof, len(leafProof), 0, len(leafProof2), 0)
+		bufferProof = append(bufferProof, leafProof...)
+		return proof, append(bufferProof, leafProof2...), nil
+	}
+
+	val, ok := m.operandArg(1).(value.IntValue)
+	if !ok {
+		return proof, emptyProof, nil
+	}
+	switch op {
+	case SETBUFFER8:
+		lowByte := uint8(new(big.Int).And(val.BigInt(), big.NewInt(0xff)).Uint64())
+		newBuf, err := bufferSet(buf, loc, []byte{lowByte})
+		if err != nil {
+			return nil, nil, err
+		}
+		leafProof := buf.MakeProof(loc)
+		normProof := newBuf.MakeNormalizationProof()
+		bufferProof = insertSizes(bufferProof, len(leafProof), len(normProof), 0, 0)
+		bufferProof = append(bufferProof, leafProof...)
+		bufferProof = append(bufferProof, normProof...)
+		return proof, bufferProof, nil
+	case SETBUFFER64:
+		bufferProof, err := makeSetBufferProof(buf, loc, val.BigInt(), 8)
+		return proof, bufferProof, err
+	default:
+		bufferProof, err := makeSetBufferProof(buf, loc, val.BigInt(), 32)
+		return proof, bufferProof, err
+	}
+}
+
+// makeSetBufferProof proves a write of wordSize bytes to buf. If the write
+// crosses a leaf boundary, the proof also includes the state of the buffer
+// after writing the first leaf.
+func makeSetBufferProof(buf *value.Buffer, loc uint64, val *big.Int, wordSize uint64) ([]byte, error) {
+	word := make([]byte, wordSize)
+	valBytes := val.Bytes()
+	if uint64(len(valBytes)) > wordSize {
+		valBytes = valBytes[uint64(len(valBytes))-wordSize:]
+	}
+	copy(word[wordSize-uint64(len(valBytes)):], valBytes)
+
+	split := uint64(0)
+	for i := uint64(1); i < wordSize; i++ {
+		if (loc+i)%value.BufferLeafSize == 0 {
+			split = i
+		}
+	}
+	newBuf, err := bufferSet(buf, loc, word)
+	if err != nil {
+		return nil, err
+	}
+	leafProof := buf.MakeProof(loc)
+	if split == 0 {
+		normProof := newBuf.MakeNormalizationProof()
+		proof := insertSizes(nil, len(leafProof), len(normProof), 0, 0)
+		proof = append(proof, leafProof...)
+		return append(proof, normProof...), nil
+	}
+
+	partialBuf, err := bufferSet(buf, loc, word[:split])
+	if err != nil {
+		return nil, err
+	}
+	normProof1 := partialBuf.MakeNormalizationProof()
+	leafProof2 := partialBuf.MakeProof(loc + wordSize - 1)
+	normProof2 := newBuf.MakeNormalizationProof()
+	proof := insertSizes(nil, len(leafProof), len(normProof1), len(leafProof2), len(normProof2))
+	proof = append(proof, leafProof...)
+	proof = append(proof, normProof1...)
+	proof = append(proof, leafProof2...)
+	return append(proof, normProof2...), nil
+}
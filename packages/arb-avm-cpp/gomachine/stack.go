@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomachine
+
+import (
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+var emptyStackPreImage = value.NewEmptyTuple().GetPreImage()
+
+// stack is a data stack, hashed as a linked list of 2-tuples. The hash of
+// each prefix of the stack is cached, so pushes and pops only need to hash
+// the items which changed.
+type stack struct {
+	values []value.Value
+	// preImages[i] is the HashPreImage of the stack made up of values[:i+1]
+	preImages []value.HashPreImage
+}
+
+func (s *stack) clone() *stack {
+	return &stack{
+		values:    append([]value.Value(nil), s.values...),
+		preImages: append([]value.HashPreImage(nil), s.preImages...),
+	}
+}
+
+func (s *stack) size() int {
+	return len(s.values)
+}
+
+func (s *stack) push(val value.Value) {
+	s.values = append(s.values, val)
+}
+
+func (s *stack) pop() value.Value {
+	val := s.values[len(s.values)-1]
+	s.truncate(len(s.values) - 1)
+	return val
+}
+
+// peek returns the value i items below the top of the stack
+func (s *stack) peek(i int) value.Value {
+	return s.values[len(s.values)-1-i]
+}
+
+// set replaces the value i items below the top of the stack
+func (s *stack) set(i int, val value.Value) {
+	index := len(s.values) - 1 - i
+	s.values[index] = val
+	if len(s.preImages) > index {
+		s.preImages = s.preImages[:index]
+	}
+}
+
+func (s *stack) truncate(size int) {
+	s.values = s.values[:size]
+	if len(s.preImages) > size {
+		s.preImages = s.preImages[:size]
+	}
+}
+
+// preImageOfSize returns the HashPreImage of the bottom size items of the
+// stack
+func (s *stack) preImageOfSize(size int) value.HashPreImage {
+	for len(s.preImages) < size {
+		prev := emptyStackPreImage
+		if len(s.preImages) > 0 {
+			prev = s.preImages[len(s.preImages)-1]
+		}
+		tup := value.NewTuple2(s.values[len(s.preImages)], prev)
+		s.preImages = append(s.preImages, tup.GetPreImage())
+	}
+	if size == 0 {
+		return emptyStackPreImage
+	}
+	return s.preImages[size-1]
+}
+
+func (s *stack) preImage() value.HashPreImage {
+	return s.preImageOfSize(len(s.values))
+}
+
+func (s *stack) hash() common.Hash {
+	return s.preImage().Hash()
+}
+
+// marshalForProof marshals the top items of the stack which an instruction
+// reads, deepest first, followed by the HashPreImage of the rest of the
+// stack. If the stack underflows, everything is marshalled at level 0.
+func (s *stack) marshalForProof(pops []int) ([]byte, value.HashPreImage, int, error) {
+	count := len(pops)
+	underflow := false
+	if count > s.size() {
+		count = s.size()
+		underflow = true
+	}
+	var data []byte
+	for i := count - 1; i >= 0; i-- {
+		level := 0
+		if !underflow {
+			level = pops[i]
+		}
+		var err error
+		data, err = marshalValueForProof(data, s.peek(i), level)
+		if err != nil {
+			return nil, value.HashPreImage{}, 0, err
+		}
+	}
+	return data, s.preImageOfSize(s.size() - count), count, nil
+}
@@ -75,3 +75,11 @@ func ArbOSTestFiles() ([]string, error) {
 
 	return filenames, nil
 }
+
+func ArbOSFile() (string, error) {
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		return "", errors.New("failed to get filename")
+	}
+	return filepath.Join(filepath.Dir(filename), "../../arb-os/arb_os/arbos.mexe"), nil
+}
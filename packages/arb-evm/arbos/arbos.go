@@ -18,6 +18,7 @@ var ARB_OWNER_ADDRESS = ethcommon.HexToAddress("0x000000000000000000000000000000
 var ARB_GAS_INFO_ADDRESS = ethcommon.HexToAddress("0x000000000000000000000000000000000000006C")
 var ARB_AGGREGATOR_ADDRESS = ethcommon.HexToAddress("0x000000000000000000000000000000000000006D")
 var ARB_RETRYABLE_ADDRESS = ethcommon.HexToAddress("0x000000000000000000000000000000000000006E")
+var ARB_STATISTICS_ADDRESS = ethcommon.HexToAddress("0x000000000000000000000000000000000000006F")
 
 var ARB_NODE_INTERFACE_ADDRESS = ethcommon.HexToAddress("0x00000000000000000000000000000000000000C8")
 
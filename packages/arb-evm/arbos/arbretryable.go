@@ -32,11 +32,14 @@ import (
 )
 
 var (
-	RetryCanceledEvent abi.Event
-	RetryRedeemedEvent abi.Event
+	RetryCanceledEvent         abi.Event
+	RetryRedeemedEvent         abi.Event
+	RetryTicketCreatedEvent    abi.Event
+	RetryLifetimeExtendedEvent abi.Event
 
 	createRetryableTicketABI abi.Method
 	redeemABI                abi.Method
+	getTimeoutABI            abi.Method
 )
 
 func init() {
@@ -52,7 +55,10 @@ func init() {
 
 	RetryCanceledEvent = parsedABI.Events["Canceled"]
 	RetryRedeemedEvent = parsedABI.Events["Redeemed"]
+	RetryTicketCreatedEvent = parsedABI.Events["TicketCreated"]
+	RetryLifetimeExtendedEvent = parsedABI.Events["LifetimeExtended"]
 	redeemABI = parsedABI.Methods["redeem"]
+	getTimeoutABI = parsedABI.Methods["getTimeout"]
 	createRetryableTicketABI = creatorABI.Methods["createRetryableTicket"]
 }
 
@@ -77,6 +83,33 @@ func RedeemData(txId common.Hash) []byte {
 	return append(redeemABI.ID, txId[:]...)
 }
 
+// ParseRedeemData returns the ticket ID being redeemed if data is a call to
+// ArbRetryableTx.redeem
+func ParseRedeemData(data []byte) (common.Hash, bool) {
+	if len(data) != 4+32 || !bytes.Equal(data[:4], redeemABI.ID) {
+		return common.Hash{}, false
+	}
+	var txId common.Hash
+	copy(txId[:], data[4:])
+	return txId, true
+}
+
+func GetTimeoutData(txId common.Hash) []byte {
+	return makeFuncData(getTimeoutABI, [32]byte(txId))
+}
+
+func ParseGetTimeoutResult(data []byte) (*big.Int, error) {
+	vals, err := getTimeoutABI.Outputs.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := vals[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected tx result")
+	}
+	return val, nil
+}
+
 func ParseCreateRetryableTicketTx(tx *types.Transaction) (*message.RetryableTx, error) {
 	if !bytes.Equal(tx.Data()[:4], createRetryableTicketABI.ID) {
 		return nil, errors.New("bad func id")
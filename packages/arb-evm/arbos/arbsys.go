@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
@@ -37,6 +38,8 @@ var (
 	chainIdABI      abi.Method
 
 	L2ToL1TransactionID ethcommon.Hash
+
+	arbSysFilterer *arboscontracts.ArbSysFilterer
 )
 
 func init() {
@@ -52,6 +55,24 @@ func init() {
 	chainIdABI = arbsys.Methods["arbChainID"]
 
 	L2ToL1TransactionID = arbsys.Events["L2ToL1Transaction"].ID
+
+	arbSysFilterer, err = arboscontracts.NewArbSysFilterer(ARB_SYS_ADDRESS, nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseL2ToL1TransactionLog decodes an L2ToL1Transaction event emitted by
+// ArbSys from its topics and data
+func ParseL2ToL1TransactionLog(topics []ethcommon.Hash, data []byte) (*arboscontracts.ArbSysL2ToL1Transaction, error) {
+	if len(topics) == 0 || topics[0] != L2ToL1TransactionID {
+		return nil, errors.New("not an L2ToL1Transaction event")
+	}
+	return arbSysFilterer.ParseL2ToL1Transaction(types.Log{
+		Address: ARB_SYS_ADDRESS,
+		Topics:  topics,
+		Data:    data,
+	})
 }
 
 func TransactionCountData(address common.Address) []byte {
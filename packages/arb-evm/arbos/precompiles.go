@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arbos
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+)
+
+// Precompiles is a typed client for every ArbOS precompile, made of the
+// bindings generated from their ABIs in arboscontracts. The backend can be a
+// node, such as web3.EthClient or ethutils.RPCEthClient, or a
+// snapshot.ContractBackend to run directly against a machine.
+type Precompiles struct {
+	ArbSys           *arboscontracts.ArbSys
+	ArbInfo          *arboscontracts.ArbInfo
+	ArbAddressTable  *arboscontracts.ArbAddressTable
+	ArbBLS           *arboscontracts.ArbBLS
+	ArbFunctionTable *arboscontracts.ArbFunctionTable
+	ArbosTest        *arboscontracts.ArbosTest
+	ArbOwner         *arboscontracts.ArbOwner
+	ArbGasInfo       *arboscontracts.ArbGasInfo
+	ArbAggregator    *arboscontracts.ArbAggregator
+	ArbRetryableTx   *arboscontracts.ArbRetryableTx
+	ArbStatistics    *arboscontracts.ArbStatistics
+	// NodeInterface isn't part of ArbOS, but is served by nodes at a
+	// precompile address, so it's only available with a node backend
+	NodeInterface *arboscontracts.NodeInterface
+}
+
+func NewPrecompiles(backend bind.ContractBackend) (*Precompiles, error) {
+	p := &Precompiles{}
+	var err error
+	if p.ArbSys, err = arboscontracts.NewArbSys(ARB_SYS_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbInfo, err = arboscontracts.NewArbInfo(ARB_INFO_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbAddressTable, err = arboscontracts.NewArbAddressTable(ARB_ADDRESS_TABLE_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbBLS, err = arboscontracts.NewArbBLS(ARB_BLS_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbFunctionTable, err = arboscontracts.NewArbFunctionTable(ARB_FUNCTION_TABLE_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbosTest, err = arboscontracts.NewArbosTest(ARB_TEST_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbOwner, err = arboscontracts.NewArbOwner(ARB_OWNER_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbGasInfo, err = arboscontracts.NewArbGasInfo(ARB_GAS_INFO_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbAggregator, err = arboscontracts.NewArbAggregator(ARB_AGGREGATOR_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbRetryableTx, err = arboscontracts.NewArbRetryableTx(ARB_RETRYABLE_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.ArbStatistics, err = arboscontracts.NewArbStatistics(ARB_STATISTICS_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	if p.NodeInterface, err = arboscontracts.NewNodeInterface(ARB_NODE_INTERFACE_ADDRESS, backend); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
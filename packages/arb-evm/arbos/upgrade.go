@@ -0,0 +1,50 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package arbos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// Maximum length in hex characters of each chunk passed to continueCodeUpload
+const upgradeChunkSize = 50000
+
+type upgrade struct {
+	Instructions []string `json:"instructions"`
+}
+
+// LoadUpgradeChunks reads an upgrade file and splits its instructions into
+// hex encoded chunks to be uploaded with continueCodeUpload
+func LoadUpgradeChunks(upgradeFile string) ([]string, error) {
+	updateBytes, err := ioutil.ReadFile(upgradeFile)
+	if err != nil {
+		return nil, err
+	}
+	upgrade := upgrade{}
+	if err := json.Unmarshal(updateBytes, &upgrade); err != nil {
+		return nil, err
+	}
+	chunks := []string{"0x"}
+	for _, insn := range upgrade.Instructions {
+		if len(chunks[len(chunks)-1])+len(insn) > upgradeChunkSize {
+			chunks = append(chunks, "0x")
+		}
+		chunks[len(chunks)-1] += insn
+	}
+	return chunks, nil
+}
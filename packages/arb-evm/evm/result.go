@@ -487,6 +487,16 @@ func parseTxResult(l1MsgVal value.Value, resultInfo value.Value, gasInfo value.V
 	returnData, _ := resultTup.GetByInt64(1)
 	evmLogs, _ := resultTup.GetByInt64(2)
 
+	logs, err := LogStackToLogs(evmLogs)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling logs")
+	}
+	return newTxResult(l1MsgVal, resultCode, returnData, logs, gasInfo, chainInfo, feeStatsVal)
+}
+
+// newTxResult builds a tx result from the parts of its value, with the logs
+// already parsed
+func newTxResult(l1MsgVal value.Value, resultCode value.Value, returnData value.Value, logs []Log, gasInfo value.Value, chainInfo value.Value, feeStatsVal value.Value) (*TxResult, error) {
 	gasInfoTup, ok := gasInfo.(*value.TupleValue)
 	if !ok || gasInfoTup.Len() != 2 {
 		return nil, errors.Errorf("advise expected gas info tuple of length 2, but recieved %v", gasInfoTup)
@@ -498,7 +508,7 @@ func parseTxResult(l1MsgVal value.Value, resultInfo value.Value, gasInfo value.V
 
 	chainInfoTup, ok := chainInfo.(*value.TupleValue)
 	if !ok || chainInfoTup.Len() != 3 {
-		return nil, errors.Errorf("advise expected tx block data tuple of length 3, but recieved %v", resultTup)
+		return nil, errors.Errorf("advise expected tx block data tuple of length 3, but recieved %v", chainInfo)
 	}
 
 	// Tuple size already verified above, so error can be ignored
@@ -514,10 +524,6 @@ func parseTxResult(l1MsgVal value.Value, resultInfo value.Value, gasInfo value.V
 	if err != nil {
 		return nil, errors.Wrap(err, "umarshalling return data")
 	}
-	logs, err := LogStackToLogs(evmLogs)
-	if err != nil {
-		return nil, errors.Wrap(err, "unmarshaling logs")
-	}
 	resultCodeInt, ok := resultCode.(value.IntValue)
 	if !ok {
 		return nil, errors.New("resultCode must be an int")
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package evm
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// expectTuple is a visitor for a part of a result which must be a tuple
+type expectTuple struct {
+	what  string
+	visit func(size int) (value.TupleVisitor, error)
+}
+
+func (v expectTuple) unexpected() error {
+	return errors.Errorf("expected %v to be a tuple", v.what)
+}
+
+func (v expectTuple) VisitInt(value.IntValue) error {
+	return v.unexpected()
+}
+
+func (v expectTuple) VisitBuffer([]byte) error {
+	return v.unexpected()
+}
+
+func (v expectTuple) VisitHashPreImage(value.HashPreImage) error {
+	return v.unexpected()
+}
+
+func (v expectTuple) VisitCodePoint(value.CodePointValue) error {
+	return v.unexpected()
+}
+
+func (v expectTuple) VisitCodePointStub(value.CodePointStub) error {
+	return v.unexpected()
+}
+
+func (v expectTuple) VisitTuple(size int) (value.TupleVisitor, error) {
+	return v.visit(size)
+}
+
+// tupleItems visits the items of a tuple with the given visitors, skipping
+// any items past the end of them
+type tupleItems struct {
+	items []value.Visitor
+	end   func() error
+}
+
+func (t tupleItems) Item(i int) (value.Visitor, error) {
+	if i >= len(t.items) {
+		return nil, nil
+	}
+	return t.items[i], nil
+}
+
+func (t tupleItems) End() error {
+	if t.end == nil {
+		return nil
+	}
+	return t.end()
+}
+
+func buildValue(dest *value.Value) value.Visitor {
+	return value.NewValueBuilder(func(val value.Value) error {
+		*dest = val
+		return nil
+	})
+}
+
+// logStackVisitor parses each log in a log stack as soon as it's decoded,
+// appending them to logs from the top of the stack down
+func logStackVisitor(logs *[]Log) value.Visitor {
+	return expectTuple{what: "log stack", visit: func(size int) (value.TupleVisitor, error) {
+		switch size {
+		case 0:
+			return tupleItems{}, nil
+		case 2:
+			parseLog := value.NewValueBuilder(func(val value.Value) error {
+				evmLog, err := NewLogFromValue(val)
+				if err != nil {
+					return err
+				}
+				*logs = append(*logs, evmLog)
+				return nil
+			})
+			return tupleItems{items: []value.Visitor{parseLog, logStackVisitor(logs)}}, nil
+		default:
+			return nil, errors.Errorf("log stack expected tuple of length 0 or 2, but received len %v", size)
+		}
+	}}
+}
+
+// NewTxResultFromReader decodes a serialized tx result within the limits of
+// config. Unlike NewTxResultFromValue, the result is parsed as it's read, so
+// its EVM logs are built one at a time rather than as one large stack.
+func NewTxResultFromReader(r io.Reader, config value.DecoderConfig) (*TxResult, error) {
+	var l1MsgVal, resultCode, returnData, gasInfo, chainInfo, feeStats value.Value
+	logs := make([]Log, 0)
+	var res *TxResult
+
+	kind := value.NewValueBuilder(func(val value.Value) error {
+		kindInt, ok := val.(value.IntValue)
+		if !ok {
+			return errors.New("result kind must be an int")
+		}
+		if kindInt.BigInt().Sign() != 0 {
+			return errors.Errorf("got result kind %v but expected TxResult", kindInt.BigInt())
+		}
+		return nil
+	})
+	resultInfo := expectTuple{what: "result info", visit: func(size int) (value.TupleVisitor, error) {
+		if size != 3 {
+			return nil, errors.Errorf("expected result info tuple of length 3, but received len %v", size)
+		}
+		items := []value.Visitor{buildValue(&resultCode), buildValue(&returnData), logStackVisitor(&logs)}
+		return tupleItems{items: items}, nil
+	}}
+	result := expectTuple{what: "result", visit: func(size int) (value.TupleVisitor, error) {
+		if size != 6 && size != 7 {
+			return nil, errors.Errorf("tx result expected tuple of length 6 or 7, but received len %v", size)
+		}
+		items := []value.Visitor{kind, buildValue(&l1MsgVal), resultInfo, buildValue(&gasInfo), buildValue(&chainInfo), buildValue(&feeStats)}
+		end := func() error {
+			// Logs were read from the top of the stack, but are ordered from
+			// the bottom
+			for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+				logs[i], logs[j] = logs[j], logs[i]
+			}
+			var err error
+			res, err = newTxResult(l1MsgVal, resultCode, returnData, logs, gasInfo, chainInfo, feeStats)
+			return err
+		}
+		return tupleItems{items: items, end: end}, nil
+	}}
+
+	if err := value.NewDecoder(r, config).Visit(result); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
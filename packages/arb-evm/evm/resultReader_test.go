@@ -0,0 +1,183 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package evm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func byteArrayTestValue(data []byte) value.Value {
+	return value.NewTuple2(value.NewInt64Value(int64(len(data))), value.NewBuffer(data))
+}
+
+func tupleTestValue(t *testing.T, items ...value.Value) value.Value {
+	t.Helper()
+	tup, err := value.NewTupleFromSlice(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tup
+}
+
+func intTestValue(val *big.Int) value.Value {
+	return value.NewIntValue(val)
+}
+
+func logTestValue(t *testing.T, l Log) value.Value {
+	items := []value.Value{inbox.NewIntFromAddress(l.Address), byteArrayTestValue(l.Data)}
+	for _, topic := range l.Topics {
+		items = append(items, value.NewIntValue(new(big.Int).SetBytes(topic[:])))
+	}
+	return tupleTestValue(t, items...)
+}
+
+// txResultTestValue builds the value ArbOS emits for a tx result with the
+// given logs, which are pushed onto the log stack in the order they were
+// emitted
+func txResultTestValue(t *testing.T, logs []Log) value.Value {
+	req := NewRandomIncomingRequest()
+	var messageID big.Int
+	messageID.SetBytes(req.MessageID[:])
+	zero := value.NewInt64Value(0)
+	provenance := tupleTestValue(t, zero, zero, zero)
+	reqVal := tupleTestValue(t,
+		value.NewInt64Value(int64(req.Kind)),
+		intTestValue(req.L2BlockNumber),
+		intTestValue(req.L1BlockNumber),
+		intTestValue(req.L2Timestamp),
+		inbox.NewIntFromAddress(req.Sender),
+		intTestValue(&messageID),
+		byteArrayTestValue(req.Data),
+		tupleTestValue(t, provenance, tupleTestValue(t, zero), zero),
+	)
+
+	logVals := make([]value.Value, 0, len(logs))
+	for _, l := range logs {
+		logVals = append(logVals, logTestValue(t, l))
+	}
+	resultInfo := tupleTestValue(t,
+		value.NewInt64Value(int64(ReturnCode)),
+		byteArrayTestValue(common.RandBytes(100)),
+		inbox.ListToStackValue(logVals),
+	)
+	randInt := func() value.Value {
+		return intTestValue(common.RandBigInt())
+	}
+	feeSet := func() value.Value {
+		return tupleTestValue(t, randInt(), randInt(), randInt(), randInt())
+	}
+	return tupleTestValue(t,
+		zero,
+		reqVal,
+		resultInfo,
+		tupleTestValue(t, randInt(), randInt()),
+		tupleTestValue(t, randInt(), randInt(), randInt()),
+		tupleTestValue(t, feeSet(), feeSet(), feeSet(), zero),
+	)
+}
+
+func checkSameTxResult(t *testing.T, res *TxResult, expected *TxResult) {
+	t.Helper()
+	if diffs := CompareIncomingRequests(res.IncomingRequest, expected.IncomingRequest); len(diffs) > 0 {
+		t.Error("different requests", diffs)
+	}
+	if res.ResultCode != expected.ResultCode || !bytes.Equal(res.ReturnData, expected.ReturnData) {
+		t.Error("different result")
+	}
+	if res.GasUsed.Cmp(expected.GasUsed) != 0 ||
+		res.GasPrice.Cmp(expected.GasPrice) != 0 ||
+		res.CumulativeGas.Cmp(expected.CumulativeGas) != 0 ||
+		res.TxIndex.Cmp(expected.TxIndex) != 0 ||
+		res.StartLogIndex.Cmp(expected.StartLogIndex) != 0 {
+		t.Error("different gas or chain info")
+	}
+	if res.FeeStats.Paid.L2Computation.Cmp(expected.FeeStats.Paid.L2Computation) != 0 {
+		t.Error("different fee stats")
+	}
+	if len(res.EVMLogs) != len(expected.EVMLogs) {
+		t.Fatalf("got %v logs but expected %v", len(res.EVMLogs), len(expected.EVMLogs))
+	}
+	for i := range res.EVMLogs {
+		if !res.EVMLogs[i].Equals(expected.EVMLogs[i]) {
+			t.Errorf("log %v is %v but expected %v", i, res.EVMLogs[i], expected.EVMLogs[i])
+		}
+	}
+}
+
+func TestTxResultFromReaderMatchesValue(t *testing.T) {
+	for _, logCount := range []int{0, 1, 2, 5} {
+		logs := make([]Log, 0, logCount)
+		for i := 0; i < logCount; i++ {
+			logs = append(logs, NewRandomLog(int32(i%4)))
+		}
+		val := txResultTestValue(t, logs)
+		fromValue, err := NewTxResultFromValue(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := value.MarshalValue(val, &buf); err != nil {
+			t.Fatal(err)
+		}
+		fromReader, err := NewTxResultFromReader(&buf, value.DefaultDecoderConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSameTxResult(t, fromReader, fromValue)
+
+		// The log stack is read from the top, so both must have undone the
+		// reversal to give the logs in the order they were emitted
+		for i := range logs {
+			if !fromReader.EVMLogs[i].Equals(logs[i]) {
+				t.Errorf("log %v of %v out of order", i, logCount)
+			}
+		}
+	}
+}
+
+func TestTxResultFromReaderErrors(t *testing.T) {
+	val := txResultTestValue(t, []Log{NewRandomLog(1), NewRandomLog(2), NewRandomLog(3)})
+	var buf bytes.Buffer
+	if err := value.MarshalValue(val, &buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Each log nests the log stack one level deeper
+	if _, err := NewTxResultFromReader(bytes.NewReader(data), value.DecoderConfig{MaxDepth: 5}); err == nil {
+		t.Error("expected depth limit to be hit")
+	}
+	if _, err := NewTxResultFromReader(bytes.NewReader(data[:len(data)-10]), value.DefaultDecoderConfig); err == nil {
+		t.Error("expected error for truncated result")
+	}
+
+	blockResult := tupleTestValue(t, value.NewInt64Value(1))
+	buf.Reset()
+	if err := value.MarshalValue(blockResult, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTxResultFromReader(&buf, value.DefaultDecoderConfig); err == nil {
+		t.Error("expected error for result of another kind")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
 	"math/big"
 )
@@ -73,3 +74,16 @@ func (t RetryableTx) Equals(o RetryableTx) bool {
 		t.GasPriceBid.Cmp(o.GasPriceBid) == 0 &&
 		bytes.Equal(t.Data, o.Data)
 }
+
+// RetryableTicketID returns the ID of the ticket created by the retryable
+// submission with the given request ID, which is also the request ID of the
+// ticket's eventual execution
+func RetryableTicketID(requestId common.Hash) common.Hash {
+	return hashing.SoliditySHA3(hashing.Bytes32(requestId), hashing.Uint256(big.NewInt(0)))
+}
+
+// RetryableAutoRedeemID returns the request ID of the automatic redeem
+// attempt made for the retryable submission with the given request ID
+func RetryableAutoRedeemID(requestId common.Hash) common.Hash {
+	return hashing.SoliditySHA3(hashing.Bytes32(requestId), hashing.Uint256(big.NewInt(1)))
+}
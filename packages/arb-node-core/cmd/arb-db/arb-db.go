@@ -24,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	golog "log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -47,21 +48,74 @@ func main() {
 
 	if err := startup(); err != nil {
 		logger.Error().Err(err).Msg("Error running arb-db")
+		os.Exit(1)
 	}
 }
 
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+// commands is filled in by init since the commands refer back to it through
+// printUsage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"export-inbox": {"--file=inbox.bin [--start=0] [--end=0]", exportInbox},
+		"import-inbox": {"--file=inbox.bin [--batch-size=1000]", importInbox},
+		"messages":     {"[--start=0] [--count=1]", inspectMessages},
+		"logs":         {"[--start=0] [--count=1]", inspectLogs},
+		"sends":        {"[--start=0] [--count=1]", inspectSends},
+		"accumulators": {"[--start=0] [--count=1] [--delayed]", inspectAccumulators},
+		"cursor":       {"--block=0", inspectCursor},
+		"machine":      {"--block=0 [--path=register/0] [--no-resolve]", inspectMachine},
+		"machine-diff": {"--from=0 [--to=1] [--path=register] [--no-resolve]", diffMachines},
+		"verify":       {"[--skip-inbox] [--skip-blocks] [--checkpoint-samples=10] [--checkpoint-distance=100]", verifyDatabase},
+		"replay":       {"--start=1 [--end=1] [--keep-going]", replayBlocks},
+	}
+}
+
+func printUsage() {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage: %s --persistent.chain='.arbitrum/mainnet' --core.database.metadata\n", os.Args[0])
+	fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.make-validator\n", os.Args[0])
+	fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.prune-on-startup\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("              %s %s --persistent.chain='.arbitrum/mainnet' %s\n", os.Args[0], name, commands[name].usage)
+	}
+}
+
+// usageError prints the usage and returns err, unless only help was requested
+func usageError(err error) error {
+	printUsage()
+	if strings.Contains(err.Error(), "help requested") {
+		return nil
+	}
+	return err
+}
+
 func startup() error {
-	config, err := configuration.ParseDBTool()
-	if err != nil || len(config.Persistent.Chain) == 0 {
-		fmt.Printf("\n")
-		fmt.Printf("Sample usage: %s --persistent.chain='.arbitrum/mainnet' --core.database.metadata\n", os.Args[0])
-		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.make-validator\n", os.Args[0])
-		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.prune-on-startup\n", os.Args[0])
-		if err != nil && !strings.Contains(err.Error(), "help requested") {
-			fmt.Printf("%s\n", err.Error())
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		cmd, ok := commands[os.Args[1]]
+		if !ok {
+			return usageError(errors.Errorf("unknown command %s", os.Args[1]))
 		}
+		return cmd.run(os.Args[2:])
+	}
 
-		return nil
+	config, err := configuration.ParseDBTool()
+	if err == nil && len(config.Persistent.Chain) == 0 {
+		err = errors.New("--persistent.chain is required")
+	}
+	if err != nil {
+		return usageError(err)
 	}
 
 	// Make sure arbcore does not continue to run
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/inboxarchive"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// parseCommand parses the configuration for a subcommand whose own flags
+// have already been added to f
+func parseCommand(f *flag.FlagSet, args []string) (*configuration.Config, error) {
+	config, err := configuration.ParseDBToolArgs(f, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Persistent.Chain) == 0 {
+		return nil, errors.New("--persistent.chain is required")
+	}
+	return config, nil
+}
+
+// openDatabase loads the ArbCore database, optionally starting the core
+// thread so that messages can be delivered
+func openDatabase(config *configuration.Config, requireExisting bool, startThread bool) (*monitor.Monitor, error) {
+	databasePath := config.GetDatabasePath()
+	exists := configuration.DatabaseInDirectory(databasePath)
+	if requireExisting && !exists {
+		return nil, errors.New("unable to access database in " + databasePath)
+	}
+	logger.Info().Str("path", databasePath).Msg("using database")
+
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		// An existing database is loaded from its last checkpoint, which
+		// doesn't need the machine executable
+		err = mon.ApplyConfig()
+	} else {
+		err = mon.Initialize(config.Rollup.Machine.Filename)
+	}
+	if err != nil {
+		mon.Close()
+		return nil, err
+	}
+	if startThread {
+		if err := mon.Start(); err != nil {
+			mon.Close()
+			return nil, err
+		}
+	}
+	return mon, nil
+}
+
+func exportInbox(args []string) error {
+	f := flag.NewFlagSet("export-inbox", flag.ContinueOnError)
+	f.String("file", "", "file to write the inbox archive to")
+	f.Uint64("start", 0, "first message to export, which must begin a sequencer batch item")
+	f.Uint64("end", 0, "stop exporting after the batch item containing the message before this one, 0 for no limit")
+
+	config, err := parseCommand(f, args)
+	if err != nil {
+		return usageError(err)
+	}
+	filename, _ := f.GetString("file")
+	if filename == "" {
+		return errors.New("--file is required")
+	}
+	start, _ := f.GetUint64("start")
+	endFlag, _ := f.GetUint64("end")
+	var end *big.Int
+	if endFlag != 0 {
+		end = new(big.Int).SetUint64(endFlag)
+	}
+
+	mon, err := openDatabase(config, true, false)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	trailer, err := inboxarchive.Export(mon.Core, out, new(big.Int).SetUint64(start), end)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Printf("Exported %v sequencer batch items and %v delayed messages to %s\n", trailer.SequencerBatchItemCount, trailer.DelayedMessageCount, filename)
+	fmt.Printf("Checksum: %v\n", trailer.Checksum)
+	return nil
+}
+
+func importInbox(args []string) error {
+	f := flag.NewFlagSet("import-inbox", flag.ContinueOnError)
+	f.String("file", "", "inbox archive file to import")
+	f.Int("batch-size", inboxarchive.DefaultImportBatchSize, "number of sequencer batch items to deliver to the database at a time")
+
+	config, err := parseCommand(f, args)
+	if err != nil {
+		return usageError(err)
+	}
+	filename, _ := f.GetString("file")
+	if filename == "" {
+		return errors.New("--file is required")
+	}
+	batchSize, _ := f.GetInt("batch-size")
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+
+	// A new database is created if one doesn't exist yet
+	mon, err := openDatabase(config, false, true)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	trailer, err := inboxarchive.Import(context.Background(), mon.Core, in, batchSize)
+	if err != nil {
+		return err
+	}
+	messageCount, err := mon.Core.GetMessageCount()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %v sequencer batch items and %v delayed messages from %s\n", trailer.SequencerBatchItemCount, trailer.DelayedMessageCount, filename)
+	fmt.Printf("Database now contains %v messages\n", messageCount)
+	return nil
+}
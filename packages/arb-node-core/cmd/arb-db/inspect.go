@@ -0,0 +1,528 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// Maximum number of entries to request from ArbCore at a time
+const inspectPageSize = 1000
+
+type rangeFlags struct {
+	f *flag.FlagSet
+}
+
+func addRangeFlags(f *flag.FlagSet, what string) rangeFlags {
+	f.Uint64("start", 0, "index of first "+what+" to print")
+	f.Uint64("count", 1, "number of "+what+"s to print, 0 for all remaining")
+	return rangeFlags{f: f}
+}
+
+// resolve returns the range requested, limited to the total available
+func (r rangeFlags) resolve(total *big.Int) (*big.Int, *big.Int, error) {
+	startFlag, _ := r.f.GetUint64("start")
+	countFlag, _ := r.f.GetUint64("count")
+	start := new(big.Int).SetUint64(startFlag)
+	if start.Cmp(total) > 0 {
+		return nil, nil, errors.Errorf("start %v is past the end %v", start, total)
+	}
+	remaining := new(big.Int).Sub(total, start)
+	count := new(big.Int).SetUint64(countFlag)
+	if countFlag == 0 || count.Cmp(remaining) > 0 {
+		count = remaining
+	}
+	return start, count, nil
+}
+
+// forEachPage calls fetch with consecutive pages covering count entries
+// beginning at start
+func forEachPage(start, count *big.Int, fetch func(start, count *big.Int) error) error {
+	end := new(big.Int).Add(start, count)
+	for pos := new(big.Int).Set(start); pos.Cmp(end) < 0; {
+		pageCount := new(big.Int).Sub(end, pos)
+		if pageCount.Cmp(big.NewInt(inspectPageSize)) > 0 {
+			pageCount.SetInt64(inspectPageSize)
+		}
+		if err := fetch(pos, pageCount); err != nil {
+			return err
+		}
+		pos = new(big.Int).Add(pos, pageCount)
+	}
+	return nil
+}
+
+// runInspect parses the flags for a read-only subcommand and runs inspect
+// against the opened database, printing each result as a JSON line
+func runInspect(f *flag.FlagSet, args []string, inspect func(lookup core.ArbCoreLookup, out *json.Encoder) error) error {
+	config, err := parseCommand(f, args)
+	if err != nil {
+		return usageError(err)
+	}
+	mon, err := openDatabase(config, true, false)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+	return inspect(mon.Core, json.NewEncoder(os.Stdout))
+}
+
+type messageJSON struct {
+	Index   *big.Int           `json:"index"`
+	Message inbox.InboxMessage `json:"message"`
+}
+
+func inspectMessages(args []string) error {
+	f := flag.NewFlagSet("messages", flag.ContinueOnError)
+	r := addRangeFlags(f, "inbox message")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		total, err := lookup.GetMessageCount()
+		if err != nil {
+			return err
+		}
+		start, count, err := r.resolve(total)
+		if err != nil {
+			return err
+		}
+		return forEachPage(start, count, func(pageStart, pageCount *big.Int) error {
+			messages, err := lookup.GetMessages(pageStart, pageCount)
+			if err != nil {
+				return err
+			}
+			for i, msg := range messages {
+				index := new(big.Int).Add(pageStart, big.NewInt(int64(i)))
+				if err := out.Encode(messageJSON{Index: index, Message: msg}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+type evmLogJSON struct {
+	Address common.Address   `json:"address"`
+	Topics  []ethcommon.Hash `json:"topics"`
+	Data    hexutil.Bytes    `json:"data"`
+}
+
+type feeSetJSON struct {
+	L1Transaction *big.Int `json:"l1Transaction"`
+	L1Calldata    *big.Int `json:"l1Calldata"`
+	L2Storage     *big.Int `json:"l2Storage"`
+	L2Computation *big.Int `json:"l2Computation"`
+}
+
+type feeStatsJSON struct {
+	Price      *feeSetJSON     `json:"price"`
+	UnitsUsed  *feeSetJSON     `json:"unitsUsed"`
+	Paid       *feeSetJSON     `json:"paid"`
+	Aggregator *common.Address `json:"aggregator"`
+}
+
+type txResultJSON struct {
+	RequestID     ethcommon.Hash `json:"requestId"`
+	Kind          inbox.Type     `json:"kind"`
+	Sender        common.Address `json:"sender"`
+	L1BlockNumber *big.Int       `json:"l1BlockNumber"`
+	L2BlockNumber *big.Int       `json:"l2BlockNumber"`
+	L2Timestamp   *big.Int       `json:"l2Timestamp"`
+	ResultCode    string         `json:"resultCode"`
+	ReturnData    hexutil.Bytes  `json:"returnData"`
+	GasUsed       *big.Int       `json:"gasUsed"`
+	GasPrice      *big.Int       `json:"gasPrice"`
+	CumulativeGas *big.Int       `json:"cumulativeGas"`
+	TxIndex       *big.Int       `json:"txIndex"`
+	StartLogIndex *big.Int       `json:"startLogIndex"`
+	EVMLogs       []evmLogJSON   `json:"evmLogs"`
+	FeeStats      *feeStatsJSON  `json:"feeStats"`
+}
+
+type outputStatisticsJSON struct {
+	GasUsed      *big.Int `json:"gasUsed"`
+	TxCount      *big.Int `json:"txCount"`
+	EVMLogCount  *big.Int `json:"evmLogCount"`
+	AVMLogCount  *big.Int `json:"avmLogCount"`
+	AVMSendCount *big.Int `json:"avmSendCount"`
+}
+
+type gasSummaryJSON struct {
+	PricePerL1CalldataByte   *big.Int `json:"pricePerL1CalldataByte"`
+	PricePerStorageCell      *big.Int `json:"pricePerStorageCell"`
+	PricePerArbGasBase       *big.Int `json:"pricePerArbGasBase"`
+	PricePerArbGasCongestion *big.Int `json:"pricePerArbGasCongestion"`
+	PricePerArbGasTotal      *big.Int `json:"pricePerArbGasTotal"`
+	GasPool                  *big.Int `json:"gasPool"`
+}
+
+type blockInfoJSON struct {
+	BlockNumber    *big.Int              `json:"blockNumber"`
+	Timestamp      *big.Int              `json:"timestamp"`
+	L1BlockNumber  *big.Int              `json:"l1BlockNumber"`
+	PreviousHeight *big.Int              `json:"previousHeight"`
+	BlockStats     *outputStatisticsJSON `json:"blockStats"`
+	ChainStats     *outputStatisticsJSON `json:"chainStats"`
+	GasSummary     *gasSummaryJSON       `json:"gasSummary"`
+}
+
+type sendResultJSON struct {
+	BatchNumber *big.Int      `json:"batchNumber"`
+	BatchIndex  *big.Int      `json:"batchIndex"`
+	Data        hexutil.Bytes `json:"data"`
+}
+
+type merkleRootResultJSON struct {
+	BatchNumber *big.Int       `json:"batchNumber"`
+	NumInBatch  *big.Int       `json:"numInBatch"`
+	Root        ethcommon.Hash `json:"root"`
+}
+
+type logJSON struct {
+	Index      *big.Int              `json:"index"`
+	InboxCount *big.Int              `json:"inboxCount"`
+	InboxAcc   ethcommon.Hash        `json:"inboxAcc"`
+	Type       string                `json:"type"`
+	Tx         *txResultJSON         `json:"tx,omitempty"`
+	Block      *blockInfoJSON        `json:"block,omitempty"`
+	Send       *sendResultJSON       `json:"send,omitempty"`
+	MerkleRoot *merkleRootResultJSON `json:"merkleRoot,omitempty"`
+	Error      string                `json:"error,omitempty"`
+}
+
+func newFeeSetJSON(fs *evm.FeeSet) *feeSetJSON {
+	if fs == nil {
+		return nil
+	}
+	return &feeSetJSON{
+		L1Transaction: fs.L1Transaction,
+		L1Calldata:    fs.L1Calldata,
+		L2Storage:     fs.L2Storage,
+		L2Computation: fs.L2Computation,
+	}
+}
+
+func newTxResultJSON(res *evm.TxResult) *txResultJSON {
+	logs := make([]evmLogJSON, 0, len(res.EVMLogs))
+	for _, evmLog := range res.EVMLogs {
+		logs = append(logs, evmLogJSON{
+			Address: evmLog.Address,
+			Topics:  common.NewEthHashesFromHashes(evmLog.Topics),
+			Data:    evmLog.Data,
+		})
+	}
+	var feeStats *feeStatsJSON
+	if res.FeeStats != nil {
+		feeStats = &feeStatsJSON{
+			Price:      newFeeSetJSON(res.FeeStats.Price),
+			UnitsUsed:  newFeeSetJSON(res.FeeStats.UnitsUsed),
+			Paid:       newFeeSetJSON(res.FeeStats.Paid),
+			Aggregator: res.FeeStats.Aggregator,
+		}
+	}
+	return &txResultJSON{
+		RequestID:     res.IncomingRequest.MessageID.ToEthHash(),
+		Kind:          res.IncomingRequest.Kind,
+		Sender:        res.IncomingRequest.Sender,
+		L1BlockNumber: res.IncomingRequest.L1BlockNumber,
+		L2BlockNumber: res.IncomingRequest.L2BlockNumber,
+		L2Timestamp:   res.IncomingRequest.L2Timestamp,
+		ResultCode:    res.ResultCode.String(),
+		ReturnData:    res.ReturnData,
+		GasUsed:       res.GasUsed,
+		GasPrice:      res.GasPrice,
+		CumulativeGas: res.CumulativeGas,
+		TxIndex:       res.TxIndex,
+		StartLogIndex: res.StartLogIndex,
+		EVMLogs:       logs,
+		FeeStats:      feeStats,
+	}
+}
+
+func newOutputStatisticsJSON(stats *evm.OutputStatistics) *outputStatisticsJSON {
+	if stats == nil {
+		return nil
+	}
+	return &outputStatisticsJSON{
+		GasUsed:      stats.GasUsed,
+		TxCount:      stats.TxCount,
+		EVMLogCount:  stats.EVMLogCount,
+		AVMLogCount:  stats.AVMLogCount,
+		AVMSendCount: stats.AVMSendCount,
+	}
+}
+
+func newBlockInfoJSON(info *evm.BlockInfo) *blockInfoJSON {
+	var gasSummary *gasSummaryJSON
+	if info.GasSummary != nil {
+		gasSummary = &gasSummaryJSON{
+			PricePerL1CalldataByte:   info.GasSummary.PricePerL1CalldataByte,
+			PricePerStorageCell:      info.GasSummary.PricePerStorageCell,
+			PricePerArbGasBase:       info.GasSummary.PricePerArbGasBase,
+			PricePerArbGasCongestion: info.GasSummary.PricePerArbGasCongestion,
+			PricePerArbGasTotal:      info.GasSummary.PricePerArbGasTotal,
+			GasPool:                  info.GasSummary.GasPool,
+		}
+	}
+	return &blockInfoJSON{
+		BlockNumber:    info.BlockNum,
+		Timestamp:      info.Timestamp,
+		L1BlockNumber:  info.L1BlockNum,
+		PreviousHeight: info.PreviousHeight,
+		BlockStats:     newOutputStatisticsJSON(info.BlockStats),
+		ChainStats:     newOutputStatisticsJSON(info.ChainStats),
+		GasSummary:     gasSummary,
+	}
+}
+
+func newLogJSON(index *big.Int, avmLog core.ValueAndInbox) logJSON {
+	ret := logJSON{
+		Index:      index,
+		InboxCount: avmLog.Inbox.Count,
+		InboxAcc:   avmLog.Inbox.Accumulator.ToEthHash(),
+	}
+	res, err := evm.NewResultFromValue(avmLog.Value)
+	if err != nil {
+		ret.Type = "unknown"
+		ret.Error = err.Error()
+		return ret
+	}
+	switch res := res.(type) {
+	case *evm.TxResult:
+		ret.Type = "tx"
+		ret.Tx = newTxResultJSON(res)
+	case *evm.BlockInfo:
+		ret.Type = "block"
+		ret.Block = newBlockInfoJSON(res)
+	case *evm.SendResult:
+		ret.Type = "send"
+		ret.Send = &sendResultJSON{
+			BatchNumber: res.BatchNumber,
+			BatchIndex:  res.BatchIndex,
+			Data:        res.Data,
+		}
+	case *evm.MerkleRootResult:
+		ret.Type = "merkleRoot"
+		ret.MerkleRoot = &merkleRootResultJSON{
+			BatchNumber: res.BatchNumber,
+			NumInBatch:  res.NumInBatch,
+			Root:        res.Tree.Hash().ToEthHash(),
+		}
+	default:
+		ret.Type = "unknown"
+	}
+	return ret
+}
+
+func inspectLogs(args []string) error {
+	f := flag.NewFlagSet("logs", flag.ContinueOnError)
+	r := addRangeFlags(f, "AVM log")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		total, err := lookup.GetLogCount()
+		if err != nil {
+			return err
+		}
+		start, count, err := r.resolve(total)
+		if err != nil {
+			return err
+		}
+		return forEachPage(start, count, func(pageStart, pageCount *big.Int) error {
+			logs, err := lookup.GetLogs(pageStart, pageCount)
+			if err != nil {
+				return err
+			}
+			for i, avmLog := range logs {
+				index := new(big.Int).Add(pageStart, big.NewInt(int64(i)))
+				if err := out.Encode(newLogJSON(index, avmLog)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+type withdrawEthJSON struct {
+	Destination common.Address `json:"destination"`
+	Amount      *big.Int       `json:"amount"`
+}
+
+type l2ToL1TxJSON struct {
+	L2Sender  common.Address `json:"l2Sender"`
+	L1Dest    common.Address `json:"l1Dest"`
+	L2Block   *big.Int       `json:"l2Block"`
+	L1Block   *big.Int       `json:"l1Block"`
+	Timestamp *big.Int       `json:"timestamp"`
+	Value     *big.Int       `json:"value"`
+	Calldata  hexutil.Bytes  `json:"calldata"`
+}
+
+type sendJSON struct {
+	Index       *big.Int         `json:"index"`
+	Data        hexutil.Bytes    `json:"data"`
+	WithdrawEth *withdrawEthJSON `json:"withdrawEth,omitempty"`
+	L2ToL1Tx    *l2ToL1TxJSON    `json:"l2ToL1Tx,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+func newSendJSON(index *big.Int, data []byte) sendJSON {
+	ret := sendJSON{Index: index, Data: data}
+	msg, err := evm.NewVirtualSendResultFromData(data)
+	if err != nil {
+		ret.Error = err.Error()
+		return ret
+	}
+	switch msg := msg.(type) {
+	case *evm.WithdrawEthResult:
+		ret.WithdrawEth = &withdrawEthJSON{
+			Destination: msg.Destination,
+			Amount:      msg.Amount,
+		}
+	case *evm.L2ToL1TxResult:
+		ret.L2ToL1Tx = &l2ToL1TxJSON{
+			L2Sender:  msg.L2Sender,
+			L1Dest:    msg.L1Dest,
+			L2Block:   msg.L2Block,
+			L1Block:   msg.L1Block,
+			Timestamp: msg.Timestamp,
+			Value:     msg.Value,
+			Calldata:  msg.Calldata,
+		}
+	}
+	return ret
+}
+
+func inspectSends(args []string) error {
+	f := flag.NewFlagSet("sends", flag.ContinueOnError)
+	r := addRangeFlags(f, "send")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		total, err := lookup.GetSendCount()
+		if err != nil {
+			return err
+		}
+		start, count, err := r.resolve(total)
+		if err != nil {
+			return err
+		}
+		return forEachPage(start, count, func(pageStart, pageCount *big.Int) error {
+			sends, err := lookup.GetSends(pageStart, pageCount)
+			if err != nil {
+				return err
+			}
+			for i, send := range sends {
+				index := new(big.Int).Add(pageStart, big.NewInt(int64(i)))
+				if err := out.Encode(newSendJSON(index, send)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+type accumulatorJSON struct {
+	Index       *big.Int       `json:"index"`
+	Accumulator ethcommon.Hash `json:"accumulator"`
+}
+
+func inspectAccumulators(args []string) error {
+	f := flag.NewFlagSet("accumulators", flag.ContinueOnError)
+	r := addRangeFlags(f, "accumulator")
+	f.Bool("delayed", false, "print delayed inbox accumulators instead of sequencer inbox accumulators")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		delayed, _ := f.GetBool("delayed")
+		var total *big.Int
+		var err error
+		getAcc := lookup.GetInboxAcc
+		if delayed {
+			total, err = lookup.GetDelayedMessageCount()
+			getAcc = lookup.GetDelayedInboxAcc
+		} else {
+			total, err = lookup.GetMessageCount()
+		}
+		if err != nil {
+			return err
+		}
+		start, count, err := r.resolve(total)
+		if err != nil {
+			return err
+		}
+		end := new(big.Int).Add(start, count)
+		for index := start; index.Cmp(end) < 0; index = new(big.Int).Add(index, big.NewInt(1)) {
+			acc, err := getAcc(index)
+			if err != nil {
+				return err
+			}
+			if err := out.Encode(accumulatorJSON{Index: index, Accumulator: acc.ToEthHash()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type executionCursorJSON struct {
+	L2BlockNumber     *big.Int       `json:"l2BlockNumber"`
+	MachineHash       ethcommon.Hash `json:"machineHash"`
+	TotalMessagesRead *big.Int       `json:"totalMessagesRead"`
+	InboxAcc          ethcommon.Hash `json:"inboxAcc"`
+	TotalGasConsumed  *big.Int       `json:"totalGasConsumed"`
+	TotalSteps        *big.Int       `json:"totalSteps"`
+	TotalSendCount    *big.Int       `json:"totalSendCount"`
+	SendAcc           ethcommon.Hash `json:"sendAcc"`
+	TotalLogCount     *big.Int       `json:"totalLogCount"`
+	LogAcc            ethcommon.Hash `json:"logAcc"`
+}
+
+func newExecutionCursorJSON(cursor core.ExecutionCursor) executionCursorJSON {
+	return executionCursorJSON{
+		L2BlockNumber:     cursor.L2BlockNumber(),
+		MachineHash:       cursor.MachineHash().ToEthHash(),
+		TotalMessagesRead: cursor.TotalMessagesRead(),
+		InboxAcc:          cursor.InboxAcc().ToEthHash(),
+		TotalGasConsumed:  cursor.TotalGasConsumed(),
+		TotalSteps:        cursor.TotalSteps(),
+		TotalSendCount:    cursor.TotalSendCount(),
+		SendAcc:           cursor.SendAcc().ToEthHash(),
+		TotalLogCount:     cursor.TotalLogCount(),
+		LogAcc:            cursor.LogAcc().ToEthHash(),
+	}
+}
+
+func inspectCursor(args []string) error {
+	f := flag.NewFlagSet("cursor", flag.ContinueOnError)
+	f.Uint64("block", 0, "L2 block to print the execution cursor at the end of")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		block, _ := f.GetUint64("block")
+		cursor, err := lookup.GetExecutionCursorAtEndOfBlock(block, true)
+		if err != nil {
+			return err
+		}
+		return out.Encode(newExecutionCursorJSON(cursor))
+	})
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machinestate"
+)
+
+// loadMachineTree decodes the machine at the end of block, resolving its
+// contents from the database if resolve is set. Only the part of the machine
+// which path starts with is resolved, so that looking at the stacks doesn't
+// load the ArbOS state in the register.
+func loadMachineTree(mon *monitor.Monitor, block uint64, path string, resolve bool) (*machinestate.Tree, error) {
+	cursor, err := mon.Core.GetExecutionCursorAtEndOfBlock(block, true)
+	if err != nil {
+		return nil, err
+	}
+	mach, err := mon.Core.TakeMachine(cursor)
+	if err != nil {
+		return nil, err
+	}
+	data, err := mach.MarshalState()
+	if err != nil {
+		return nil, err
+	}
+	state, err := machinestate.DecodeState(data)
+	if err != nil {
+		return nil, err
+	}
+	if resolve {
+		loader, ok := mon.Storage.(machinestate.ValueLoader)
+		if !ok {
+			return nil, errors.Errorf("storage type %T can't load values", mon.Storage)
+		}
+		root := strings.Split(strings.Trim(path, "/"), "/")[0]
+		if err := state.ResolveRoot(loader, root); err != nil {
+			return nil, err
+		}
+	}
+	return machinestate.NewTree(state), nil
+}
+
+// runMachineCommand parses the flags for a machine subcommand and runs it
+// against the opened database
+func runMachineCommand(f *flag.FlagSet, args []string, run func(mon *monitor.Monitor, out *json.Encoder) error) error {
+	f.Bool("no-resolve", false, "only decode the top level of the machine instead of loading its contents from the database, which for the register is the whole ArbOS state")
+	config, err := parseCommand(f, args)
+	if err != nil {
+		return usageError(err)
+	}
+	mon, err := openDatabase(config, true, false)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+	return run(mon, json.NewEncoder(os.Stdout))
+}
+
+func inspectMachine(args []string) error {
+	f := flag.NewFlagSet("machine", flag.ContinueOnError)
+	f.Uint64("block", 0, "L2 block to print the machine at the end of")
+	f.String("path", "", "only print the value at this path, such as register/2/0 or stack/0")
+	return runMachineCommand(f, args, func(mon *monitor.Monitor, out *json.Encoder) error {
+		block, _ := f.GetUint64("block")
+		path, _ := f.GetString("path")
+		noResolve, _ := f.GetBool("no-resolve")
+		tree, err := loadMachineTree(mon, block, path, !noResolve)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return out.Encode(tree)
+		}
+		node, err := tree.Lookup(path)
+		if err != nil {
+			return err
+		}
+		return out.Encode(node)
+	})
+}
+
+func diffMachines(args []string) error {
+	f := flag.NewFlagSet("machine-diff", flag.ContinueOnError)
+	f.Uint64("from", 0, "L2 block to compare the machine at the end of")
+	f.Uint64("to", 0, "L2 block to compare against, defaulting to the block after --from")
+	f.String("path", "", "only print differences under this path, such as register/2")
+	return runMachineCommand(f, args, func(mon *monitor.Monitor, out *json.Encoder) error {
+		from, _ := f.GetUint64("from")
+		to, _ := f.GetUint64("to")
+		if !f.Changed("to") {
+			to = from + 1
+		}
+		path, _ := f.GetString("path")
+		path = strings.Trim(path, "/")
+		noResolve, _ := f.GetBool("no-resolve")
+		before, err := loadMachineTree(mon, from, path, !noResolve)
+		if err != nil {
+			return err
+		}
+		after, err := loadMachineTree(mon, to, path, !noResolve)
+		if err != nil {
+			return err
+		}
+		for _, diff := range machinestate.Diff(before, after) {
+			if path != "" && diff.Path != path && !strings.HasPrefix(diff.Path, path+"/") {
+				continue
+			}
+			if err := out.Encode(diff); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
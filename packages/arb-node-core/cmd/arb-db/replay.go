@@ -0,0 +1,336 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+type fieldDiffJSON struct {
+	Path     string      `json:"path"`
+	Stored   interface{} `json:"stored"`
+	Replayed interface{} `json:"replayed"`
+}
+
+type logDiffJSON struct {
+	Kind      string          `json:"kind"`
+	Block     uint64          `json:"block"`
+	LogIndex  *big.Int        `json:"logIndex"`
+	Type      string          `json:"type"`
+	RequestID *ethcommon.Hash `json:"requestId,omitempty"`
+	Diffs     []fieldDiffJSON `json:"diffs"`
+}
+
+type sendDiffJSON struct {
+	Kind      string        `json:"kind"`
+	Block     uint64        `json:"block"`
+	SendIndex *big.Int      `json:"sendIndex"`
+	Stored    hexutil.Bytes `json:"stored"`
+	Replayed  hexutil.Bytes `json:"replayed"`
+}
+
+type blockReplayJSON struct {
+	Kind                string         `json:"kind"`
+	Block               uint64         `json:"block"`
+	Matches             bool           `json:"matches"`
+	StoredMachineHash   ethcommon.Hash `json:"storedMachineHash"`
+	ReplayedMachineHash ethcommon.Hash `json:"replayedMachineHash"`
+	StoredGas           *big.Int       `json:"storedGas"`
+	ReplayedGas         uint64         `json:"replayedGas"`
+	Logs                int            `json:"logs"`
+	MismatchedLogs      int            `json:"mismatchedLogs"`
+	Sends               int            `json:"sends"`
+	MismatchedSends     int            `json:"mismatchedSends"`
+}
+
+// toGeneric converts v to the maps, slices and numbers it would be decoded
+// from as JSON, so that two results can be compared field by field
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var ret interface{}
+	if err := dec.Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
+
+// diffGeneric appends the differences between the stored and replayed
+// values at each path
+func diffGeneric(path string, stored, replayed interface{}, diffs []fieldDiffJSON) []fieldDiffJSON {
+	switch s := stored.(type) {
+	case map[string]interface{}:
+		r, ok := replayed.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(s)+len(r))
+		for key := range s {
+			keys = append(keys, key)
+		}
+		for key := range r {
+			if _, ok := s[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffs = diffGeneric(joinPath(path, key), s[key], r[key], diffs)
+		}
+		return diffs
+	case []interface{}:
+		r, ok := replayed.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(s) || i < len(r); i++ {
+			var storedItem, replayedItem interface{}
+			if i < len(s) {
+				storedItem = s[i]
+			}
+			if i < len(r) {
+				replayedItem = r[i]
+			}
+			diffs = diffGeneric(joinPath(path, strconv.Itoa(i)), storedItem, replayedItem, diffs)
+		}
+		return diffs
+	}
+	if !reflect.DeepEqual(stored, replayed) {
+		diffs = append(diffs, fieldDiffJSON{Path: path, Stored: stored, Replayed: replayed})
+	}
+	return diffs
+}
+
+// diffLog compares a stored AVM log with the replayed one, either of which
+// may be missing
+func diffLog(block uint64, index *big.Int, stored *core.ValueAndInbox, replayed value.Value) (*logDiffJSON, error) {
+	if stored != nil && replayed != nil && value.Eq(stored.Value, replayed) {
+		return nil, nil
+	}
+	ret := &logDiffJSON{Kind: "log", Block: block, LogIndex: index}
+	var storedJSON, replayedJSON interface{}
+	if stored != nil {
+		parsed := newLogJSON(index, *stored)
+		ret.Type = parsed.Type
+		if parsed.Tx != nil {
+			ret.RequestID = &parsed.Tx.RequestID
+		}
+		storedJSON = parsed
+	}
+	if replayed != nil {
+		replayedLog := core.ValueAndInbox{Value: replayed}
+		if stored != nil {
+			// The inbox state isn't part of the replay
+			replayedLog.Inbox = stored.Inbox
+		}
+		parsed := newLogJSON(index, replayedLog)
+		if ret.Type == "" {
+			ret.Type = parsed.Type
+		}
+		if ret.RequestID == nil && parsed.Tx != nil {
+			ret.RequestID = &parsed.Tx.RequestID
+		}
+		replayedJSON = parsed
+	}
+	storedGeneric, err := toGeneric(storedJSON)
+	if err != nil {
+		return nil, err
+	}
+	replayedGeneric, err := toGeneric(replayedJSON)
+	if err != nil {
+		return nil, err
+	}
+	ret.Diffs = diffGeneric("", storedGeneric, replayedGeneric, nil)
+	if len(ret.Diffs) == 0 {
+		// The values differ in a way that doesn't show in their parsed form
+		ret.Diffs = []fieldDiffJSON{{Path: "hash", Stored: stored.Value.Hash(), Replayed: replayed.Hash()}}
+	}
+	return ret, nil
+}
+
+func replayBlocks(args []string) error {
+	f := flag.NewFlagSet("replay", flag.ContinueOnError)
+	f.Uint64("start", 1, "first L2 block to replay")
+	f.Uint64("end", 0, "last L2 block to replay, defaulting to --start")
+	f.Bool("keep-going", false, "continue replaying after a block that doesn't match")
+	return runInspect(f, args, func(lookup core.ArbCoreLookup, out *json.Encoder) error {
+		start, _ := f.GetUint64("start")
+		end, _ := f.GetUint64("end")
+		if !f.Changed("end") {
+			end = start
+		}
+		keepGoing, _ := f.GetBool("keep-going")
+		if start == 0 {
+			return errors.New("replay must start after block 0")
+		}
+		if end < start {
+			return errors.Errorf("end %v is before start %v", end, start)
+		}
+
+		startCursor, err := lookup.GetExecutionCursorAtEndOfBlock(start-1, true)
+		if err != nil {
+			return err
+		}
+		endCursor, err := lookup.GetExecutionCursorAtEndOfBlock(end, true)
+		if err != nil {
+			return err
+		}
+		messagesRead := startCursor.TotalMessagesRead()
+		gasConsumed := startCursor.TotalGasConsumed()
+		logIndex := startCursor.TotalLogCount()
+		sendIndex := startCursor.TotalSendCount()
+		mach, err := lookup.TakeMachine(startCursor.Clone())
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		for block := start; block <= end; block++ {
+			cursor := endCursor
+			if block != end {
+				cursor, err = lookup.GetExecutionCursorAtEndOfBlock(block, true)
+				if err != nil {
+					return err
+				}
+			}
+			// Only the messages this block read are loaded, so that long
+			// ranges don't hold every message in memory at once. Blocks which
+			// read none skip the lookup, which returns the first message
+			// rather than nothing when asked for none at index 0.
+			var messages []inbox.InboxMessage
+			messageCount := new(big.Int).Sub(cursor.TotalMessagesRead(), messagesRead)
+			if messageCount.Sign() > 0 {
+				messages, err = lookup.GetMessages(messagesRead, messageCount)
+				if err != nil {
+					return err
+				}
+			}
+			storedGas := new(big.Int).Sub(cursor.TotalGasConsumed(), gasConsumed)
+			assertion, _, _, err := mach.ExecuteAssertion(ctx, storedGas.Uint64(), false, messages, false)
+			if err != nil {
+				return err
+			}
+			messagesRead = new(big.Int).Add(messagesRead, new(big.Int).SetUint64(assertion.InboxMessagesConsumed))
+			gasConsumed = cursor.TotalGasConsumed()
+
+			result := blockReplayJSON{
+				Kind:                "block",
+				Block:               block,
+				StoredMachineHash:   cursor.MachineHash().ToEthHash(),
+				ReplayedMachineHash: mach.Hash().ToEthHash(),
+				StoredGas:           storedGas,
+				ReplayedGas:         assertion.NumGas,
+				Logs:                len(assertion.Logs),
+				Sends:               len(assertion.Sends),
+			}
+
+			storedLogs, err := lookup.GetLogs(logIndex, new(big.Int).Sub(cursor.TotalLogCount(), logIndex))
+			if err != nil {
+				return err
+			}
+			for i := 0; i < len(storedLogs) || i < len(assertion.Logs); i++ {
+				var stored *core.ValueAndInbox
+				var replayed value.Value
+				if i < len(storedLogs) {
+					stored = &storedLogs[i]
+				}
+				if i < len(assertion.Logs) {
+					replayed = assertion.Logs[i]
+				}
+				index := new(big.Int).Add(logIndex, big.NewInt(int64(i)))
+				diff, err := diffLog(block, index, stored, replayed)
+				if err != nil {
+					return err
+				}
+				if diff != nil {
+					result.MismatchedLogs++
+					if err := out.Encode(diff); err != nil {
+						return err
+					}
+				}
+			}
+			logIndex = cursor.TotalLogCount()
+
+			storedSends, err := lookup.GetSends(sendIndex, new(big.Int).Sub(cursor.TotalSendCount(), sendIndex))
+			if err != nil {
+				return err
+			}
+			for i := 0; i < len(storedSends) || i < len(assertion.Sends); i++ {
+				var stored, replayed []byte
+				if i < len(storedSends) {
+					stored = storedSends[i]
+				}
+				if i < len(assertion.Sends) {
+					replayed = assertion.Sends[i]
+				}
+				if i < len(storedSends) && i < len(assertion.Sends) && bytes.Equal(stored, replayed) {
+					continue
+				}
+				result.MismatchedSends++
+				diff := sendDiffJSON{
+					Kind:      "send",
+					Block:     block,
+					SendIndex: new(big.Int).Add(sendIndex, big.NewInt(int64(i))),
+					Stored:    stored,
+					Replayed:  replayed,
+				}
+				if err := out.Encode(diff); err != nil {
+					return err
+				}
+			}
+			sendIndex = cursor.TotalSendCount()
+
+			result.Matches = result.StoredMachineHash == result.ReplayedMachineHash &&
+				result.StoredGas.Cmp(new(big.Int).SetUint64(result.ReplayedGas)) == 0 &&
+				result.MismatchedLogs == 0 &&
+				result.MismatchedSends == 0
+			if err := out.Encode(result); err != nil {
+				return err
+			}
+			if !result.Matches && !keepGoing {
+				return errors.Errorf("replay of block %v doesn't match the database", block)
+			}
+		}
+		return nil
+	})
+}
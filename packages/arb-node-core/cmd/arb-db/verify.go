@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/dbverify"
+)
+
+func verifyDatabase(args []string) error {
+	f := flag.NewFlagSet("verify", flag.ContinueOnError)
+	f.Bool("skip-inbox", false, "skip recomputing inbox accumulators")
+	f.Bool("skip-blocks", false, "skip checking txdb block headers")
+	f.Uint64("checkpoint-samples", 10, "number of blocks to spot-check machine checkpoints at, 0 to skip")
+	f.Uint64("checkpoint-distance", 100, "number of blocks to re-execute for each checkpoint sample")
+
+	config, err := parseCommand(f, args)
+	if err != nil {
+		return usageError(err)
+	}
+	skipInbox, _ := f.GetBool("skip-inbox")
+	skipBlocks, _ := f.GetBool("skip-blocks")
+	checkpointSamples, _ := f.GetUint64("checkpoint-samples")
+	checkpointDistance, _ := f.GetUint64("checkpoint-distance")
+
+	mon, err := openDatabase(config, true, false)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	var corruptions []*dbverify.Corruption
+	runCheck := func(check dbverify.Check, run func() (*dbverify.Corruption, error)) error {
+		logger.Info().Str("check", string(check)).Msg("running check")
+		corruption, err := run()
+		if err != nil {
+			return errors.Wrapf(err, "error running %v check", check)
+		}
+		if corruption != nil {
+			fmt.Printf("FAILED %v: first corrupted index %v: %v\n", corruption.Check, corruption.Index, corruption.Reason)
+			corruptions = append(corruptions, corruption)
+		} else {
+			fmt.Printf("OK     %v\n", check)
+		}
+		return nil
+	}
+
+	if !skipInbox {
+		if err := runCheck(dbverify.SequencerInboxCheck, func() (*dbverify.Corruption, error) {
+			return dbverify.VerifyInbox(mon.Core)
+		}); err != nil {
+			return err
+		}
+	}
+	if !skipBlocks {
+		if err := runCheck(dbverify.BlockCheck, func() (*dbverify.Corruption, error) {
+			return dbverify.VerifyBlocks(mon.Core, mon.Storage.GetNodeStore())
+		}); err != nil {
+			return err
+		}
+	}
+	if checkpointSamples > 0 {
+		if err := runCheck(dbverify.CheckpointCheck, func() (*dbverify.Corruption, error) {
+			return dbverify.VerifyCheckpoints(mon.Core, checkpointSamples, checkpointDistance)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(corruptions) > 0 {
+		return corruptions[0]
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// VerifyBlocks checks that the block headers saved by txdb chain together by
+// parent hash and that each one agrees with the L2 block info log it was
+// built from. Blocks whose log is past the end of the ArbCore logs are
+// ignored since txdb may not have processed a reorg yet.
+func VerifyBlocks(lookup core.ArbOutputLookup, nodeStore machine.NodeStore) (*Corruption, error) {
+	blockCount, err := nodeStore.BlockCount()
+	if err != nil {
+		return nil, err
+	}
+	logCount, err := lookup.GetLogCount()
+	if err != nil {
+		return nil, err
+	}
+
+	prevHash := ethcommon.Hash{}
+	for height := uint64(0); height < blockCount; height++ {
+		index := new(big.Int).SetUint64(height)
+		info, err := nodeStore.GetBlockInfo(height)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil || info.Header == nil {
+			return newCorruption(BlockCheck, index, "block is missing"), nil
+		}
+		if info.BlockLog >= logCount.Uint64() {
+			logger.Warn().
+				Uint64("block", height).
+				Uint64("blockLog", info.BlockLog).
+				Uint64("logCount", logCount.Uint64()).
+				Msg("txdb has blocks past the end of the ArbCore logs")
+			break
+		}
+		header := info.Header
+		if header.Number == nil || header.Number.Uint64() != height {
+			return newCorruption(BlockCheck, index, "header has block number %v", header.Number), nil
+		}
+		if header.ParentHash != prevHash {
+			return newCorruption(BlockCheck, index, "parent hash %v doesn't match previous block hash %v", header.ParentHash, prevHash), nil
+		}
+
+		blockLog, err := core.GetZeroOrOneLog(lookup, new(big.Int).SetUint64(info.BlockLog))
+		if err != nil {
+			return nil, err
+		}
+		if blockLog.Value == nil {
+			return newCorruption(BlockCheck, index, "block info log %v is missing", info.BlockLog), nil
+		}
+		blockInfo, err := evm.NewBlockResultFromValue(blockLog.Value)
+		if err != nil {
+			return newCorruption(BlockCheck, index, "log %v is not a block info log: %v", info.BlockLog, err), nil
+		}
+		if blockInfo.BlockNum.Uint64() != height {
+			return newCorruption(BlockCheck, index, "block info log %v is for block %v", info.BlockLog, blockInfo.BlockNum), nil
+		}
+		if blockInfo.Timestamp.Uint64() != header.Time {
+			return newCorruption(BlockCheck, index, "header timestamp %v doesn't match block info %v", header.Time, blockInfo.Timestamp), nil
+		}
+		if blockInfo.BlockStats.GasUsed.Uint64() != header.GasUsed {
+			return newCorruption(BlockCheck, index, "header gas used %v doesn't match block info %v", header.GasUsed, blockInfo.BlockStats.GasUsed), nil
+		}
+		if blockInfo.BlockStats.AVMLogCount.Uint64() != info.LogCount {
+			return newCorruption(BlockCheck, index, "stored log count %v doesn't match block info %v", info.LogCount, blockInfo.BlockStats.AVMLogCount), nil
+		}
+
+		prevHash = header.Hash()
+		if height%100_000 == 0 && height > 0 {
+			logger.Info().Uint64("blocks", height).Msg("verified blocks")
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// VerifyCheckpoints spot-checks the stored machine checkpoints at samples
+// evenly spaced blocks. For each sampled block the machine is loaded at the
+// end of the block distance blocks earlier and executed forward, and the
+// result is compared with the machine loaded directly at the end of the
+// sampled block. Since both machines are restored from the closest
+// checkpoint, a mismatch means one of those checkpoints is corrupt.
+func VerifyCheckpoints(lookup core.ArbCoreLookup, samples uint64, distance uint64) (*Corruption, error) {
+	if samples == 0 {
+		return nil, nil
+	}
+	if distance == 0 {
+		distance = 1
+	}
+	lastBlock, err := lookup.GetLastMachineL2BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range checkpointSamples(lastBlock.Uint64(), samples, distance) {
+		corruption, err := verifyCheckpoint(lookup, block-distance, block)
+		if corruption != nil || err != nil {
+			return corruption, err
+		}
+		logger.Info().Uint64("block", block).Msg("verified checkpoint")
+	}
+	return nil, nil
+}
+
+// checkpointSamples returns up to samples evenly spaced blocks at least
+// distance blocks after the start of the chain, ending at lastBlock
+func checkpointSamples(lastBlock, samples, distance uint64) []uint64 {
+	if lastBlock < distance {
+		return nil
+	}
+	span := lastBlock - distance
+	if samples > span+1 {
+		samples = span + 1
+	}
+	blocks := make([]uint64, 0, samples)
+	for i := samples; i > 0; i-- {
+		blocks = append(blocks, lastBlock-(span+1)*(i-1)/samples)
+	}
+	return blocks
+}
+
+func verifyCheckpoint(lookup core.ArbCoreLookup, startBlock, endBlock uint64) (*Corruption, error) {
+	index := new(big.Int).SetUint64(endBlock)
+	expected, err := lookup.GetExecutionCursorAtEndOfBlock(endBlock, true)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := lookup.GetExecutionCursorAtEndOfBlock(startBlock, true)
+	if err != nil {
+		return nil, err
+	}
+	gas := new(big.Int).Sub(expected.TotalGasConsumed(), cursor.TotalGasConsumed())
+	if gas.Sign() < 0 {
+		return newCorruption(CheckpointCheck, index, "gas used %v is less than %v at block %v", expected.TotalGasConsumed(), cursor.TotalGasConsumed(), startBlock), nil
+	}
+	if gas.Sign() > 0 {
+		if err := lookup.AdvanceExecutionCursor(cursor, gas, false, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if cursor.TotalGasConsumed().Cmp(expected.TotalGasConsumed()) != 0 {
+		return newCorruption(CheckpointCheck, index, "re-execution stopped at gas %v instead of %v", cursor.TotalGasConsumed(), expected.TotalGasConsumed()), nil
+	}
+	if cursor.MachineHash() != expected.MachineHash() {
+		return newCorruption(CheckpointCheck, index, "re-executed machine hash %v doesn't match stored %v", cursor.MachineHash(), expected.MachineHash()), nil
+	}
+	if cursor.TotalMessagesRead().Cmp(expected.TotalMessagesRead()) != 0 || cursor.InboxAcc() != expected.InboxAcc() {
+		return newCorruption(CheckpointCheck, index, "re-executed machine read %v messages but stored read %v", cursor.TotalMessagesRead(), expected.TotalMessagesRead()), nil
+	}
+	if cursor.TotalLogCount().Cmp(expected.TotalLogCount()) != 0 || cursor.LogAcc() != expected.LogAcc() {
+		return newCorruption(CheckpointCheck, index, "re-executed machine produced %v logs but stored produced %v", cursor.TotalLogCount(), expected.TotalLogCount()), nil
+	}
+	if cursor.TotalSendCount().Cmp(expected.TotalSendCount()) != 0 || cursor.SendAcc() != expected.SendAcc() {
+		return newCorruption(CheckpointCheck, index, "re-executed machine produced %v sends but stored produced %v", cursor.TotalSendCount(), expected.TotalSendCount()), nil
+	}
+	return nil, nil
+}
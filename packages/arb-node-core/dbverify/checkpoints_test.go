@@ -0,0 +1,41 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointSamples(t *testing.T) {
+	testCases := []struct {
+		lastBlock, samples, distance uint64
+		expected                     []uint64
+	}{
+		{1000, 10, 100, []uint64{190, 280, 370, 460, 550, 640, 730, 820, 910, 1000}},
+		{105, 10, 100, []uint64{100, 101, 102, 103, 104, 105}},
+		{102, 2, 100, []uint64{101, 102}},
+		{100, 3, 100, []uint64{100}},
+		{99, 3, 100, nil},
+	}
+	for _, tc := range testCases {
+		blocks := checkpointSamples(tc.lastBlock, tc.samples, tc.distance)
+		if !reflect.DeepEqual(blocks, tc.expected) {
+			t.Errorf("samples %v for last block %v: got %v, expected %v", tc.samples, tc.lastBlock, blocks, tc.expected)
+		}
+	}
+}
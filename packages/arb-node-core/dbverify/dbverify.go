@@ -0,0 +1,60 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dbverify checks that the data stored in an ArbCore database and its
+// txdb block index is internally consistent. Each check stops at the first
+// problem found and reports the index where it occurred, which is the point a
+// node would need to reorg back to before resyncing.
+package dbverify
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+)
+
+var logger = arblog.Logger.With().Str("component", "dbverify").Logger()
+
+type Check string
+
+const (
+	SequencerInboxCheck Check = "sequencer-inbox"
+	DelayedInboxCheck   Check = "delayed-inbox"
+	BlockCheck          Check = "blocks"
+	CheckpointCheck     Check = "checkpoints"
+)
+
+// Corruption describes the first inconsistency found by a check. Index is a
+// message sequence number for the inbox checks and an L2 block number for the
+// block and checkpoint checks.
+type Corruption struct {
+	Check  Check
+	Index  *big.Int
+	Reason string
+}
+
+func (c *Corruption) Error() string {
+	return fmt.Sprintf("%v check failed at %v: %v", c.Check, c.Index, c.Reason)
+}
+
+func newCorruption(check Check, index *big.Int, format string, args ...interface{}) *Corruption {
+	return &Corruption{
+		Check:  check,
+		Index:  new(big.Int).Set(index),
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
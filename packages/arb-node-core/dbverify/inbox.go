@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// inboxLookup is the subset of core.ArbCoreLookup needed to verify the inbox
+type inboxLookup interface {
+	GetMessageCount() (*big.Int, error)
+	GetMessages(startIndex *big.Int, count *big.Int) ([]inbox.InboxMessage, error)
+	GetSequencerBatchItemsLimited(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error)
+	GetInboxAcc(index *big.Int) (common.Hash, error)
+	GetDelayedInboxAcc(index *big.Int) (common.Hash, error)
+}
+
+// inboxPageSize is the number of sequencer batch items loaded at a time
+var inboxPageSize uint64 = 10_000
+
+// VerifyInbox recomputes the accumulator of every sequencer batch item and
+// every sequenced delayed message, comparing them against the stored items
+// and the values returned by GetInboxAcc and GetDelayedInboxAcc. Delayed
+// messages which haven't been sequenced yet aren't stored with their contents
+// so they can't be checked.
+//
+// A nil Corruption is returned if the inbox is consistent. The error return
+// is reserved for failures reading from the database.
+func VerifyInbox(lookup inboxLookup) (*Corruption, error) {
+	messageCount, err := lookup.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	nextSeqNum := big.NewInt(0)
+	prevAcc := common.Hash{}
+	prevDelayedCount := big.NewInt(0)
+	delayedAcc := common.Hash{}
+	verified := 0
+	for {
+		items, err := lookup.GetSequencerBatchItemsLimited(nextSeqNum, inboxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			if item.TotalDelayedCount.Cmp(prevDelayedCount) < 0 {
+				return newCorruption(SequencerInboxCheck, nextSeqNum, "total delayed count decreased from %v to %v", prevDelayedCount, item.TotalDelayedCount), nil
+			}
+
+			var expected inbox.SequencerBatchItem
+			if len(item.SequencerMessage) > 0 {
+				msg, err := inbox.NewInboxMessageFromData(item.SequencerMessage)
+				if err != nil {
+					return newCorruption(SequencerInboxCheck, nextSeqNum, "invalid sequencer message: %v", err), nil
+				}
+				if msg.InboxSeqNum.Cmp(nextSeqNum) != 0 {
+					return newCorruption(SequencerInboxCheck, nextSeqNum, "sequencer message has sequence number %v", msg.InboxSeqNum), nil
+				}
+				expected = inbox.NewSequencerItem(item.TotalDelayedCount, msg, prevAcc)
+			} else {
+				if item.TotalDelayedCount.Cmp(prevDelayedCount) > 0 {
+					var corruption *Corruption
+					delayedAcc, corruption, err = verifyDelayedMessages(lookup, nextSeqNum, prevDelayedCount, item.TotalDelayedCount, delayedAcc)
+					if corruption != nil || err != nil {
+						return corruption, err
+					}
+				}
+				lastSeqNum := new(big.Int).Sub(item.TotalDelayedCount, prevDelayedCount)
+				lastSeqNum = lastSeqNum.Add(lastSeqNum, nextSeqNum)
+				lastSeqNum = lastSeqNum.Sub(lastSeqNum, big.NewInt(1))
+				expected = inbox.NewDelayedItem(lastSeqNum, item.TotalDelayedCount, prevAcc, prevDelayedCount, delayedAcc)
+			}
+			if expected.LastSeqNum.Cmp(item.LastSeqNum) != 0 {
+				return newCorruption(SequencerInboxCheck, nextSeqNum, "batch item should end at %v but ends at %v", expected.LastSeqNum, item.LastSeqNum), nil
+			}
+			if expected.Accumulator != item.Accumulator {
+				return newCorruption(SequencerInboxCheck, nextSeqNum, "stored batch item accumulator %v doesn't match computed %v", item.Accumulator, expected.Accumulator), nil
+			}
+			storedAcc, err := lookup.GetInboxAcc(item.LastSeqNum)
+			if err != nil {
+				return nil, err
+			}
+			if storedAcc != expected.Accumulator {
+				return newCorruption(SequencerInboxCheck, nextSeqNum, "inbox accumulator %v doesn't match computed %v", storedAcc, expected.Accumulator), nil
+			}
+
+			nextSeqNum = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+			prevAcc = item.Accumulator
+			prevDelayedCount = item.TotalDelayedCount
+			verified++
+			if verified%100_000 == 0 {
+				logger.Info().Str("messages", nextSeqNum.String()).Msg("verified sequencer inbox")
+			}
+		}
+	}
+	if nextSeqNum.Cmp(messageCount) != 0 {
+		return newCorruption(SequencerInboxCheck, nextSeqNum, "batch items end at %v but message count is %v", nextSeqNum, messageCount), nil
+	}
+	return nil, nil
+}
+
+// verifyDelayedMessages recomputes the delayed accumulators of the messages
+// sequenced by a delayed batch item starting at firstSeqNum, returning the
+// accumulator of the last one
+func verifyDelayedMessages(lookup inboxLookup, firstSeqNum, prevDelayedCount, totalDelayedCount *big.Int, prevDelayedAcc common.Hash) (common.Hash, *Corruption, error) {
+	count := new(big.Int).Sub(totalDelayedCount, prevDelayedCount)
+	messages, err := lookup.GetMessages(firstSeqNum, count)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if int64(len(messages)) != count.Int64() {
+		return common.Hash{}, newCorruption(DelayedInboxCheck, prevDelayedCount, "expected %v delayed messages but found %v", count, len(messages)), nil
+	}
+	acc := prevDelayedAcc
+	delayedSeqNum := new(big.Int).Set(prevDelayedCount)
+	for _, msg := range messages {
+		if msg.InboxSeqNum.Cmp(delayedSeqNum) != 0 {
+			return common.Hash{}, newCorruption(DelayedInboxCheck, delayedSeqNum, "delayed message has sequence number %v", msg.InboxSeqNum), nil
+		}
+		acc = hashing.SoliditySHA3(hashing.Bytes32(acc), hashing.Bytes32(msg.CommitmentHash()))
+		storedAcc, err := lookup.GetDelayedInboxAcc(delayedSeqNum)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		if storedAcc != acc {
+			return common.Hash{}, newCorruption(DelayedInboxCheck, delayedSeqNum, "delayed accumulator %v doesn't match computed %v", storedAcc, acc), nil
+		}
+		delayedSeqNum = new(big.Int).Add(delayedSeqNum, big.NewInt(1))
+	}
+	return acc, nil, nil
+}
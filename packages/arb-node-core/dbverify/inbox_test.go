@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+type testInbox struct {
+	items       []inbox.SequencerBatchItem
+	messages    []inbox.InboxMessage
+	inboxAccs   map[uint64]common.Hash
+	delayedAccs []common.Hash
+}
+
+func (ti *testInbox) GetMessageCount() (*big.Int, error) {
+	return big.NewInt(int64(len(ti.messages))), nil
+}
+
+func (ti *testInbox) GetMessages(startIndex *big.Int, count *big.Int) ([]inbox.InboxMessage, error) {
+	end := startIndex.Int64() + count.Int64()
+	if end > int64(len(ti.messages)) {
+		end = int64(len(ti.messages))
+	}
+	return ti.messages[startIndex.Int64():end], nil
+}
+
+func (ti *testInbox) GetSequencerBatchItemsLimited(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error) {
+	var items []inbox.SequencerBatchItem
+	for _, item := range ti.items {
+		if item.LastSeqNum.Cmp(startIndex) >= 0 && uint64(len(items)) < maxCount {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (ti *testInbox) GetInboxAcc(index *big.Int) (common.Hash, error) {
+	acc, ok := ti.inboxAccs[index.Uint64()]
+	if !ok {
+		return common.Hash{}, errors.New("no accumulator")
+	}
+	return acc, nil
+}
+
+func (ti *testInbox) GetDelayedInboxAcc(index *big.Int) (common.Hash, error) {
+	return ti.delayedAccs[index.Uint64()], nil
+}
+
+func (ti *testInbox) addItem(item inbox.SequencerBatchItem) {
+	ti.items = append(ti.items, item)
+	ti.inboxAccs[item.LastSeqNum.Uint64()] = item.Accumulator
+}
+
+// newTestInbox builds an inbox of a sequencer message, two delayed messages
+// and another sequencer message
+func newTestInbox() *testInbox {
+	ti := &testInbox{inboxAccs: make(map[uint64]common.Hash)}
+
+	seqMsg := inbox.NewRandomInboxMessage()
+	seqMsg.InboxSeqNum = big.NewInt(0)
+	ti.messages = append(ti.messages, seqMsg)
+	ti.addItem(inbox.NewSequencerItem(big.NewInt(0), seqMsg, common.Hash{}))
+
+	delayedAcc := common.Hash{}
+	for i := int64(0); i < 2; i++ {
+		msg := inbox.NewRandomInboxMessage()
+		msg.InboxSeqNum = big.NewInt(i)
+		delayed := inbox.NewDelayedMessage(delayedAcc, msg)
+		delayedAcc = delayed.DelayedAccumulator
+		ti.delayedAccs = append(ti.delayedAccs, delayedAcc)
+		ti.messages = append(ti.messages, msg)
+	}
+	ti.addItem(inbox.NewDelayedItem(big.NewInt(2), big.NewInt(2), ti.items[0].Accumulator, big.NewInt(0), delayedAcc))
+
+	seqMsg = inbox.NewRandomInboxMessage()
+	seqMsg.InboxSeqNum = big.NewInt(3)
+	ti.messages = append(ti.messages, seqMsg)
+	ti.addItem(inbox.NewSequencerItem(big.NewInt(2), seqMsg, ti.items[1].Accumulator))
+	return ti
+}
+
+func TestVerifyInbox(t *testing.T) {
+	corruption, err := VerifyInbox(newTestInbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption != nil {
+		t.Fatal("unexpected corruption in valid inbox", corruption)
+	}
+}
+
+func TestVerifyInboxReportsFirstCorruption(t *testing.T) {
+	ti := newTestInbox()
+	ti.inboxAccs[3] = common.RandHash()
+	corruption, err := VerifyInbox(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption == nil || corruption.Check != SequencerInboxCheck || corruption.Index.Cmp(big.NewInt(3)) != 0 {
+		t.Error("expected sequencer inbox corruption at 3, got", corruption)
+	}
+
+	ti = newTestInbox()
+	ti.delayedAccs[1] = common.RandHash()
+	corruption, err = VerifyInbox(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption == nil || corruption.Check != DelayedInboxCheck || corruption.Index.Cmp(big.NewInt(1)) != 0 {
+		t.Error("expected delayed inbox corruption at 1, got", corruption)
+	}
+
+	ti = newTestInbox()
+	ti.items[2].LastSeqNum = big.NewInt(4)
+	corruption, err = VerifyInbox(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption == nil || corruption.Index.Cmp(big.NewInt(3)) != 0 {
+		t.Error("expected corruption at 3, got", corruption)
+	}
+}
+
+func TestVerifyInboxAcrossPages(t *testing.T) {
+	defer func(pageSize uint64) { inboxPageSize = pageSize }(inboxPageSize)
+	inboxPageSize = 1
+
+	corruption, err := VerifyInbox(newTestInbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption != nil {
+		t.Fatal("unexpected corruption in valid inbox", corruption)
+	}
+
+	ti := newTestInbox()
+	ti.items[2].Accumulator = common.RandHash()
+	corruption, err = VerifyInbox(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corruption == nil || corruption.Check != SequencerInboxCheck || corruption.Index.Cmp(big.NewInt(3)) != 0 {
+		t.Error("expected sequencer inbox corruption at 3, got", corruption)
+	}
+}
@@ -96,6 +96,14 @@ func (r *DelayedBridgeWatcher) CurrentBlockHeight(ctx context.Context) (*big.Int
 	return latestHeader.Number, nil
 }
 
+func (r *DelayedBridgeWatcher) BlockHash(ctx context.Context, height *big.Int) (common.Hash, error) {
+	header, err := r.client.HeaderByNumber(ctx, height)
+	if err != nil {
+		return common.Hash{}, errors.WithStack(err)
+	}
+	return common.NewHashFromEth(header.Hash()), nil
+}
+
 func (r *DelayedBridgeWatcher) GetAccumulator(ctx context.Context, sequenceNumber *big.Int, blockNumber *big.Int) (common.Hash, error) {
 	opts := &bind.CallOpts{
 		Context:     ctx,
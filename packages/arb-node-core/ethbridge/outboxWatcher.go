@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+type OutboxWatcher struct {
+	con     *ethbridgecontracts.Outbox
+	address ethcommon.Address
+}
+
+func NewOutboxWatcher(address ethcommon.Address, client ethutils.EthClient) (*OutboxWatcher, error) {
+	con, err := ethbridgecontracts.NewOutbox(address, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &OutboxWatcher{
+		con:     con,
+		address: address,
+	}, nil
+}
+
+func (o *OutboxWatcher) Address() ethcommon.Address {
+	return o.address
+}
+
+// OutboxEntryExists returns true if the given L2 to L1 message batch has been
+// confirmed and can be executed on L1
+func (o *OutboxWatcher) OutboxEntryExists(ctx context.Context, batchNum *big.Int) (bool, error) {
+	exists, err := o.con.OutboxEntryExists(&bind.CallOpts{Context: ctx}, batchNum)
+	return exists, errors.WithStack(err)
+}
@@ -296,6 +296,11 @@ func (r *RollupWatcher) DelayedBridge(ctx context.Context) (common.Address, erro
 	return common.NewAddressFromEth(addr), errors.WithStack(err)
 }
 
+func (r *RollupWatcher) Outbox(ctx context.Context) (common.Address, error) {
+	addr, err := r.con.Outbox(r.getCallOpts(ctx))
+	return common.NewAddressFromEth(addr), errors.WithStack(err)
+}
+
 func (r *RollupWatcher) StakerCount(ctx context.Context) (*big.Int, error) {
 	count, err := r.con.StakerCount(r.getCallOpts(ctx))
 	return count, errors.WithStack(err)
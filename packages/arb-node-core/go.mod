@@ -11,6 +11,7 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.11.0 // indirect
 	github.com/rs/zerolog v1.26.1
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0 // indirect
 )
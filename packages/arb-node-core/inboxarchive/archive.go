@@ -0,0 +1,354 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inboxarchive reads and writes portable archives of the sequencer
+// batch items and delayed messages stored in ArbCore.
+//
+// An archive starts with an 8 byte magic string, a 4 byte version and a header
+// describing the inbox state preceding the first exported item. It is followed
+// by a series of records, each made of a 1 byte type, a 4 byte payload length
+// and the payload. Delayed messages are always written before the sequencer
+// batch item that first includes them. The final record is a trailer holding
+// the record counts and a sha256 checksum of everything before it.
+package inboxarchive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+var logger = arblog.Logger.With().Str("component", "inboxarchive").Logger()
+
+const (
+	Magic   = "ARBINBOX"
+	Version = 1
+)
+
+type recordType uint8
+
+const (
+	sequencerBatchItemRecord recordType = 1
+	delayedMessageRecord     recordType = 2
+	trailerRecord            recordType = 0xff
+)
+
+// Maximum payload size accepted when reading, to avoid allocating unbounded
+// amounts of memory for a corrupted length
+const maxRecordSize = 1 << 28
+
+var ErrChecksumMismatch = errors.New("inbox archive checksum mismatch")
+
+// Header describes the inbox state immediately before the first item in the
+// archive. It is all zero for archives starting at the beginning of the inbox.
+type Header struct {
+	MessageCount *big.Int
+	InboxAcc     common.Hash
+	DelayedCount *big.Int
+	DelayedAcc   common.Hash
+}
+
+// Trailer summarizes the contents of an archive
+type Trailer struct {
+	SequencerBatchItemCount uint64
+	DelayedMessageCount     uint64
+	Checksum                common.Hash
+}
+
+// DelayedEntry is a delayed message along with the L1 block it was delivered in
+type DelayedEntry struct {
+	BlockNumber *big.Int
+	Message     inbox.DelayedMessage
+}
+
+// Entry is a single record read from an archive. Exactly one of Item and
+// Delayed is set.
+type Entry struct {
+	Item    *inbox.SequencerBatchItem
+	Delayed *DelayedEntry
+}
+
+type Writer struct {
+	out      *bufio.Writer
+	checksum hash.Hash
+	trailer  Trailer
+	closed   bool
+}
+
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	writer := &Writer{
+		out:      bufio.NewWriter(w),
+		checksum: sha256.New(),
+	}
+	var data []byte
+	data = append(data, Magic...)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(Magic):], Version)
+	data = append(data, math.U256Bytes(bigOrZero(header.MessageCount))...)
+	data = append(data, header.InboxAcc.Bytes()...)
+	data = append(data, math.U256Bytes(bigOrZero(header.DelayedCount))...)
+	data = append(data, header.DelayedAcc.Bytes()...)
+	if err := writer.write(data); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) WriteSequencerBatchItem(item inbox.SequencerBatchItem) error {
+	if err := w.writeRecord(sequencerBatchItemRecord, item.ToBytesWithSeqNum()); err != nil {
+		return err
+	}
+	w.trailer.SequencerBatchItemCount++
+	return nil
+}
+
+func (w *Writer) WriteDelayedMessage(blockNumber *big.Int, msg inbox.DelayedMessage) error {
+	var data []byte
+	data = append(data, math.U256Bytes(blockNumber)...)
+	data = append(data, msg.ToBytesWithSeqNum()...)
+	if err := w.writeRecord(delayedMessageRecord, data); err != nil {
+		return err
+	}
+	w.trailer.DelayedMessageCount++
+	return nil
+}
+
+// Close writes the trailer and flushes the archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() (Trailer, error) {
+	if w.closed {
+		return Trailer{}, errors.New("inbox archive already closed")
+	}
+	w.closed = true
+	copy(w.trailer.Checksum[:], w.checksum.Sum(nil))
+	if err := w.writeRecordHeader(trailerRecord, 32*3); err != nil {
+		return Trailer{}, err
+	}
+	var data []byte
+	data = append(data, math.U256Bytes(new(big.Int).SetUint64(w.trailer.SequencerBatchItemCount))...)
+	data = append(data, math.U256Bytes(new(big.Int).SetUint64(w.trailer.DelayedMessageCount))...)
+	data = append(data, w.trailer.Checksum.Bytes()...)
+	if _, err := w.out.Write(data); err != nil {
+		return Trailer{}, errors.WithStack(err)
+	}
+	if err := w.out.Flush(); err != nil {
+		return Trailer{}, errors.WithStack(err)
+	}
+	return w.trailer, nil
+}
+
+func (w *Writer) writeRecord(typ recordType, payload []byte) error {
+	if w.closed {
+		return errors.New("inbox archive already closed")
+	}
+	if err := w.writeRecordHeader(typ, len(payload)); err != nil {
+		return err
+	}
+	return w.write(payload)
+}
+
+func (w *Writer) writeRecordHeader(typ recordType, length int) error {
+	var data [5]byte
+	data[0] = byte(typ)
+	binary.BigEndian.PutUint32(data[1:], uint32(length))
+	if typ == trailerRecord {
+		// The trailer isn't covered by the checksum it contains
+		_, err := w.out.Write(data[:])
+		return errors.WithStack(err)
+	}
+	return w.write(data[:])
+}
+
+func (w *Writer) write(data []byte) error {
+	w.checksum.Write(data)
+	_, err := w.out.Write(data)
+	return errors.WithStack(err)
+}
+
+type Reader struct {
+	in       *bufio.Reader
+	checksum hash.Hash
+	header   Header
+	trailer  *Trailer
+	counts   Trailer
+}
+
+// NewReader reads the archive header from r. The checksum is only verified
+// once every entry has been read with Next.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{
+		in:       bufio.NewReader(r),
+		checksum: sha256.New(),
+	}
+	data := make([]byte, len(Magic)+4+32*4)
+	if err := reader.read(data); err != nil {
+		return nil, errors.Wrap(err, "error reading inbox archive header")
+	}
+	if !bytes.Equal(data[:len(Magic)], []byte(Magic)) {
+		return nil, errors.New("not an inbox archive")
+	}
+	data = data[len(Magic):]
+	version := binary.BigEndian.Uint32(data[:4])
+	if version != Version {
+		return nil, errors.Errorf("unsupported inbox archive version %v", version)
+	}
+	data = data[4:]
+
+	reader.header.MessageCount = new(big.Int).SetBytes(data[:32])
+	data = data[32:]
+	copy(reader.header.InboxAcc[:], data[:32])
+	data = data[32:]
+	reader.header.DelayedCount = new(big.Int).SetBytes(data[:32])
+	data = data[32:]
+	copy(reader.header.DelayedAcc[:], data[:32])
+	return reader, nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Trailer returns the archive trailer, or nil if Next hasn't reached it yet
+func (r *Reader) Trailer() *Trailer {
+	return r.trailer
+}
+
+// Next returns the next entry in the archive. It returns io.EOF once the
+// trailer has been read and the checksum and record counts verified.
+func (r *Reader) Next() (*Entry, error) {
+	if r.trailer != nil {
+		return nil, io.EOF
+	}
+	var recordHeader [5]byte
+	if _, err := io.ReadFull(r.in, recordHeader[:]); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("inbox archive truncated before trailer")
+		}
+		return nil, errors.WithStack(err)
+	}
+	typ := recordType(recordHeader[0])
+	length := binary.BigEndian.Uint32(recordHeader[1:])
+	if length > maxRecordSize {
+		return nil, errors.Errorf("inbox archive record too large: %v bytes", length)
+	}
+	if typ == trailerRecord {
+		return nil, r.readTrailer(length)
+	}
+	r.checksum.Write(recordHeader[:])
+	payload := make([]byte, length)
+	if err := r.read(payload); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case sequencerBatchItemRecord:
+		item, err := inbox.NewSequencerBatchItemFromData(payload)
+		if err != nil {
+			return nil, err
+		}
+		r.counts.SequencerBatchItemCount++
+		return &Entry{Item: &item}, nil
+	case delayedMessageRecord:
+		if len(payload) < 32 {
+			return nil, errors.New("Not enough data for delayed message record")
+		}
+		msg, err := inbox.NewDelayedMessageFromData(payload[32:])
+		if err != nil {
+			return nil, err
+		}
+		r.counts.DelayedMessageCount++
+		return &Entry{Delayed: &DelayedEntry{
+			BlockNumber: new(big.Int).SetBytes(payload[:32]),
+			Message:     msg,
+		}}, nil
+	default:
+		return nil, errors.Errorf("unknown inbox archive record type %v", typ)
+	}
+}
+
+func (r *Reader) readTrailer(length uint32) error {
+	if length != 32*3 {
+		return errors.Errorf("unexpected inbox archive trailer length %v", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.in, data); err != nil {
+		return errors.WithStack(err)
+	}
+	trailer := &Trailer{
+		SequencerBatchItemCount: new(big.Int).SetBytes(data[:32]).Uint64(),
+		DelayedMessageCount:     new(big.Int).SetBytes(data[32:64]).Uint64(),
+	}
+	copy(trailer.Checksum[:], data[64:])
+
+	var checksum common.Hash
+	copy(checksum[:], r.checksum.Sum(nil))
+	if checksum != trailer.Checksum {
+		return ErrChecksumMismatch
+	}
+	if trailer.SequencerBatchItemCount != r.counts.SequencerBatchItemCount ||
+		trailer.DelayedMessageCount != r.counts.DelayedMessageCount {
+		return errors.New("inbox archive record count mismatch")
+	}
+	r.trailer = trailer
+	return io.EOF
+}
+
+func (r *Reader) read(data []byte) error {
+	if _, err := io.ReadFull(r.in, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("inbox archive truncated")
+		}
+		return errors.WithStack(err)
+	}
+	r.checksum.Write(data)
+	return nil
+}
+
+// Verify reads an entire archive, checking its structure and checksum
+func Verify(r io.Reader) (Header, Trailer, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return Header{}, Trailer{}, err
+	}
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Header{}, Trailer{}, err
+		}
+	}
+	return reader.Header(), *reader.Trailer(), nil
+}
+
+func bigOrZero(val *big.Int) *big.Int {
+	if val == nil {
+		return big.NewInt(0)
+	}
+	return val
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxarchive
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+func writeTestArchive(t *testing.T) ([]byte, Header, []Entry) {
+	header := Header{
+		MessageCount: big.NewInt(5),
+		InboxAcc:     common.RandHash(),
+		DelayedCount: big.NewInt(2),
+		DelayedAcc:   common.RandHash(),
+	}
+	delayedMsg := inbox.NewRandomInboxMessage()
+	delayedMsg.InboxSeqNum = big.NewInt(2)
+	delayed := inbox.NewDelayedMessage(header.DelayedAcc, delayedMsg)
+	delayedItem := inbox.NewDelayedItem(big.NewInt(5), big.NewInt(3), header.InboxAcc, big.NewInt(2), delayed.DelayedAccumulator)
+	seqMsg := inbox.NewRandomInboxMessage()
+	seqMsg.InboxSeqNum = big.NewInt(6)
+	seqItem := inbox.NewSequencerItem(big.NewInt(3), seqMsg, delayedItem.Accumulator)
+
+	entries := []Entry{
+		{Delayed: &DelayedEntry{BlockNumber: big.NewInt(100), Message: delayed}},
+		{Item: &delayedItem},
+		{Item: &seqItem},
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Delayed != nil {
+			err = writer.WriteDelayedMessage(entry.Delayed.BlockNumber, entry.Delayed.Message)
+		} else {
+			err = writer.WriteSequencerBatchItem(*entry.Item)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	trailer, err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trailer.SequencerBatchItemCount != 2 || trailer.DelayedMessageCount != 1 {
+		t.Fatal("unexpected trailer counts", trailer)
+	}
+	return buf.Bytes(), header, entries
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	data, header, entries := writeTestArchive(t)
+
+	reader, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readHeader := reader.Header()
+	if readHeader.MessageCount.Cmp(header.MessageCount) != 0 ||
+		readHeader.InboxAcc != header.InboxAcc ||
+		readHeader.DelayedCount.Cmp(header.DelayedCount) != 0 ||
+		readHeader.DelayedAcc != header.DelayedAcc {
+		t.Fatal("header mismatch")
+	}
+
+	for i, expected := range entries {
+		entry, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected.Delayed != nil {
+			if entry.Delayed == nil {
+				t.Fatal("expected delayed message at entry", i)
+			}
+			if entry.Delayed.BlockNumber.Cmp(expected.Delayed.BlockNumber) != 0 ||
+				entry.Delayed.Message.DelayedSequenceNumber.Cmp(expected.Delayed.Message.DelayedSequenceNumber) != 0 ||
+				entry.Delayed.Message.DelayedAccumulator != expected.Delayed.Message.DelayedAccumulator ||
+				!bytes.Equal(entry.Delayed.Message.Message, expected.Delayed.Message.Message) {
+				t.Error("delayed message mismatch at entry", i)
+			}
+		} else {
+			if entry.Item == nil {
+				t.Fatal("expected batch item at entry", i)
+			}
+			if !bytes.Equal(entry.Item.ToBytesWithSeqNum(), expected.Item.ToBytesWithSeqNum()) {
+				t.Error("batch item mismatch at entry", i)
+			}
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatal("expected EOF after trailer, got", err)
+	}
+	if reader.Trailer() == nil {
+		t.Fatal("expected trailer to be read")
+	}
+}
+
+func TestArchiveDetectsCorruption(t *testing.T) {
+	data, _, _ := writeTestArchive(t)
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(Magic)+4+32*4+20] ^= 0xff
+	if _, _, err := Verify(bytes.NewReader(corrupted)); err == nil {
+		t.Error("expected corrupted archive to fail verification")
+	}
+
+	truncated := data[:len(data)-10]
+	if _, _, err := Verify(bytes.NewReader(truncated)); err == nil {
+		t.Error("expected truncated archive to fail verification")
+	}
+
+	if _, _, err := Verify(bytes.NewReader(data)); err != nil {
+		t.Error("expected valid archive to verify", err)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxarchive
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// exportPageSize is the number of sequencer batch items loaded at a time
+const exportPageSize = 1000
+
+// Export writes the inbox stored in lookup to w, starting at message start
+// and continuing through the batch item containing message end-1. A nil end
+// exports everything. start must be the first message of a batch item.
+//
+// Only delayed messages which have been sequenced are stored in ArbCore with
+// their contents, so any remaining delayed messages are left to be read from
+// L1 after importing.
+func Export(lookup core.ArbCoreLookup, w io.Writer, start, end *big.Int) (Trailer, error) {
+	header := Header{
+		MessageCount: new(big.Int).Set(start),
+		DelayedCount: big.NewInt(0),
+	}
+	if start.Sign() > 0 {
+		prevSeqNum := new(big.Int).Sub(start, big.NewInt(1))
+		items, err := lookup.GetSequencerBatchItemsLimited(prevSeqNum, 1)
+		if err != nil {
+			return Trailer{}, err
+		}
+		if len(items) == 0 || items[0].LastSeqNum.Cmp(prevSeqNum) != 0 {
+			return Trailer{}, errors.Errorf("message %v is not the start of a sequencer batch item", start)
+		}
+		header.InboxAcc = items[0].Accumulator
+		header.DelayedCount = items[0].TotalDelayedCount
+		if header.DelayedCount.Sign() > 0 {
+			header.DelayedAcc, err = lookup.GetDelayedInboxAcc(new(big.Int).Sub(header.DelayedCount, big.NewInt(1)))
+			if err != nil {
+				return Trailer{}, err
+			}
+		}
+	}
+
+	writer, err := NewWriter(w, header)
+	if err != nil {
+		return Trailer{}, err
+	}
+	nextSeqNum := new(big.Int).Set(start)
+	prevDelayedCount := new(big.Int).Set(header.DelayedCount)
+	for end == nil || nextSeqNum.Cmp(end) < 0 {
+		items, err := lookup.GetSequencerBatchItemsLimited(nextSeqNum, exportPageSize)
+		if err != nil {
+			return Trailer{}, err
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			if end != nil && nextSeqNum.Cmp(end) >= 0 {
+				break
+			}
+			if len(item.SequencerMessage) == 0 && item.TotalDelayedCount.Cmp(prevDelayedCount) > 0 {
+				if err := exportDelayedMessages(lookup, writer, nextSeqNum, prevDelayedCount, item.TotalDelayedCount); err != nil {
+					return Trailer{}, err
+				}
+			}
+			if err := writer.WriteSequencerBatchItem(item); err != nil {
+				return Trailer{}, err
+			}
+			nextSeqNum = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+			prevDelayedCount = item.TotalDelayedCount
+		}
+	}
+
+	trailer, err := writer.Close()
+	if err != nil {
+		return Trailer{}, err
+	}
+	logger.Info().
+		Str("start", start.String()).
+		Str("end", nextSeqNum.String()).
+		Uint64("items", trailer.SequencerBatchItemCount).
+		Uint64("delayed", trailer.DelayedMessageCount).
+		Msg("exported inbox")
+	return trailer, nil
+}
+
+// exportDelayedMessages writes the delayed messages sequenced by a batch item
+// starting at firstSeqNum
+func exportDelayedMessages(lookup core.ArbCoreLookup, writer *Writer, firstSeqNum, prevDelayedCount, totalDelayedCount *big.Int) error {
+	count := new(big.Int).Sub(totalDelayedCount, prevDelayedCount)
+	messages, err := lookup.GetMessages(firstSeqNum, count)
+	if err != nil {
+		return err
+	}
+	if int64(len(messages)) != count.Int64() {
+		return errors.Errorf("expected %v delayed messages at %v but found %v", count, firstSeqNum, len(messages))
+	}
+	delayedSeqNum := new(big.Int).Set(prevDelayedCount)
+	for _, msg := range messages {
+		if msg.InboxSeqNum.Cmp(delayedSeqNum) != 0 {
+			return errors.Errorf("expected delayed message %v but found %v", delayedSeqNum, msg.InboxSeqNum)
+		}
+		acc, err := lookup.GetDelayedInboxAcc(delayedSeqNum)
+		if err != nil {
+			return err
+		}
+		delayed := inbox.DelayedMessage{
+			DelayedSequenceNumber: new(big.Int).Set(delayedSeqNum),
+			DelayedAccumulator:    acc,
+			Message:               msg.ToBytes(),
+		}
+		if err := writer.WriteDelayedMessage(msg.ChainTime.BlockNum.AsInt(), delayed); err != nil {
+			return err
+		}
+		delayedSeqNum = delayedSeqNum.Add(delayedSeqNum, big.NewInt(1))
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxarchive
+
+import (
+	"context"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+const DefaultImportBatchSize = 1000
+
+// importer tracks the inbox state while replaying an archive so that every
+// accumulator can be recomputed before it is handed to ArbCore
+type importer struct {
+	db core.ArbCore
+
+	dbMessageCount *big.Int
+	dbDelayedCount *big.Int
+
+	nextSeqNum       *big.Int
+	inboxAcc         common.Hash
+	itemDelayedCount *big.Int
+	delayedCount     *big.Int
+	delayedAcc       common.Hash
+
+	pendingBeforeCount *big.Int
+	pendingBeforeAcc   common.Hash
+	pendingItems       []inbox.SequencerBatchItem
+	pendingDelayed     []inbox.DelayedMessage
+
+	imported Trailer
+}
+
+// Import verifies the archive in r and then delivers its contents to db,
+// recomputing every accumulator along the way. db must already contain the
+// inbox state the archive starts from. Items already present in db are
+// checked for consistency and skipped. The returned Trailer counts the
+// records that were actually delivered.
+func Import(ctx context.Context, db core.ArbCore, r io.ReadSeeker, batchSize int) (Trailer, error) {
+	if _, _, err := Verify(r); err != nil {
+		return Trailer{}, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Trailer{}, errors.WithStack(err)
+	}
+	reader, err := NewReader(r)
+	if err != nil {
+		return Trailer{}, err
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+	imp, err := newImporter(db, reader.Header())
+	if err != nil {
+		return Trailer{}, err
+	}
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Trailer{}, err
+		}
+		if entry.Delayed != nil {
+			err = imp.addDelayedMessage(entry.Delayed.Message)
+		} else {
+			err = imp.addItem(*entry.Item)
+		}
+		if err != nil {
+			return Trailer{}, err
+		}
+		if len(imp.pendingItems) >= batchSize {
+			if err := imp.deliver(ctx); err != nil {
+				return Trailer{}, err
+			}
+		}
+	}
+	if err := imp.deliver(ctx); err != nil {
+		return Trailer{}, err
+	}
+	logger.Info().
+		Str("messageCount", imp.nextSeqNum.String()).
+		Str("delayedCount", imp.delayedCount.String()).
+		Uint64("items", imp.imported.SequencerBatchItemCount).
+		Uint64("delayed", imp.imported.DelayedMessageCount).
+		Msg("imported inbox")
+	return imp.imported, nil
+}
+
+func newImporter(db core.ArbCore, header Header) (*importer, error) {
+	dbMessageCount, err := db.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	dbDelayedCount, err := db.GetDelayedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if header.MessageCount.Cmp(dbMessageCount) > 0 {
+		return nil, errors.Errorf("archive starts at message %v but database only has %v", header.MessageCount, dbMessageCount)
+	}
+	if header.DelayedCount.Cmp(dbDelayedCount) > 0 {
+		return nil, errors.Errorf("archive starts at delayed message %v but database only has %v", header.DelayedCount, dbDelayedCount)
+	}
+	if header.MessageCount.Sign() > 0 {
+		acc, err := db.GetInboxAcc(new(big.Int).Sub(header.MessageCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		if acc != header.InboxAcc {
+			return nil, errors.Errorf("archive doesn't match database inbox accumulator at message %v", header.MessageCount)
+		}
+	}
+	if header.DelayedCount.Sign() > 0 {
+		acc, err := db.GetDelayedInboxAcc(new(big.Int).Sub(header.DelayedCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		if acc != header.DelayedAcc {
+			return nil, errors.Errorf("archive doesn't match database delayed accumulator at delayed message %v", header.DelayedCount)
+		}
+	}
+	return &importer{
+		db:                 db,
+		dbMessageCount:     dbMessageCount,
+		dbDelayedCount:     dbDelayedCount,
+		nextSeqNum:         new(big.Int).Set(header.MessageCount),
+		inboxAcc:           header.InboxAcc,
+		itemDelayedCount:   new(big.Int).Set(header.DelayedCount),
+		delayedCount:       new(big.Int).Set(header.DelayedCount),
+		delayedAcc:         header.DelayedAcc,
+		pendingBeforeCount: new(big.Int).Set(header.MessageCount),
+		pendingBeforeAcc:   header.InboxAcc,
+	}, nil
+}
+
+func (imp *importer) addDelayedMessage(msg inbox.DelayedMessage) error {
+	if msg.DelayedSequenceNumber.Cmp(imp.delayedCount) != 0 {
+		return errors.Errorf("expected delayed message %v but found %v", imp.delayedCount, msg.DelayedSequenceNumber)
+	}
+	inboxMessage, err := inbox.NewInboxMessageFromData(msg.Message)
+	if err != nil {
+		return err
+	}
+	acc := hashing.SoliditySHA3(
+		hashing.Bytes32(imp.delayedAcc),
+		hashing.Bytes32(inboxMessage.CommitmentHash()),
+	)
+	if acc != msg.DelayedAccumulator {
+		return errors.Errorf("delayed accumulator mismatch at delayed message %v", msg.DelayedSequenceNumber)
+	}
+	if msg.DelayedSequenceNumber.Cmp(imp.dbDelayedCount) < 0 {
+		dbAcc, err := imp.db.GetDelayedInboxAcc(msg.DelayedSequenceNumber)
+		if err != nil {
+			return err
+		}
+		if dbAcc != acc {
+			return errors.Errorf("archive conflicts with database at delayed message %v", msg.DelayedSequenceNumber)
+		}
+	} else {
+		imp.pendingDelayed = append(imp.pendingDelayed, msg)
+	}
+	imp.delayedAcc = acc
+	imp.delayedCount = new(big.Int).Add(imp.delayedCount, big.NewInt(1))
+	return nil
+}
+
+func (imp *importer) addItem(item inbox.SequencerBatchItem) error {
+	var expected inbox.SequencerBatchItem
+	if len(item.SequencerMessage) > 0 {
+		msg, err := inbox.NewInboxMessageFromData(item.SequencerMessage)
+		if err != nil {
+			return err
+		}
+		if msg.InboxSeqNum.Cmp(imp.nextSeqNum) != 0 {
+			return errors.Errorf("expected message %v but found %v", imp.nextSeqNum, msg.InboxSeqNum)
+		}
+		expected = inbox.NewSequencerItem(item.TotalDelayedCount, msg, imp.inboxAcc)
+	} else {
+		if item.TotalDelayedCount.Cmp(imp.delayedCount) != 0 {
+			return errors.Errorf("batch item at %v sequences %v delayed messages but archive contains %v", item.LastSeqNum, item.TotalDelayedCount, imp.delayedCount)
+		}
+		expected = inbox.NewDelayedItem(item.LastSeqNum, item.TotalDelayedCount, imp.inboxAcc, imp.itemDelayedCount, imp.delayedAcc)
+	}
+	if expected.LastSeqNum.Cmp(item.LastSeqNum) != 0 || expected.Accumulator != item.Accumulator {
+		return errors.Errorf("inbox accumulator mismatch at message %v", item.LastSeqNum)
+	}
+
+	if item.LastSeqNum.Cmp(imp.dbMessageCount) < 0 {
+		dbAcc, err := imp.db.GetInboxAcc(item.LastSeqNum)
+		if err != nil {
+			return err
+		}
+		if dbAcc != item.Accumulator {
+			return errors.Errorf("archive conflicts with database at message %v", item.LastSeqNum)
+		}
+		imp.pendingBeforeCount = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+		imp.pendingBeforeAcc = item.Accumulator
+	} else {
+		imp.pendingItems = append(imp.pendingItems, item)
+	}
+	imp.nextSeqNum = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+	imp.inboxAcc = item.Accumulator
+	imp.itemDelayedCount = item.TotalDelayedCount
+	return nil
+}
+
+func (imp *importer) deliver(ctx context.Context) error {
+	if len(imp.pendingItems) == 0 && len(imp.pendingDelayed) == 0 {
+		return nil
+	}
+	err := core.DeliverMessagesAndWait(ctx, imp.db, imp.pendingBeforeCount, imp.pendingBeforeAcc, imp.pendingItems, imp.pendingDelayed, nil)
+	if err != nil {
+		return err
+	}
+	if len(imp.pendingItems) > 0 {
+		last := imp.pendingItems[len(imp.pendingItems)-1]
+		acc, err := imp.db.GetInboxAcc(last.LastSeqNum)
+		if err != nil {
+			return err
+		}
+		if acc != last.Accumulator {
+			return errors.Errorf("database inbox accumulator mismatch after importing message %v", last.LastSeqNum)
+		}
+		imp.pendingBeforeCount = new(big.Int).Add(last.LastSeqNum, big.NewInt(1))
+		imp.pendingBeforeAcc = last.Accumulator
+	}
+	if len(imp.pendingDelayed) > 0 {
+		last := imp.pendingDelayed[len(imp.pendingDelayed)-1]
+		acc, err := imp.db.GetDelayedInboxAcc(last.DelayedSequenceNumber)
+		if err != nil {
+			return err
+		}
+		if acc != last.DelayedAccumulator {
+			return errors.Errorf("database delayed accumulator mismatch after importing delayed message %v", last.DelayedSequenceNumber)
+		}
+	}
+	imp.imported.SequencerBatchItemCount += uint64(len(imp.pendingItems))
+	imp.imported.DelayedMessageCount += uint64(len(imp.pendingDelayed))
+	logger.Info().
+		Str("messageCount", imp.pendingBeforeCount.String()).
+		Int("items", len(imp.pendingItems)).
+		Int("delayed", len(imp.pendingDelayed)).
+		Msg("delivered archived inbox messages")
+	imp.pendingItems = nil
+	imp.pendingDelayed = nil
+	return nil
+}
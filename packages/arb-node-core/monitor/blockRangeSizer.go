@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+const (
+	defaultBlocksToFetch    = 100
+	defaultMinBlocksToFetch = 2
+	defaultMaxBlocksToFetch = 5000
+
+	// Batch counts used to decide whether a successful lookup should grow or
+	// shrink the range of the next one
+	targetMinBatchesPerRange = 5
+	targetMaxBatchesPerRange = 10
+)
+
+// Substrings of L1 provider errors which indicate that a log query covered too
+// many blocks or returned too many results, rather than a real failure
+var logRangeErrorMessages = []string{
+	"query returned more than",
+	"too many results",
+	"too many logs",
+	"response size exceeded",
+	"response size should not greater than",
+	"limit exceeded",
+	"block range",
+	"exceed maximum block range",
+	"timeout",
+	"timed out",
+	"deadline exceeded",
+}
+
+// blockRangeSizer decides how many L1 blocks the InboxReader should cover with
+// each log lookup. The range grows while lookups succeed with few results and
+// shrinks when lookups return many batches or the provider rejects the query.
+type blockRangeSizer struct {
+	size uint64
+	min  uint64
+	max  uint64
+}
+
+func newBlockRangeSizer(config configuration.InboxReader) *blockRangeSizer {
+	minSize := config.MinBlocksToFetch
+	if minSize == 0 {
+		minSize = defaultMinBlocksToFetch
+	}
+	maxSize := config.MaxBlocksToFetch
+	if maxSize == 0 {
+		maxSize = defaultMaxBlocksToFetch
+	}
+	if maxSize < minSize {
+		maxSize = minSize
+	}
+	sizer := &blockRangeSizer{
+		size: config.BlocksToFetch,
+		min:  minSize,
+		max:  maxSize,
+	}
+	if sizer.size == 0 {
+		sizer.size = defaultBlocksToFetch
+	}
+	sizer.clamp()
+	return sizer
+}
+
+func (s *blockRangeSizer) Size() uint64 {
+	return s.size
+}
+
+// Succeeded adjusts the range after a lookup that returned batchCount
+// sequencer batches
+func (s *blockRangeSizer) Succeeded(batchCount int) {
+	if batchCount < targetMinBatchesPerRange {
+		s.size += s.size/2 + 1
+	} else if batchCount > targetMaxBatchesPerRange {
+		s.size /= 2
+	}
+	s.clamp()
+}
+
+// Failed shrinks the range after a lookup was rejected. It returns false if
+// the range is already as small as allowed, in which case the error shouldn't
+// be attributed to the size of the range.
+func (s *blockRangeSizer) Failed() bool {
+	if s.size <= s.min {
+		return false
+	}
+	s.size /= 2
+	s.clamp()
+	return true
+}
+
+func (s *blockRangeSizer) clamp() {
+	if s.size < s.min {
+		s.size = s.min
+	}
+	if s.size > s.max {
+		s.size = s.max
+	}
+}
+
+// isLogRangeError returns true if err looks like the L1 provider refusing a
+// log query because of its size rather than a persistent failure
+func isLogRangeError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, rangeMsg := range logRangeErrorMessages {
+		if strings.Contains(msg, rangeMsg) {
+			return true
+		}
+	}
+	return false
+}
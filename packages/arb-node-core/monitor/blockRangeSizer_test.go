@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+func TestBlockRangeSizer(t *testing.T) {
+	sizer := newBlockRangeSizer(configuration.InboxReader{
+		BlocksToFetch:    100,
+		MinBlocksToFetch: 10,
+		MaxBlocksToFetch: 1000,
+	})
+	if sizer.Size() != 100 {
+		t.Fatal("unexpected initial size", sizer.Size())
+	}
+
+	for i := 0; i < 20; i++ {
+		sizer.Succeeded(0)
+	}
+	if sizer.Size() != 1000 {
+		t.Error("size should grow to max, got", sizer.Size())
+	}
+
+	sizer.Succeeded(targetMaxBatchesPerRange + 1)
+	if sizer.Size() != 500 {
+		t.Error("size should halve with many batches, got", sizer.Size())
+	}
+
+	sizer.Succeeded(targetMinBatchesPerRange)
+	if sizer.Size() != 500 {
+		t.Error("size should be unchanged with target batch count, got", sizer.Size())
+	}
+
+	for sizer.Failed() {
+	}
+	if sizer.Size() != 10 {
+		t.Error("size should shrink to min, got", sizer.Size())
+	}
+}
+
+func TestBlockRangeSizerDefaults(t *testing.T) {
+	sizer := newBlockRangeSizer(configuration.InboxReader{})
+	if sizer.Size() != defaultBlocksToFetch {
+		t.Error("unexpected default size", sizer.Size())
+	}
+	if sizer.min != defaultMinBlocksToFetch || sizer.max != defaultMaxBlocksToFetch {
+		t.Error("unexpected default bounds", sizer.min, sizer.max)
+	}
+}
+
+func TestIsLogRangeError(t *testing.T) {
+	ctx := context.Background()
+	if !isLogRangeError(ctx, errors.New("query returned more than 10000 results")) {
+		t.Error("expected result limit error to be a range error")
+	}
+	if !isLogRangeError(ctx, errors.Wrap(context.DeadlineExceeded, "filter logs")) {
+		t.Error("expected timeout to be a range error")
+	}
+	if isLogRangeError(ctx, errors.New("connection refused")) {
+		t.Error("unexpected range error")
+	}
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if isLogRangeError(cancelledCtx, errors.New("timeout")) {
+		t.Error("errors after cancellation shouldn't be range errors")
+	}
+}
+
+func TestInboxCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inbox-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, InboxCheckpointFilename)
+
+	loaded, err := LoadInboxCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != nil {
+		t.Fatal("expected no checkpoint before saving")
+	}
+
+	checkpoint := &InboxCheckpoint{
+		BlockNumber:  1234,
+		BlockHash:    ethcommon.HexToHash("0x01"),
+		MessageCount: 56,
+		InboxAcc:     ethcommon.HexToHash("0x02"),
+		DelayedCount: 7,
+		DelayedAcc:   ethcommon.HexToHash("0x03"),
+	}
+	if err := SaveInboxCheckpoint(filename, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadInboxCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded != *checkpoint {
+		t.Error("loaded checkpoint doesn't match saved", loaded, checkpoint)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+const InboxCheckpointFilename = "inbox-reader-checkpoint.json"
+
+// InboxCheckpoint records the last L1 block whose inbox messages have been
+// fully delivered to ArbCore, along with the ArbCore inbox state at that point
+// so that a stale checkpoint can be detected after a database reorg.
+type InboxCheckpoint struct {
+	BlockNumber  uint64         `json:"blockNumber"`
+	BlockHash    ethcommon.Hash `json:"blockHash"`
+	MessageCount uint64         `json:"messageCount"`
+	InboxAcc     ethcommon.Hash `json:"inboxAcc"`
+	DelayedCount uint64         `json:"delayedCount"`
+	DelayedAcc   ethcommon.Hash `json:"delayedAcc"`
+}
+
+func (c *InboxCheckpoint) Block() *big.Int {
+	return new(big.Int).SetUint64(c.BlockNumber)
+}
+
+// LoadInboxCheckpoint returns nil without an error if no checkpoint has been
+// saved yet
+func LoadInboxCheckpoint(filename string) (*InboxCheckpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	checkpoint := &InboxCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, errors.Wrap(err, "error parsing inbox reader checkpoint")
+	}
+	return checkpoint, nil
+}
+
+// SaveInboxCheckpoint atomically replaces the checkpoint stored in filename
+func SaveInboxCheckpoint(filename string, checkpoint *InboxCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpFilename := filename + ".tmp"
+	if err := ioutil.WriteFile(tmpFilename, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmpFilename, filename))
+}
+
+// newInboxCheckpoint captures the current ArbCore inbox state as having been
+// read through the given L1 block
+func newInboxCheckpoint(db core.ArbCore, blockNumber *big.Int, blockHash common.Hash) (*InboxCheckpoint, error) {
+	checkpoint := &InboxCheckpoint{
+		BlockNumber: blockNumber.Uint64(),
+		BlockHash:   blockHash.ToEthHash(),
+	}
+	messageCount, err := db.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.MessageCount = messageCount.Uint64()
+	if messageCount.Sign() > 0 {
+		acc, err := db.GetInboxAcc(new(big.Int).Sub(messageCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		checkpoint.InboxAcc = acc.ToEthHash()
+	}
+	delayedCount, err := db.GetDelayedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.DelayedCount = delayedCount.Uint64()
+	if delayedCount.Sign() > 0 {
+		acc, err := db.GetDelayedInboxAcc(new(big.Int).Sub(delayedCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		checkpoint.DelayedAcc = acc.ToEthHash()
+	}
+	return checkpoint, nil
+}
+
+// matchesCore returns true if ArbCore still contains every message that was
+// delivered when the checkpoint was taken
+func (c *InboxCheckpoint) matchesCore(db core.ArbCore) bool {
+	if c.MessageCount > 0 {
+		acc, err := db.GetInboxAcc(new(big.Int).SetUint64(c.MessageCount - 1))
+		if err != nil || acc.ToEthHash() != c.InboxAcc {
+			return false
+		}
+	}
+	if c.DelayedCount > 0 {
+		acc, err := db.GetDelayedInboxAcc(new(big.Int).SetUint64(c.DelayedCount - 1))
+		if err != nil || acc.ToEthHash() != c.DelayedAcc {
+			return false
+		}
+	}
+	return true
+}
@@ -46,6 +46,11 @@ var (
 
 const RECENT_FEED_ITEM_TTL time.Duration = time.Second * 10
 
+// Number of L1 blocks to step back after the first reorg is detected. The step
+// doubles each time the reorg is still present, up to maxReorgStep.
+const minReorgStep = 10
+const maxReorgStep = 10_000
+
 type InboxReader struct {
 	// Only in run thread
 	db                 core.ArbCore
@@ -59,6 +64,11 @@ type InboxReader struct {
 	recentFeedItems    map[common.Hash]time.Time
 	inboxReaderConfig  configuration.InboxReader
 	sequencerAddresses map[ethcommon.Address]time.Time
+	rangeSizer         *blockRangeSizer
+	reorgStep          *big.Int
+	checkpointFilename string
+	checkpoint         *InboxCheckpoint
+	lastCheckpointSave time.Time
 
 	// Only in main thread
 	cancelFunc context.CancelFunc
@@ -66,6 +76,7 @@ type InboxReader struct {
 	// Thread safe
 	delayedBridge        *ethbridge.DelayedBridgeWatcher
 	sequencerInbox       *ethbridge.SequencerInboxWatcher
+	outbox               *ethbridge.OutboxWatcher
 	bridgeUtils          *ethbridge.BridgeUtils
 	caughtUpChan         chan bool
 	MessageDeliveryMutex sync.Mutex
@@ -76,11 +87,13 @@ func NewInboxReader(
 	ctx context.Context,
 	bridge *ethbridge.DelayedBridgeWatcher,
 	sequencerInbox *ethbridge.SequencerInboxWatcher,
+	outbox *ethbridge.OutboxWatcher,
 	bridgeUtils *ethbridge.BridgeUtils,
 	db core.ArbCore,
 	healthChan chan nodehealth.Log,
 	broadcastFeed chan broadcaster.BroadcastFeedMessage,
 	inboxReaderConfig configuration.InboxReader,
+	checkpointFilename string,
 ) (*InboxReader, error) {
 	firstMessageBlock := bridge.FromBlock()
 	if firstMessageBlock <= 1 {
@@ -93,6 +106,7 @@ func NewInboxReader(
 	return &InboxReader{
 		delayedBridge:      bridge,
 		sequencerInbox:     sequencerInbox,
+		outbox:             outbox,
 		bridgeUtils:        bridgeUtils,
 		db:                 db,
 		firstMessageBlock:  big.NewInt(firstMessageBlock),
@@ -102,6 +116,9 @@ func NewInboxReader(
 		BroadcastFeed:      broadcastFeed,
 		inboxReaderConfig:  inboxReaderConfig,
 		sequencerAddresses: make(map[ethcommon.Address]time.Time),
+		rangeSizer:         newBlockRangeSizer(inboxReaderConfig),
+		reorgStep:          big.NewInt(minReorgStep),
+		checkpointFilename: checkpointFilename,
 	}, nil
 }
 
@@ -156,6 +173,10 @@ func (ir *InboxReader) GetSequencerInboxWatcher() *ethbridge.SequencerInboxWatch
 	return ir.sequencerInbox
 }
 
+func (ir *InboxReader) GetOutboxWatcher() *ethbridge.OutboxWatcher {
+	return ir.outbox
+}
+
 func (ir *InboxReader) isValidSignature(ctx context.Context, message broadcaster.BroadcastFeedMessage) bool {
 	if message.FeedItem.BatchItem.Accumulator.Equals(common.Hash{}) {
 		// Nitro feed message, ignore
@@ -210,14 +231,13 @@ func (ir *InboxReader) isValidSignature(ctx context.Context, message broadcaster
 }
 
 func (ir *InboxReader) getMessages(ctx context.Context, temporarilyParanoid bool, inboxReaderDelayBlocks int64) error {
-	from, err := ir.getNextBlockToRead()
+	from, err := ir.getNextBlockToRead(ctx)
 	if err != nil {
 		return err
 	}
 	if ir.healthChan != nil && from != nil {
 		ir.healthChan <- nodehealth.Log{Comp: "InboxReader", Var: "getNextBlockToRead", ValBigInt: new(big.Int).Set(from)}
 	}
-	blocksToFetch := uint64(100)
 	missingFeedDelayedReference := false
 	for {
 		select {
@@ -281,9 +301,13 @@ func (ir *InboxReader) getMessages(ctx context.Context, temporarilyParanoid bool
 					ir.caughtUpChan <- true
 				}
 			}
+			blocksToFetch := ir.rangeSizer.Size()
 			if from.Cmp(currentHeight) >= 0 {
 				if reorgingDelayed || reorgingSequencer {
 					from = new(big.Int).Sub(currentHeight, new(big.Int).SetUint64(blocksToFetch))
+					if from.Cmp(ir.firstMessageBlock) < 0 {
+						from = new(big.Int).Set(ir.firstMessageBlock)
+					}
 				} else {
 					break
 				}
@@ -294,10 +318,16 @@ func (ir *InboxReader) getMessages(ctx context.Context, temporarilyParanoid bool
 			}
 			delayedMessages, err := ir.delayedBridge.LookupMessagesInRange(ctx, from, to)
 			if err != nil {
+				if ir.shrinkRangeAfterError(ctx, err) {
+					continue
+				}
 				return err
 			}
 			sequencerBatches, err := ir.sequencerInbox.LookupBatchesInRange(ctx, from, to)
 			if err != nil {
+				if ir.shrinkRangeAfterError(ctx, err) {
+					continue
+				}
 				return err
 			}
 			if to.Cmp(currentHeight) == 0 && !reorgingDelayed && !reorgingSequencer {
@@ -364,18 +394,12 @@ func (ir *InboxReader) getMessages(ctx context.Context, temporarilyParanoid bool
 			if ir.healthChan != nil && ir.caughtUpTarget != nil {
 				ir.healthChan <- nodehealth.Log{Comp: "InboxReader", Var: "caughtUpTarget", ValBigInt: new(big.Int).Set(ir.caughtUpTarget)}
 			}
-			if len(sequencerBatches) < 5 {
-				blocksToFetch += 20
-			} else if len(sequencerBatches) > 10 {
-				blocksToFetch /= 2
-			}
-			if blocksToFetch < 2 {
-				blocksToFetch = 2
-			}
+			ir.rangeSizer.Succeeded(len(sequencerBatches))
 
 			logMsg := logger.Debug().
 				Str("from", from.String()).
 				Str("to", to.String()).
+				Uint64("blocksToFetch", blocksToFetch).
 				Int("delayedCount", len(delayedMessages)).
 				Int("batchCount", len(sequencerBatches))
 			if len(sequencerBatches) > 0 {
@@ -394,12 +418,16 @@ func (ir *InboxReader) getMessages(ctx context.Context, temporarilyParanoid bool
 				}
 			}
 			if reorgingDelayed || reorgingSequencer {
-				from, err = ir.getPrevBlockForReorg(from)
+				from, err = ir.getPrevBlockForReorg(ctx, from)
 				if err != nil {
 					return err
 				}
 			} else {
-				delta := new(big.Int).SetUint64(blocksToFetch)
+				ir.reorgStep.SetInt64(minReorgStep)
+				if err := ir.maybeSaveCheckpoint(ctx, to); err != nil {
+					logger.Warn().Err(err).Msg("failed to save inbox reader checkpoint")
+				}
+				delta := new(big.Int).SetUint64(ir.rangeSizer.Size())
 				if new(big.Int).Add(to, delta).Cmp(currentHeight) >= 0 {
 					delta = delta.Div(delta, big.NewInt(2))
 					from = from.Add(from, delta)
@@ -508,14 +536,21 @@ func (ir *InboxReader) deliverQueueItems(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
-func (ir *InboxReader) getNextBlockToRead() (*big.Int, error) {
+func (ir *InboxReader) getNextBlockToRead(ctx context.Context) (*big.Int, error) {
 	messageCount, err := ir.db.GetMessageCount()
 	if err != nil {
 		return nil, err
 	}
 	ir.lastCount = new(big.Int).Set(messageCount)
+	checkpointBlock, err := ir.loadCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
 	if messageCount.Cmp(big.NewInt(0)) == 0 {
-		return ir.firstMessageBlock, nil
+		if checkpointBlock != nil {
+			return checkpointBlock, nil
+		}
+		return new(big.Int).Set(ir.firstMessageBlock), nil
 	}
 	var acc common.Hash
 	if messageCount.Cmp(big.NewInt(0)) > 0 {
@@ -545,20 +580,126 @@ func (ir *InboxReader) getNextBlockToRead() (*big.Int, error) {
 	if startBlock.Sign() < 0 {
 		startBlock.SetInt64(0)
 	}
+	if checkpointBlock != nil && checkpointBlock.Cmp(startBlock) > 0 {
+		// Everything up to the checkpoint has already been read
+		return checkpointBlock, nil
+	}
 	return startBlock, nil
 }
 
-func (ir *InboxReader) getPrevBlockForReorg(from *big.Int) (*big.Int, error) {
-	if from.Cmp(ir.firstMessageBlock) == 0 {
+func (ir *InboxReader) getPrevBlockForReorg(ctx context.Context, from *big.Int) (*big.Int, error) {
+	floor := ir.firstMessageBlock
+	if ir.checkpoint != nil {
+		checkpointBlock := ir.checkpoint.Block()
+		if from.Cmp(checkpointBlock) <= 0 {
+			logger.Warn().Uint64("block", ir.checkpoint.BlockNumber).Msg("reorg is older than inbox reader checkpoint, discarding it")
+			ir.checkpoint = nil
+		} else {
+			valid, err := ir.checkpointValid(ctx, ir.checkpoint)
+			if err != nil {
+				return nil, err
+			}
+			if valid {
+				floor = checkpointBlock
+			} else {
+				logger.Warn().Uint64("block", ir.checkpoint.BlockNumber).Msg("inbox reader checkpoint no longer valid, discarding it")
+				ir.checkpoint = nil
+			}
+		}
+	}
+	if from.Cmp(ir.firstMessageBlock) <= 0 {
 		return nil, errors.New("can't get older messages")
 	}
-	newFrom := new(big.Int).Sub(from, big.NewInt(10))
-	if newFrom.Cmp(ir.firstMessageBlock) < 0 {
-		newFrom = ir.firstMessageBlock
+	newFrom := new(big.Int).Sub(from, ir.reorgStep)
+	if newFrom.Cmp(floor) < 0 {
+		newFrom = new(big.Int).Set(floor)
+	}
+	if ir.reorgStep.Cmp(big.NewInt(maxReorgStep)) < 0 {
+		ir.reorgStep.Lsh(ir.reorgStep, 1)
 	}
 	return newFrom, nil
 }
 
+// shrinkRangeAfterError returns true if the lookup that failed with err
+// should be retried with a smaller block range
+func (ir *InboxReader) shrinkRangeAfterError(ctx context.Context, err error) bool {
+	if !isLogRangeError(ctx, err) || !ir.rangeSizer.Failed() {
+		return false
+	}
+	logger.Warn().Err(err).Uint64("blocksToFetch", ir.rangeSizer.Size()).Msg("reducing inbox lookup range after error")
+	return true
+}
+
+// loadCheckpoint reads the persisted checkpoint if there is one and returns
+// the L1 block to resume reading from, or nil if it can't be used
+func (ir *InboxReader) loadCheckpoint(ctx context.Context) (*big.Int, error) {
+	if ir.checkpointFilename == "" {
+		return nil, nil
+	}
+	if ir.checkpoint == nil {
+		checkpoint, err := LoadInboxCheckpoint(ir.checkpointFilename)
+		if err != nil {
+			logger.Warn().Err(err).Msg("ignoring unreadable inbox reader checkpoint")
+			return nil, nil
+		}
+		if checkpoint == nil {
+			return nil, nil
+		}
+		ir.checkpoint = checkpoint
+	}
+	valid, err := ir.checkpointValid(ctx, ir.checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		logger.Warn().Uint64("block", ir.checkpoint.BlockNumber).Msg("inbox reader checkpoint no longer valid, discarding it")
+		ir.checkpoint = nil
+		return nil, nil
+	}
+	checkpointBlock := ir.checkpoint.Block()
+	if checkpointBlock.Cmp(ir.firstMessageBlock) < 0 {
+		return nil, nil
+	}
+	logger.Info().Uint64("block", ir.checkpoint.BlockNumber).Msg("resuming inbox reader from checkpoint")
+	return checkpointBlock, nil
+}
+
+// checkpointValid returns true if the checkpointed L1 block is still canonical
+// and ArbCore still holds the messages read up to it
+func (ir *InboxReader) checkpointValid(ctx context.Context, checkpoint *InboxCheckpoint) (bool, error) {
+	if !checkpoint.matchesCore(ir.db) {
+		return false, nil
+	}
+	blockHash, err := ir.delayedBridge.BlockHash(ctx, checkpoint.Block())
+	if err != nil {
+		return false, err
+	}
+	return blockHash.ToEthHash() == checkpoint.BlockHash, nil
+}
+
+func (ir *InboxReader) maybeSaveCheckpoint(ctx context.Context, to *big.Int) error {
+	if ir.checkpointFilename == "" || time.Since(ir.lastCheckpointSave) < ir.inboxReaderConfig.CheckpointInterval {
+		return nil
+	}
+	if ir.checkpoint != nil && to.Cmp(ir.checkpoint.Block()) <= 0 {
+		return nil
+	}
+	blockHash, err := ir.delayedBridge.BlockHash(ctx, to)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := newInboxCheckpoint(ir.db, to, blockHash)
+	if err != nil {
+		return err
+	}
+	if err := SaveInboxCheckpoint(ir.checkpointFilename, checkpoint); err != nil {
+		return err
+	}
+	ir.checkpoint = checkpoint
+	ir.lastCheckpointSave = time.Now()
+	return nil
+}
+
 func (ir *InboxReader) addMessages(ctx context.Context, sequencerBatchRefs []ethbridge.SequencerBatchRef, deliveredDelayedMessages []*ethbridge.DeliveredInboxMessage) (bool, error) {
 	var seqBatchItems []inbox.SequencerBatchItem
 	for _, ref := range sequencerBatchRefs {
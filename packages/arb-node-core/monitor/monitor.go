@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -46,6 +47,8 @@ type Monitor struct {
 	Core       core.ArbCore
 	Reader     *InboxReader
 	CoreConfig *configuration.Core
+
+	dbDir string
 }
 
 func NewInitializedMonitor(dbDir string, contractFile string, coreConfig *configuration.Core) (*Monitor, error) {
@@ -76,6 +79,7 @@ func NewMonitorWithFinalBlock(dbDir string, coreConfig *configuration.Core, fina
 		Storage:    storage,
 		Core:       storage.GetArbCore(),
 		CoreConfig: coreConfig,
+		dbDir:      dbDir,
 	}, nil
 }
 
@@ -156,19 +160,33 @@ func (m *Monitor) StartInboxReader(
 	if err != nil {
 		return nil, nil, err
 	}
+	outboxAddress, err := rollup.Outbox(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	outboxWatcher, err := ethbridge.NewOutboxWatcher(outboxAddress.ToEthAddress(), ethClient)
+	if err != nil {
+		return nil, nil, err
+	}
 	bridgeUtils, err := ethbridge.NewBridgeUtils(bridgeUtilsAddress.ToEthAddress(), ethClient, delayedBridgeWatcher, sequencerInboxWatcher)
 	if err != nil {
 		return nil, nil, err
 	}
+	var checkpointFilename string
+	if inboxReaderConfig.SaveCheckpoint {
+		checkpointFilename = filepath.Join(m.dbDir, InboxCheckpointFilename)
+	}
 	reader, err := NewInboxReader(
 		ctx,
 		delayedBridgeWatcher,
 		sequencerInboxWatcher,
+		outboxWatcher,
 		bridgeUtils,
 		m.Core,
 		healthChan,
 		sequencerFeed,
 		inboxReaderConfig,
+		checkpointFilename,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -307,3 +307,19 @@ func (m *Server) SubscribeBlockProcessingEvent(ch chan<- []*types.Log) event.Sub
 func (m *Server) GetLookup() core.ArbCoreLookup {
 	return m.db.Lookup
 }
+
+func (m *Server) AddressIndex() *txdb.AddressIndex {
+	return m.db.AddressIndex()
+}
+
+func (m *Server) RetryableIndex() *txdb.RetryableIndex {
+	return m.db.RetryableIndex()
+}
+
+func (m *Server) WithdrawalIndex() *txdb.WithdrawalIndex {
+	return m.db.WithdrawalIndex()
+}
+
+func (m *Server) GetMessageBatch(batchNumber *big.Int) (*evm.MerkleRootResult, error) {
+	return m.db.GetMessageBatch(batchNumber)
+}
@@ -17,6 +17,7 @@
 package arbostest
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"math/big"
@@ -101,6 +102,9 @@ func processResults(t *testing.T, logs []value.Value) []evm.Result {
 	for _, avmLog := range logs {
 		res, err := evm.NewResultFromValue(avmLog)
 		failIfError(t, err)
+		if res, ok := res.(*evm.TxResult); ok {
+			checkStreamedTxResult(t, avmLog, res)
+		}
 		if res, ok := res.(*evm.BlockInfo); ok {
 			if res.GasLimit().Cmp(big.NewInt(1_000_000_000_000)) > 0 {
 				t.Error("block gas limit too high", res.GasLimit())
@@ -111,6 +115,30 @@ func processResults(t *testing.T, logs []value.Value) []evm.Result {
 	return results
 }
 
+// checkStreamedTxResult checks that decoding the serialized log with
+// NewTxResultFromReader gives the same result as parsing the log value
+func checkStreamedTxResult(t *testing.T, avmLog value.Value, expected *evm.TxResult) {
+	t.Helper()
+	var buf bytes.Buffer
+	failIfError(t, value.MarshalValue(avmLog, &buf))
+	res, err := evm.NewTxResultFromReader(&buf, value.DefaultDecoderConfig)
+	failIfError(t, err)
+	if res.IncomingRequest.MessageID != expected.IncomingRequest.MessageID ||
+		res.ResultCode != expected.ResultCode ||
+		!bytes.Equal(res.ReturnData, expected.ReturnData) ||
+		res.GasUsed.Cmp(expected.GasUsed) != 0 {
+		t.Error("streamed tx result doesn't match", res, expected)
+	}
+	if len(res.EVMLogs) != len(expected.EVMLogs) {
+		t.Fatalf("streamed tx result has %v logs but expected %v", len(res.EVMLogs), len(expected.EVMLogs))
+	}
+	for i := range res.EVMLogs {
+		if !res.EVMLogs[i].Equals(expected.EVMLogs[i]) {
+			t.Errorf("streamed log %v is %v but expected %v", i, res.EVMLogs[i], expected.EVMLogs[i])
+		}
+	}
+}
+
 func processDebugPrints(t *testing.T, debugPrints []value.Value) []evm.EVMLogLine {
 	t.Helper()
 	results := make([]evm.EVMLogLine, 0, len(debugPrints))
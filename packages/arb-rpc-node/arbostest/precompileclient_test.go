@@ -0,0 +1,75 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package arbostest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestPrecompileClient(t *testing.T) {
+	ctx := context.Background()
+	var messages []message.Message
+	for i := 0; i < 3; i++ {
+		data := arbos.AddressTableRegisterData(common.RandAddress())
+		messages = append(messages, makeSyscallTx(data, big.NewInt(int64(i)), common.NewAddressFromEth(arbos.ARB_ADDRESS_TABLE_ADDRESS)))
+	}
+	_, snap := runSimpleTxAssertion(t, messages)
+
+	precompiles, err := arbos.NewPrecompiles(snapshot.NewContractBackend(snap))
+	failIfError(t, err)
+	opts := &bind.CallOpts{Context: ctx}
+
+	version, err := precompiles.ArbSys.ArbOSVersion(opts)
+	failIfError(t, err)
+	expectedVersion, err := snap.ArbOSVersion(ctx)
+	failIfError(t, err)
+	if version.Cmp(expectedVersion) != 0 {
+		t.Error("wrong ArbOS version", version, "instead of", expectedVersion)
+	}
+
+	size, err := precompiles.ArbAddressTable.Size(opts)
+	failIfError(t, err)
+	expectedSize, err := snap.AddressTableSize(ctx)
+	failIfError(t, err)
+	if size.Uint64() != expectedSize {
+		t.Error("wrong address table size", size, "instead of", expectedSize)
+	}
+
+	perL2Tx, perL1CalldataUnit, perStorage, perArbGasBase, perArbGasCongestion, perArbGasTotal, err := precompiles.ArbGasInfo.GetPricesInWei(opts)
+	failIfError(t, err)
+	expectedPrices, err := snap.GetPricesInWei(ctx)
+	failIfError(t, err)
+	prices := []*big.Int{perL2Tx, perL1CalldataUnit, perStorage, perArbGasBase, perArbGasCongestion, perArbGasTotal}
+	for i, price := range prices {
+		if price.Cmp(expectedPrices[i]) != 0 {
+			t.Error("wrong price", i, price, "instead of", expectedPrices[i])
+		}
+	}
+
+	if _, err := precompiles.NodeInterface.LookupMessageBatchProof(opts, big.NewInt(0), 0); err == nil {
+		t.Error("NodeInterface call should fail against a snapshot")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+const addressTableRefreshInterval = 10 * time.Second
+
+// addressTable is a local copy of the ArbOS address table, used to replace
+// the destinations of batched transactions with their shorter table index.
+// ArbOS resolves the index back to the full address before checking the
+// signature, so the transaction itself is unchanged.
+//
+// Entries in the table are never removed or reassigned, so a copy which is
+// behind the chain is still correct, it just compresses fewer addresses.
+type addressTable struct {
+	mu      sync.Mutex
+	enabled bool
+	indexes map[common.Address]*big.Int
+	size    uint64
+}
+
+func newAddressTable() *addressTable {
+	return &addressTable{indexes: make(map[common.Address]*big.Int)}
+}
+
+// start enables compression and keeps the table in sync with the snapshots
+// returned by latestSnapshot until ctx is done
+func (t *addressTable) start(ctx context.Context, latestSnapshot func(ctx context.Context) (*snapshot.Snapshot, error)) {
+	t.mu.Lock()
+	t.enabled = true
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(addressTableRefreshInterval)
+		defer ticker.Stop()
+		for {
+			snap, err := latestSnapshot(ctx)
+			if err != nil {
+				logger.Warn().Err(err).Msg("error getting snapshot to refresh address table")
+			} else if snap != nil {
+				if err := t.update(ctx, snap); err != nil {
+					logger.Warn().Err(err).Msg("error refreshing address table")
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// update loads the entries registered since the last update. The lock isn't
+// held while querying the snapshot, so only one update may run at a time.
+func (t *addressTable) update(ctx context.Context, snap *snapshot.Snapshot) error {
+	size, err := snap.AddressTableSize(ctx)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	start := t.size
+	t.mu.Unlock()
+	for i := start; i < size; i++ {
+		address, err := snap.AddressTableLookupIndex(ctx, i)
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.indexes[address] = new(big.Int).SetUint64(i)
+		t.size = i + 1
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+func (t *addressTable) lookup(address common.Address) *big.Int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.enabled {
+		return nil
+	}
+	return t.indexes[address]
+}
+
+// compressTx converts tx into its compressed form for a batch, using the
+// destination's index if it's registered. It also returns the number of
+// bytes that saved.
+func (t *addressTable) compressTx(tx *types.Transaction) (message.CompressedECDSATransaction, int) {
+	compressed := message.NewCompressedECDSAFromEth(tx)
+	if tx.To() == nil {
+		return compressed, 0
+	}
+	index := t.lookup(common.NewAddressFromEth(*tx.To()))
+	// Index 0 would be encoded as empty bytes, which means contract creation
+	if index == nil || index.Sign() == 0 {
+		return compressed, 0
+	}
+	fullData, err := compressed.To.Encode()
+	if err != nil {
+		return compressed, 0
+	}
+	indexAddress := message.CompressedAddressIndex{Int: index}
+	indexData, err := indexAddress.Encode()
+	if err != nil || len(indexData) >= len(fullData) {
+		return compressed, 0
+	}
+	compressed.To = indexAddress
+	return compressed, len(fullData) - len(indexData)
+}
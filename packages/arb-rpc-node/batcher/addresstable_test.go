@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestAddressTableCompression(t *testing.T) {
+	registered := ethcommon.Address{1}
+	first := ethcommon.Address{2}
+	unregistered := ethcommon.Address{3}
+
+	table := newAddressTable()
+	table.indexes[common.NewAddressFromEth(first)] = big.NewInt(0)
+	table.indexes[common.NewAddressFromEth(registered)] = big.NewInt(5)
+	table.size = 6
+
+	makeTx := func(dest ethcommon.Address) *types.Transaction {
+		return types.NewTransaction(0, dest, big.NewInt(0), 100000, big.NewInt(0), nil)
+	}
+
+	if _, saved := table.compressTx(makeTx(registered)); saved != 0 {
+		t.Error("compressed address before being enabled")
+	}
+	table.enabled = true
+
+	compressed, saved := table.compressTx(makeTx(registered))
+	index, ok := compressed.To.(message.CompressedAddressIndex)
+	if !ok || index.Cmp(big.NewInt(5)) != 0 {
+		t.Fatal("expected registered address to be compressed, got", compressed.To)
+	}
+	// 21 bytes for the full address down to 1 byte for the index
+	if saved != 20 {
+		t.Error("unexpected savings", saved)
+	}
+	data, err := compressed.AsData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := message.NewCompressedECDSAFromEth(makeTx(registered)).AsData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full)-len(data) != saved {
+		t.Error("savings don't match encoded size", len(full), len(data), saved)
+	}
+
+	for _, dest := range []ethcommon.Address{first, unregistered} {
+		compressed, saved := table.compressTx(makeTx(dest))
+		if _, ok := compressed.To.(message.CompressedAddressFull); !ok || saved != 0 {
+			t.Error("expected full address for", dest.Hex(), "got", compressed.To)
+		}
+	}
+}
@@ -97,6 +97,7 @@ type Batcher struct {
 	pendingBatch       batch
 	pendingSentBatches *list.List
 	newTxFeed          event.Feed
+	addressTable       *addressTable
 }
 
 func NewStatefulBatcher(
@@ -155,6 +156,7 @@ func newBatcher(
 		queuedTxes:         newTxQueues(),
 		pendingBatch:       pendingBatch,
 		pendingSentBatches: list.New(),
+		addressTable:       newAddressTable(),
 	}
 
 	go func() {
@@ -212,6 +214,13 @@ func newBatcher(
 	return server
 }
 
+// EnableAddressCompression makes the batcher replace the destinations of
+// transactions with their index in the ArbOS address table, keeping its copy
+// of the table in sync with the snapshots returned by latestSnapshot
+func (m *Batcher) EnableAddressCompression(ctx context.Context, latestSnapshot func(ctx context.Context) (*snapshot.Snapshot, error)) {
+	m.addressTable.start(ctx, latestSnapshot)
+}
+
 func (m *Batcher) handleNextTx(ctx context.Context) bool {
 	tx, accountIndex, cont := popRandomTx(ctx, m.pendingBatch, m.queuedTxes)
 	if tx != nil {
@@ -233,25 +242,28 @@ func (m *Batcher) maybeSubmitBatch(ctx context.Context, maxBatchTime time.Durati
 		return false, nil
 	}
 	batchTxes := make([]message.AbstractL2Message, 0, len(txes))
+	bytesSaved := 0
 	for _, tx := range txes {
-		batchTxes = append(batchTxes, message.NewCompressedECDSAFromEth(tx))
+		compressed, saved := m.addressTable.compressTx(tx)
+		batchTxes = append(batchTxes, compressed)
+		bytesSaved += saved
 	}
 	batchTx, err := message.NewTransactionBatchFromMessages(batchTxes)
 	if err != nil {
 		return false, errors.Wrap(err, "invalid transaction in batch")
 	}
 
-	logger.Info().Int("txcount", len(txes)).Msg("Submitting batch")
+	logger.Info().Int("txcount", len(txes)).Int("addressBytesSaved", bytesSaved).Msg("Submitting batch")
 	batchData := message.NewSafeL2Message(batchTx).AsData()
 	tx, err := globalInbox.SendL2MessageFromOrigin(ctx, batchData)
 	if err != nil {
 		return false, errors.Wrap(err, "error calling SendL2MessageFromOrigin")
 	}
 
+	monitor.GlobalMonitor.SubmittedBatch(common.NewHashFromEth(tx.Hash()))
 	for _, l2tx := range txes {
-		monitor.GlobalMonitor.IncludedInBatch(common.NewHashFromEth(l2tx.Hash()), common.NewHashFromEth(l2tx.Hash()))
+		monitor.GlobalMonitor.IncludedInBatch(common.NewHashFromEth(l2tx.Hash()), common.NewHashFromEth(tx.Hash()))
 	}
-	monitor.GlobalMonitor.SubmittedBatch(common.NewHashFromEth(tx.Hash()))
 
 	m.Lock()
 	m.pendingBatch = m.pendingBatch.newFromExisting()
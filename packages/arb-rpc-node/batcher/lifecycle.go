@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package batcher
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/monitor"
+)
+
+type sequencedTxs struct {
+	seqNum   *big.Int
+	txHashes []common.Hash
+}
+
+// unpostedTxs remembers the transactions the sequencer has sequenced but not
+// yet posted to L1, so that their lifecycle can be linked to the batch which
+// eventually includes them. Reorgs rewrite sequencer messages in place, so
+// sequence numbers stay valid.
+type unpostedTxs struct {
+	sync.Mutex
+	pending []sequencedTxs
+}
+
+func (u *unpostedTxs) add(seqNum *big.Int, txHashes []common.Hash) {
+	u.Lock()
+	defer u.Unlock()
+	u.pending = append(u.pending, sequencedTxs{seqNum: new(big.Int).Set(seqNum), txHashes: txHashes})
+}
+
+// submitted records the transactions sequenced through lastSeqNum as posted
+// in the L1 transaction batchTxHash
+func (u *unpostedTxs) submitted(lastSeqNum *big.Int, batchTxHash common.Hash) {
+	u.Lock()
+	defer u.Unlock()
+	monitor.GlobalMonitor.SubmittedBatch(batchTxHash)
+	posted := 0
+	for posted < len(u.pending) && u.pending[posted].seqNum.Cmp(lastSeqNum) <= 0 {
+		for _, txHash := range u.pending[posted].txHashes {
+			monitor.GlobalMonitor.IncludedInBatch(txHash, batchTxHash)
+		}
+		posted++
+	}
+	u.pending = u.pending[posted:]
+}
+
+func (b *SequencerBatcher) recordReceived(tx *types.Transaction) {
+	monitor.GlobalMonitor.GotTransactionFromUser(common.NewHashFromEth(tx.Hash()))
+}
+
+// recordSequenced records that the sequencer message seqNum contains txHashes
+func (b *SequencerBatcher) recordSequenced(seqNum *big.Int, txHashes []common.Hash) {
+	for _, txHash := range txHashes {
+		monitor.GlobalMonitor.Sequenced(txHash)
+	}
+	if b.config.Node.Sequencer.Dangerous.DisableBatchPosting {
+		// No batch will be posted to link them to
+		return
+	}
+	b.unposted.add(seqNum, txHashes)
+}
+
+func (b *SequencerBatcher) recordBatchAccepted(receipt *types.Receipt) {
+	monitor.GlobalMonitor.BatchAccepted(common.NewHashFromEth(receipt.TxHash))
+}
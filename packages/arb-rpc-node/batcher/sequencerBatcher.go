@@ -94,6 +94,12 @@ type SequencerBatcher struct {
 	// The total estimate of unpublished transactions' gas usage.
 	// Added to every time something is sequenced, zeroed when batch posted.
 	pendingBatchGasEstimateAtomic int64
+	// Calldata saved by address compression in unpublished transactions.
+	// Added to every time something is sequenced, zeroed when batch posted.
+	pendingAddressBytesSavedAtomic int64
+
+	addressTable *addressTable
+	unposted     unpostedTxs
 }
 
 var refundGasCostsDeniedEventID ethcommon.Hash
@@ -226,11 +232,19 @@ func NewSequencerBatcher(
 		publishingBatchesAtomic:       0,
 		pendingBatchGasEstimateAtomic: int64(gasCostBase),
 		fb:                            fb,
+		addressTable:                  newAddressTable(),
 	}
 
 	return batcher, nil
 }
 
+// EnableAddressCompression makes the sequencer replace the destinations of
+// transactions with their index in the ArbOS address table, keeping its copy
+// of the table in sync with the snapshots returned by latestSnapshot
+func (b *SequencerBatcher) EnableAddressCompression(ctx context.Context, latestSnapshot func(ctx context.Context) (*snapshot.Snapshot, error)) {
+	b.addressTable.start(ctx, latestSnapshot)
+}
+
 func (b *SequencerBatcher) PendingTransactionCount(_ context.Context, _ common.Address) (*uint64, error) {
 	return nil, nil
 }
@@ -281,6 +295,7 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 		return errors.New("oversized data")
 	}
 	logger.Info().Str("hash", startTx.Hash().String()).Msg("got user tx")
+	b.recordReceived(startTx)
 
 	startResultChan := make(chan error, 1)
 	b.txQueue <- txQueueItem{tx: startTx, resultChan: startResultChan, ctx: startCtx}
@@ -319,6 +334,7 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 		var resultChans []chan error
 		var l2BatchContents []message.AbstractL2Message
 		var batchDataSize int
+		var addressBytesSaved []int
 		seenOwnTx := false
 		emptiedQueue := true
 		txHashesSet := make(map[ethcommon.Hash]struct{})
@@ -358,7 +374,9 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 			}
 			batchTxs = append(batchTxs, queueItem.tx)
 			resultChans = append(resultChans, queueItem.resultChan)
-			l2BatchContents = append(l2BatchContents, message.NewCompressedECDSAFromEth(queueItem.tx))
+			compressed, saved := b.addressTable.compressTx(queueItem.tx)
+			l2BatchContents = append(l2BatchContents, compressed)
+			addressBytesSaved = append(addressBytesSaved, saved)
 			batchDataSize += len(queueItem.tx.Data())
 			txHashesSet[txHash] = struct{}{}
 		}
@@ -367,7 +385,9 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 			// Let's try again ourselves (if we fail this time we won't try again)
 			batchTxs = append(batchTxs, startTx)
 			resultChans = append(resultChans, startResultChan)
-			l2BatchContents = append(l2BatchContents, message.NewCompressedECDSAFromEth(startTx))
+			compressed, saved := b.addressTable.compressTx(startTx)
+			l2BatchContents = append(l2BatchContents, compressed)
+			addressBytesSaved = append(addressBytesSaved, saved)
 			seenOwnTx = true
 		}
 		if len(batchTxs) == 0 {
@@ -453,11 +473,15 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 			}
 		}
 		if successCount == len(batchTxs) {
+			b.recordSequenced(msgCount, txHashes)
 			msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
 			prevAcc = txBatchItem.Accumulator
 			sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
 			postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
 			atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
+			for _, saved := range addressBytesSaved {
+				atomic.AddInt64(&b.pendingAddressBytesSavedAtomic, int64(saved))
+			}
 			for _, c := range resultChans {
 				c <- nil
 			}
@@ -491,7 +515,7 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 					resultChans[i] <- evm.HandleCallError(txResults[txHash], false)
 					continue
 				}
-				l2Msg := message.NewCompressedECDSAFromEth(tx)
+				l2Msg, _ := b.addressTable.compressTx(tx)
 				batch, err = message.NewTransactionBatchFromMessages([]message.AbstractL2Message{l2Msg})
 				if err != nil {
 					return err
@@ -525,11 +549,13 @@ func (b *SequencerBatcher) SendTransaction(startCtx context.Context, startTx *ty
 					resultChans[i] <- evm.HandleCallError(txResult, false)
 					continue
 				}
+				b.recordSequenced(msgCount, []common.Hash{txHash})
 				msgCount = new(big.Int).Add(msgCount, big.NewInt(1))
 				prevAcc = txBatchItem.Accumulator
 				sequencedBatchItems = append(sequencedBatchItems, txBatchItem)
 				postingCostEstimate := gasCostPerMessage + gasCostPerMessageByte*len(seqMsg.Data)
 				atomic.AddInt64(&b.pendingBatchGasEstimateAtomic, int64(postingCostEstimate))
+				atomic.AddInt64(&b.pendingAddressBytesSavedAtomic, int64(addressBytesSaved[i]))
 				logCount = newLogCount
 				resultChans[i] <- nil
 			}
@@ -936,11 +962,23 @@ func (b *SequencerBatcher) publishBatch(ctx context.Context, dontPublishBlockNum
 	}
 
 	newMsgCount := new(big.Int).Add(lastSeqNum, big.NewInt(1))
-	logger.Info().Str("prevMsgCount", prevMsgCount.String()).Int("items", len(batchItems)).Str("newMsgCount", newMsgCount.String()).Msg("Creating sequencer batch")
+	var addressBytesSaved int64
+	if publishingAllBatchItems {
+		// When a batch is split, the savings of the earlier parts are
+		// reported with the last one
+		addressBytesSaved = atomic.SwapInt64(&b.pendingAddressBytesSavedAtomic, 0)
+	}
+	logger.Info().
+		Str("prevMsgCount", prevMsgCount.String()).
+		Int("items", len(batchItems)).
+		Str("newMsgCount", newMsgCount.String()).
+		Int64("addressBytesSaved", addressBytesSaved).
+		Msg("Creating sequencer batch")
 	arbTx, err := ethbridge.AddSequencerL2BatchFromOriginCustomNonce(ctx, b.client, b.sequencerInboxAddress, b.auth, nonce, transactionsData, transactionsLengths, metadata, lastAcc, b.gasRefunderAddress, b.config.Node.Sequencer.GasRefunderExtraGas)
 	if err != nil {
 		return false, err
 	}
+	b.unposted.submitted(lastSeqNum, common.NewHashFromEth(arbTx.Hash()))
 
 	var removedPendingGasEstimate int64
 	if publishingAllBatchItems {
@@ -966,6 +1004,7 @@ func (b *SequencerBatcher) publishBatch(ctx context.Context, dontPublishBlockNum
 		}
 
 		if receipt != nil {
+			b.recordBatchAccepted(receipt)
 			for _, log := range receipt.Logs {
 				b.handleBatchReceiptLog(log)
 			}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package callcache holds the results of calls against historical blocks.
+// Machine state can't be restored from its serialized form, so rather than
+// sharing snapshots, replicas share the results of calls they've executed.
+// Entries are keyed by block hash, so they never need to be invalidated by a
+// reorg.
+package callcache
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+)
+
+var logger = arblog.Logger.With().Str("component", "callcache").Logger()
+
+// Cache stores serialized call results. Get returns nil if the key isn't
+// present.
+type Cache interface {
+	Get(ctx context.Context, key common.Hash) ([]byte, error)
+	Add(ctx context.Context, key common.Hash, data []byte) error
+}
+
+// Key identifies the result of the call described by call, which should be
+// a canonical encoding of everything that affects its result, executed
+// against the state at the end of blockHash
+func Key(blockHash common.Hash, call []byte) common.Hash {
+	return hashing.SoliditySHA3(hashing.Bytes32(blockHash), call)
+}
+
+// Memory is an in-process Cache holding the most recently used results
+type Memory struct {
+	cache *lru.Cache
+}
+
+func NewMemory(size int) (*Memory, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &Memory{cache: cache}, nil
+}
+
+func (m *Memory) Get(_ context.Context, key common.Hash) ([]byte, error) {
+	data, ok := m.cache.Get(key)
+	if !ok {
+		return nil, nil
+	}
+	return data.([]byte), nil
+}
+
+func (m *Memory) Add(_ context.Context, key common.Hash, data []byte) error {
+	m.cache.Add(key, data)
+	return nil
+}
+
+// Tiered checks a local cache before a remote one, copying results found
+// remotely into the local cache. New results are added to both, and failures
+// of the remote cache are logged rather than returned so that they can't
+// cause calls to fail.
+type Tiered struct {
+	local  Cache
+	remote Cache
+}
+
+func NewTiered(local, remote Cache) *Tiered {
+	return &Tiered{local: local, remote: remote}
+}
+
+func (t *Tiered) Get(ctx context.Context, key common.Hash) ([]byte, error) {
+	data, err := t.local.Get(ctx, key)
+	if err != nil || data != nil {
+		return data, err
+	}
+	data, err = t.remote.Get(ctx, key)
+	if err != nil {
+		logger.Warn().Err(err).Hex("key", key.Bytes()).Msg("error reading from remote call cache")
+		return nil, nil
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return data, t.local.Add(ctx, key, data)
+}
+
+func (t *Tiered) Add(ctx context.Context, key common.Hash, data []byte) error {
+	if err := t.local.Add(ctx, key, data); err != nil {
+		return err
+	}
+	if err := t.remote.Add(ctx, key, data); err != nil {
+		logger.Warn().Err(err).Hex("key", key.Bytes()).Msg("error writing to remote call cache")
+	}
+	return nil
+}
+
+// New builds the cache described by config, returning nil if it's disabled
+func New(config configuration.CallCache) (Cache, error) {
+	if config.Size <= 0 {
+		return nil, nil
+	}
+	local, err := NewMemory(config.Size)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Peers) == 0 {
+		return local, nil
+	}
+	return NewTiered(local, NewRemote(config.Peers, config.Secret, config.Timeout)), nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package callcache
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestSharedCache(t *testing.T) {
+	ctx := context.Background()
+
+	peerLocal, err := NewMemory(10)
+	test.FailIfError(t, err)
+	server := httptest.NewServer(Handler(peerLocal, "secret"))
+	defer server.Close()
+
+	local, err := NewMemory(10)
+	test.FailIfError(t, err)
+	cache := NewTiered(local, NewRemote([]string{server.URL + "/"}, "secret", time.Second))
+
+	key1 := Key(common.Hash{1}, []byte("call1"))
+	key2 := Key(common.Hash{2}, []byte("call1"))
+	if key1 == key2 {
+		t.Fatal("calls against different blocks should have different keys")
+	}
+
+	data, err := cache.Get(ctx, key1)
+	test.FailIfError(t, err)
+	if data != nil {
+		t.Fatal("expected empty cache")
+	}
+
+	// Results added locally are shared with the peer
+	test.FailIfError(t, cache.Add(ctx, key1, []byte("result1")))
+	data, err = peerLocal.Get(ctx, key1)
+	test.FailIfError(t, err)
+	if !bytes.Equal(data, []byte("result1")) {
+		t.Error("expected peer to receive result, got", data)
+	}
+
+	// Results found at the peer are copied into the local cache
+	test.FailIfError(t, peerLocal.Add(ctx, key2, []byte("result2")))
+	data, err = cache.Get(ctx, key2)
+	test.FailIfError(t, err)
+	if !bytes.Equal(data, []byte("result2")) {
+		t.Error("expected result from peer, got", data)
+	}
+	data, err = local.Get(ctx, key2)
+	test.FailIfError(t, err)
+	if !bytes.Equal(data, []byte("result2")) {
+		t.Error("expected result to be copied locally, got", data)
+	}
+}
+
+func TestUnreachablePeer(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewMemory(10)
+	test.FailIfError(t, err)
+	cache := NewTiered(local, NewRemote([]string{"http://127.0.0.1:1"}, "", time.Second))
+	key := Key(common.Hash{1}, []byte("call"))
+
+	test.FailIfError(t, cache.Add(ctx, key, []byte("result")))
+	data, err := cache.Get(ctx, key)
+	test.FailIfError(t, err)
+	if !bytes.Equal(data, []byte("result")) {
+		t.Error("expected local result, got", data)
+	}
+	data, err = cache.Get(ctx, Key(common.Hash{2}, []byte("call")))
+	test.FailIfError(t, err)
+	if data != nil {
+		t.Error("expected missing result, got", data)
+	}
+}
+
+func TestPeerAuthorization(t *testing.T) {
+	ctx := context.Background()
+	key := Key(common.Hash{1}, []byte("call"))
+
+	// Without a secret peers can read but not add results
+	readOnlyLocal, err := NewMemory(10)
+	test.FailIfError(t, err)
+	test.FailIfError(t, readOnlyLocal.Add(ctx, key, []byte("result")))
+	readOnly := httptest.NewServer(Handler(readOnlyLocal, ""))
+	defer readOnly.Close()
+	remote := NewRemote([]string{readOnly.URL}, "", time.Second)
+	data, err := remote.Get(ctx, key)
+	test.FailIfError(t, err)
+	if !bytes.Equal(data, []byte("result")) {
+		t.Error("expected result from read only peer, got", data)
+	}
+	otherKey := Key(common.Hash{2}, []byte("call"))
+	if err := remote.Add(ctx, otherKey, []byte("poisoned")); err == nil {
+		t.Error("expected read only peer to refuse result")
+	}
+	data, err = readOnlyLocal.Get(ctx, otherKey)
+	test.FailIfError(t, err)
+	if data != nil {
+		t.Error("read only peer stored result", data)
+	}
+
+	// With a secret, peers presenting the wrong one can't read or add results
+	securedLocal, err := NewMemory(10)
+	test.FailIfError(t, err)
+	test.FailIfError(t, securedLocal.Add(ctx, key, []byte("result")))
+	secured := httptest.NewServer(Handler(securedLocal, "secret"))
+	defer secured.Close()
+	remote = NewRemote([]string{secured.URL}, "wrong", time.Second)
+	if _, err := remote.Get(ctx, key); err == nil {
+		t.Error("expected peer to refuse read with wrong secret")
+	}
+	if err := remote.Add(ctx, otherKey, []byte("poisoned")); err == nil {
+		t.Error("expected peer to refuse result with wrong secret")
+	}
+	data, err = securedLocal.Get(ctx, otherKey)
+	test.FailIfError(t, err)
+	if data != nil {
+		t.Error("peer stored result with wrong secret", data)
+	}
+}
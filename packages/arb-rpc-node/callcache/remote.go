@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package callcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// Largest result accepted from or by a peer
+const maxEntrySize = 16 * 1024 * 1024
+
+// Remote is a Cache backed by the caches of other nodes, served by Handler.
+// Results are looked up from each peer in turn and added to all of them,
+// authenticating with secret if it isn't empty.
+type Remote struct {
+	peers  []string
+	secret string
+	client *http.Client
+}
+
+func NewRemote(peers []string, secret string, timeout time.Duration) *Remote {
+	trimmed := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		trimmed = append(trimmed, strings.TrimSuffix(peer, "/"))
+	}
+	return &Remote{
+		peers:  trimmed,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (r *Remote) request(ctx context.Context, method, peer string, key common.Hash, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, peer+"/"+key.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if r.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+r.secret)
+	}
+	return r.client.Do(req)
+}
+
+func (r *Remote) Get(ctx context.Context, key common.Hash) ([]byte, error) {
+	var lastErr error
+	for _, peer := range r.peers {
+		resp, err := r.request(ctx, http.MethodGet, peer, key, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxEntrySize))
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = errors.Errorf("peer %v returned status %v", peer, resp.StatusCode)
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return data, nil
+	}
+	return nil, lastErr
+}
+
+func (r *Remote) Add(ctx context.Context, key common.Hash, data []byte) error {
+	var lastErr error
+	for _, peer := range r.peers {
+		resp, err := r.request(ctx, http.MethodPost, peer, key, bytes.NewReader(data))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			lastErr = errors.Errorf("peer %v returned status %v", peer, resp.StatusCode)
+		}
+	}
+	return lastErr
+}
+
+type handler struct {
+	cache  Cache
+	secret string
+}
+
+// Handler serves cache to other nodes' Remote caches. Only the local part of
+// a Tiered cache is served, otherwise peers would forward requests back and
+// forth. If secret is empty the cache is served read only, since anyone
+// able to add results could poison the calls of every node sharing it.
+// Otherwise peers must present secret to read or add results.
+func Handler(cache Cache, secret string) http.Handler {
+	if tiered, ok := cache.(*Tiered); ok {
+		cache = tiered.local
+	}
+	return handler{cache: cache, secret: secret}
+}
+
+func (h handler) authorized(r *http.Request) bool {
+	if h.secret == "" {
+		return r.Method != http.MethodPost
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(h.secret)) == 1
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	keyStr := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if len(keyStr) != 66 || !strings.HasPrefix(keyStr, "0x") {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+	key := common.HexToHash(keyStr)
+	switch r.Method {
+	case http.MethodGet:
+		data, err := h.cache.Get(r.Context(), key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if _, err := w.Write(data); err != nil {
+			logger.Warn().Err(err).Msg("error writing call cache entry")
+		}
+	case http.MethodPost:
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxEntrySize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := h.cache.Add(r.Context(), key, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
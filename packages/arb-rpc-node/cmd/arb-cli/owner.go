@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+)
+
+// parseParamId accepts either a parameter's 32 byte id, or its name, whose
+// hash is the id
+func parseParamId(arg string) (common.Hash, error) {
+	if strings.HasPrefix(arg, "0x") {
+		data, err := hexutil.Decode(arg)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if len(data) != 32 {
+			return common.Hash{}, errors.Errorf("parameter id %v must be 32 bytes", arg)
+		}
+		var id common.Hash
+		copy(id[:], data)
+		return id, nil
+	}
+	return hashing.SoliditySHA3([]byte(arg)), nil
+}
+
+func arbOwner() (*arboscontracts.ArbOwner, error) {
+	return arboscontracts.NewArbOwner(arbos.ARB_OWNER_ADDRESS, config.client)
+}
+
+func getChainParameter(args []string) (output, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected parameter name or id argument")
+	}
+	id, err := parseParamId(args[0])
+	if err != nil {
+		return nil, err
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	val, err := con.GetChainParameter(callOpts(nil), id)
+	if err != nil {
+		return nil, err
+	}
+	return output{{"id", id.ToEthHash()}, {"value", val}}, nil
+}
+
+func setChainParameter(args []string) (output, error) {
+	if len(args) != 2 {
+		return nil, errors.New("expected parameter name or id and value arguments")
+	}
+	id, err := parseParamId(args[0])
+	if err != nil {
+		return nil, err
+	}
+	val, err := parseInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAuth(); err != nil {
+		return nil, err
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := con.SetChainParameter(config.auth, id, val)
+	if err != nil {
+		return nil, err
+	}
+	return txOutput(tx, "SetChainParameter")
+}
+
+func addChainOwner(args []string) (output, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected address argument")
+	}
+	owner, err := parseAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAuth(); err != nil {
+		return nil, err
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := con.AddChainOwner(config.auth, owner)
+	if err != nil {
+		return nil, err
+	}
+	return txOutput(tx, "AddChainOwner")
+}
+
+func removeChainOwner(args []string) (output, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected address argument")
+	}
+	owner, err := parseAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAuth(); err != nil {
+		return nil, err
+	}
+	if owner == config.auth.From {
+		return nil, errors.New("refusing to remove the sending owner")
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := con.RemoveChainOwner(config.auth, owner)
+	if err != nil {
+		return nil, err
+	}
+	return txOutput(tx, "RemoveChainOwner")
+}
+
+func isChainOwner(args []string) (output, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected address argument")
+	}
+	addr, err := parseAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	isOwner, err := con.IsChainOwner(callOpts(nil), addr)
+	if err != nil {
+		return nil, err
+	}
+	return output{{"address", addr}, {"owner", isOwner}}, nil
+}
+
+func totalEthBalances(args []string) (output, error) {
+	var blockNum *big.Int
+	if len(args) > 1 {
+		return nil, errors.New("expected optional block number argument")
+	}
+	if len(args) == 1 {
+		var err error
+		blockNum, err = parseInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	con, err := arbOwner()
+	if err != nil {
+		return nil, err
+	}
+	total, err := con.GetTotalOfEthBalances(callOpts(blockNum))
+	if err != nil {
+		return nil, err
+	}
+	return output{{"total", total}}, nil
+}
@@ -339,7 +339,7 @@ func startup() error {
 			Port: "8548",
 			Path: "/",
 		}
-		errChan <- rpc.LaunchPublicServer(ctx, web3Server, rpcConfig, wsConfig)
+		errChan <- rpc.LaunchPublicServer(ctx, web3Server, nil, nil, rpcConfig, wsConfig)
 	}()
 	select {
 	case err := <-errChan:
@@ -384,7 +384,7 @@ func startup() error {
 			Port: "8548",
 			Path: "/",
 		}
-		err := rpc.LaunchPublicServer(ctx, web3Server, rpcConfig, wsConfig)
+		err := rpc.LaunchPublicServer(ctx, web3Server, nil, nil, rpcConfig, wsConfig)
 		if err != nil {
 			errChan <- err
 		}
@@ -0,0 +1,487 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// arb-load sends a configurable mix of transactions to a node at a target
+// rate, and reports the latency from sending each one to getting its receipt
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	golog "log"
+	"math/big"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/cmd/internal"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var logger = arblog.Logger.With().Str("component", "arb-load").Logger()
+
+const receiptPollInterval = 100 * time.Millisecond
+
+type loadConfig struct {
+	mix            txMix
+	tps            float64
+	duration       time.Duration
+	count          int
+	calldataSize   int
+	erc20          ethcommon.Address
+	receiptTimeout time.Duration
+	maxErrorRate   float64
+	jsonOutput     bool
+}
+
+type sender struct {
+	auth   *bind.TransactOpts
+	l1Auth *bind.TransactOpts
+	nonce  uint64
+}
+
+type loadTester struct {
+	config       loadConfig
+	client       *ethutils.RPCEthClient
+	chainId      *big.Int
+	l1Client     *ethutils.RPCEthClient
+	inbox        *ethbridgecontracts.Inbox
+	inboxAddress ethcommon.Address
+
+	// sink receives transfers and calldata
+	sink          ethcommon.Address
+	gasLimits     map[txKind]uint64
+	submissionFee *big.Int
+
+	gasPriceMutex sync.Mutex
+	gasPrice      *big.Int
+
+	stats    *stats
+	receipts sync.WaitGroup
+}
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error running load test")
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	url := fs.String("url", "http://localhost:8547", "url of the node's rpc")
+	mnemonic := fs.String(
+		"mnemonic",
+		"jar deny prosper gasp flush glass core corn alarm treat leg smart",
+		"mnemonic to derive the funded sender accounts from",
+	)
+	senderCount := fs.Int("senders", 10, "number of sender accounts")
+	accountOffset := fs.Int("account-offset", 0, "index of the first sender account")
+	mixStr := fs.String("mix", "transfer=1", "weights of transaction kinds: transfer, erc20, deploy, calldata and retryable")
+	tps := fs.Float64("tps", 10, "target transactions per second across all senders")
+	duration := fs.Duration("duration", time.Minute, "how long to send transactions for")
+	count := fs.Int("count", 0, "stop after sending this many transactions if nonzero")
+	calldataSize := fs.Int("calldata-size", 10000, "bytes of calldata in calldata transactions")
+	erc20 := fs.String("erc20", "", "token for erc20 transfers, which every sender must hold")
+	l1Url := fs.String("l1url", "", "url of the L1 rpc, required for retryables")
+	inbox := fs.String("inbox", "", "address of the L1 inbox, required for retryables")
+	receiptTimeout := fs.Duration("receipt-timeout", 5*time.Minute, "how long to wait for each receipt")
+	maxErrorRate := fs.Float64("max-error-rate", 0.01, "fail if a larger fraction of transactions fail")
+	jsonOutput := fs.Bool("json", false, "print the report as json")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel, gethlog.StreamHandler(os.Stderr, gethlog.TerminalFormat(true))); err != nil {
+		return err
+	}
+
+	mix, err := parseMix(*mixStr)
+	if err != nil {
+		return err
+	}
+	if *tps <= 0 {
+		return errors.New("--tps must be positive")
+	}
+	config := loadConfig{
+		mix:            mix,
+		tps:            *tps,
+		duration:       *duration,
+		count:          *count,
+		calldataSize:   *calldataSize,
+		receiptTimeout: *receiptTimeout,
+		maxErrorRate:   *maxErrorRate,
+		jsonOutput:     *jsonOutput,
+	}
+	if mix.includes(kindERC20) {
+		if !ethcommon.IsHexAddress(*erc20) {
+			return errors.New("erc20 transactions require --erc20")
+		}
+		config.erc20 = ethcommon.HexToAddress(*erc20)
+	}
+
+	lt, err := newLoadTester(ctx, config, *url)
+	if err != nil {
+		return err
+	}
+	if mix.includes(kindRetryable) {
+		if *l1Url == "" || !ethcommon.IsHexAddress(*inbox) {
+			return errors.New("retryable transactions require --l1url and --inbox")
+		}
+		if err := lt.connectL1(*l1Url, ethcommon.HexToAddress(*inbox)); err != nil {
+			return err
+		}
+	}
+
+	senders, err := lt.loadSenders(ctx, *mnemonic, *accountOffset, *senderCount)
+	if err != nil {
+		return err
+	}
+	if err := lt.prepare(ctx, senders[0]); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	lt.run(ctx, senders)
+	sendDuration := time.Since(start)
+	logger.Info().Msg("Waiting for outstanding receipts")
+	lt.receipts.Wait()
+
+	r := lt.stats.report(sendDuration, config.tps)
+	if err := r.print(config.jsonOutput); err != nil {
+		return err
+	}
+	if rate := r.errorRate(); rate > config.maxErrorRate {
+		return errors.Errorf("error rate %.4f is above the maximum of %v", rate, config.maxErrorRate)
+	}
+	return nil
+}
+
+func newLoadTester(ctx context.Context, config loadConfig, url string) (*loadTester, error) {
+	client, err := ethutils.NewRPCEthClient(url)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loadTester{
+		config:    config,
+		client:    client,
+		chainId:   chainId,
+		sink:      common.RandAddress().ToEthAddress(),
+		gasLimits: make(map[txKind]uint64),
+		stats:     newStats(),
+	}, nil
+}
+
+func (lt *loadTester) connectL1(url string, inbox ethcommon.Address) error {
+	l1Client, err := ethutils.NewRPCEthClient(url)
+	if err != nil {
+		return err
+	}
+	lt.inbox, err = ethbridgecontracts.NewInbox(inbox, l1Client)
+	if err != nil {
+		return err
+	}
+	lt.l1Client = l1Client
+	lt.inboxAddress = inbox
+	return nil
+}
+
+func (lt *loadTester) loadSenders(ctx context.Context, mnemonic string, offset int, count int) ([]*sender, error) {
+	if count <= 0 {
+		return nil, errors.New("must have at least one sender")
+	}
+	wallet, accounts, err := internal.InitializeWallet(mnemonic, offset+count)
+	if err != nil {
+		return nil, err
+	}
+	var l1ChainId *big.Int
+	if lt.l1Client != nil {
+		l1ChainId, err = lt.l1Client.ChainID(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	senders := make([]*sender, 0, count)
+	for _, account := range accounts[offset:] {
+		privKey, err := wallet.PrivateKey(account)
+		if err != nil {
+			return nil, err
+		}
+		s := &sender{}
+		s.auth, err = bind.NewKeyedTransactorWithChainID(privKey, lt.chainId)
+		if err != nil {
+			return nil, err
+		}
+		if l1ChainId != nil {
+			s.l1Auth, err = bind.NewKeyedTransactorWithChainID(privKey, l1ChainId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.nonce, err = lt.client.PendingNonceAt(ctx, s.auth.From)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, s)
+	}
+	return senders, nil
+}
+
+// prepare estimates the gas of each kind of L2 transaction, and the fee for
+// retryables
+func (lt *loadTester) prepare(ctx context.Context, s *sender) error {
+	if err := lt.updateGasPrice(ctx); err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(0))
+	for _, k := range lt.config.mix.kinds {
+		if k.kind == kindRetryable {
+			continue
+		}
+		to, value, data := lt.l2Payload(k.kind, rng)
+		gas, err := lt.client.EstimateGas(ctx, ethereum.CallMsg{From: s.auth.From, To: to, Value: value, Data: data})
+		if err != nil {
+			return errors.Wrapf(err, "error estimating gas for %v transactions", k.kind)
+		}
+		// Leave room for the L1 gas price to rise during the run
+		lt.gasLimits[k.kind] = gas * 2
+	}
+	if lt.config.mix.includes(kindRetryable) {
+		arbRetryable, err := arboscontracts.NewArbRetryableTx(arbos.ARB_RETRYABLE_ADDRESS, lt.client)
+		if err != nil {
+			return err
+		}
+		fee, _, err := arbRetryable.GetSubmissionPrice(&bind.CallOpts{Context: ctx}, big.NewInt(0))
+		if err != nil {
+			return err
+		}
+		lt.submissionFee = new(big.Int).Mul(fee, big.NewInt(2))
+	}
+	return nil
+}
+
+func (lt *loadTester) updateGasPrice(ctx context.Context) error {
+	gasPrice, err := lt.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	lt.gasPriceMutex.Lock()
+	defer lt.gasPriceMutex.Unlock()
+	lt.gasPrice = gasPrice
+	return nil
+}
+
+func (lt *loadTester) currentGasPrice() *big.Int {
+	lt.gasPriceMutex.Lock()
+	defer lt.gasPriceMutex.Unlock()
+	return lt.gasPrice
+}
+
+// run sends transactions until the duration or count is reached. Ticks of
+// the target rate are shared by the senders, and are skipped if every sender
+// is still busy with the previous one.
+func (lt *loadTester) run(ctx context.Context, senders []*sender) {
+	runCtx, cancel := context.WithTimeout(ctx, lt.config.duration)
+	defer cancel()
+
+	ticks := make(chan struct{}, len(senders))
+	var wg sync.WaitGroup
+	for i, s := range senders {
+		wg.Add(1)
+		go func(s *sender, seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for range ticks {
+				lt.send(ctx, s, lt.config.mix.pick(rng), rng)
+			}
+		}(s, time.Now().UnixNano()+int64(i))
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / lt.config.tps))
+	defer ticker.Stop()
+	priceTicker := time.NewTicker(10 * time.Second)
+	defer priceTicker.Stop()
+	issued := 0
+	for lt.config.count == 0 || issued < lt.config.count {
+		select {
+		case <-runCtx.Done():
+			close(ticks)
+			wg.Wait()
+			return
+		case <-priceTicker.C:
+			if err := lt.updateGasPrice(runCtx); err != nil {
+				logger.Warn().Err(err).Msg("error updating gas price")
+			}
+		case <-ticker.C:
+			select {
+			case ticks <- struct{}{}:
+				issued++
+			default:
+				lt.stats.recordSkippedTick()
+			}
+		}
+	}
+	close(ticks)
+	wg.Wait()
+}
+
+func (lt *loadTester) send(ctx context.Context, s *sender, kind txKind, rng *rand.Rand) {
+	if kind == kindRetryable {
+		lt.sendRetryable(ctx, s)
+		return
+	}
+	to, value, data := lt.l2Payload(kind, rng)
+	tx, err := s.auth.Signer(s.auth.From, types.NewTx(&types.LegacyTx{
+		Nonce:    s.nonce,
+		GasPrice: lt.currentGasPrice(),
+		Gas:      lt.gasLimits[kind],
+		To:       to,
+		Value:    value,
+		Data:     data,
+	}))
+	if err != nil {
+		lt.stats.recordError(kind, "sign", err)
+		return
+	}
+	start := time.Now()
+	if err := lt.client.SendTransaction(ctx, tx); err != nil {
+		lt.stats.recordError(kind, "send", err)
+		// The node may or may not have accepted the transaction, so resync
+		// the nonce
+		if nonce, err := lt.client.PendingNonceAt(ctx, s.auth.From); err == nil {
+			s.nonce = nonce
+		}
+		return
+	}
+	s.nonce++
+	lt.stats.recordSent(kind)
+	lt.receipts.Add(1)
+	go func() {
+		defer lt.receipts.Done()
+		receipt, err := lt.waitForReceipt(ctx, tx.Hash())
+		if err != nil {
+			lt.stats.recordError(kind, "receipt", err)
+			return
+		}
+		lt.stats.recordReceipt(kind, time.Since(start), receipt.Status == types.ReceiptStatusSuccessful)
+	}()
+}
+
+// sendRetryable creates a retryable ticket with no gas for auto-redemption,
+// and waits for the ticket's creation on L2
+func (lt *loadTester) sendRetryable(ctx context.Context, s *sender) {
+	opts := *s.l1Auth
+	opts.Context = ctx
+	opts.Value = lt.submissionFee
+	dest := common.RandAddress().ToEthAddress()
+	start := time.Now()
+	tx, err := lt.inbox.CreateRetryableTicket(&opts, dest, big.NewInt(0), lt.submissionFee, s.auth.From, s.auth.From, big.NewInt(0), big.NewInt(0), nil)
+	if err != nil {
+		lt.stats.recordError(kindRetryable, "send", err)
+		return
+	}
+	lt.stats.recordSent(kindRetryable)
+	lt.receipts.Add(1)
+	go func() {
+		defer lt.receipts.Done()
+		receiptCtx, cancel := context.WithTimeout(ctx, lt.config.receiptTimeout)
+		defer cancel()
+		l1Receipt, err := bind.WaitMined(receiptCtx, lt.l1Client, tx)
+		if err != nil {
+			lt.stats.recordError(kindRetryable, "l1 receipt", err)
+			return
+		}
+		if l1Receipt.Status != types.ReceiptStatusSuccessful {
+			lt.stats.recordError(kindRetryable, "l1 receipt", errors.New("inbox transaction reverted"))
+			return
+		}
+		var messageNum *big.Int
+		for _, ethLog := range l1Receipt.Logs {
+			if ethLog.Address != lt.inboxAddress {
+				continue
+			}
+			ev, err := lt.inbox.ParseInboxMessageDelivered(*ethLog)
+			if err == nil {
+				messageNum = ev.MessageNum
+				break
+			}
+		}
+		if messageNum == nil {
+			lt.stats.recordError(kindRetryable, "l1 receipt", errors.New("no inbox message delivered"))
+			return
+		}
+		requestId := message.CalculateRequestId(lt.chainId, messageNum)
+		receipt, err := lt.waitForReceipt(ctx, requestId.ToEthHash())
+		if err != nil {
+			lt.stats.recordError(kindRetryable, "receipt", err)
+			return
+		}
+		lt.stats.recordReceipt(kindRetryable, time.Since(start), receipt.Status == types.ReceiptStatusSuccessful)
+	}()
+}
+
+func (lt *loadTester) waitForReceipt(ctx context.Context, txHash ethcommon.Hash) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, lt.config.receiptTimeout)
+	defer cancel()
+	for {
+		receipt, err := lt.client.TransactionReceipt(ctx, txHash)
+		if err == nil && receipt != nil {
+			return receipt, nil
+		}
+		if err != nil && err != ethereum.NotFound {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for receipt: %w", ctx.Err())
+		case <-time.After(receiptPollInterval):
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type kindStats struct {
+	sent      int
+	confirmed int
+	reverted  int
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+// stats collects the results of a run from every sender
+type stats struct {
+	sync.Mutex
+	kinds        map[txKind]*kindStats
+	skippedTicks int
+}
+
+func newStats() *stats {
+	return &stats{kinds: make(map[txKind]*kindStats)}
+}
+
+func (s *stats) kind(kind txKind) *kindStats {
+	k, ok := s.kinds[kind]
+	if !ok {
+		k = &kindStats{errors: make(map[string]int)}
+		s.kinds[kind] = k
+	}
+	return k
+}
+
+// errorKey groups errors which only differ in their details, such as the
+// nonces in "nonce too low: address ..., tx: 5 state: 7"
+func errorKey(stage string, err error) string {
+	msg := err.Error()
+	if i := strings.Index(msg, ":"); i >= 0 {
+		msg = msg[:i]
+	}
+	return stage + ": " + msg
+}
+
+func (s *stats) recordSent(kind txKind) {
+	s.Lock()
+	defer s.Unlock()
+	s.kind(kind).sent++
+}
+
+func (s *stats) recordError(kind txKind, stage string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.kind(kind).errors[errorKey(stage, err)]++
+}
+
+func (s *stats) recordReceipt(kind txKind, latency time.Duration, succeeded bool) {
+	s.Lock()
+	defer s.Unlock()
+	k := s.kind(kind)
+	k.confirmed++
+	if !succeeded {
+		k.reverted++
+	}
+	k.latencies = append(k.latencies, latency)
+}
+
+func (s *stats) recordSkippedTick() {
+	s.Lock()
+	defer s.Unlock()
+	s.skippedTicks++
+}
+
+type kindReport struct {
+	Kind      txKind         `json:"kind"`
+	Sent      int            `json:"sent"`
+	Confirmed int            `json:"confirmed"`
+	Reverted  int            `json:"reverted"`
+	Errors    map[string]int `json:"errors"`
+	// Latencies are from sending the transaction to getting its receipt
+	LatencyMeanMs float64 `json:"latencyMeanMs"`
+	LatencyP50Ms  float64 `json:"latencyP50Ms"`
+	LatencyP90Ms  float64 `json:"latencyP90Ms"`
+	LatencyP99Ms  float64 `json:"latencyP99Ms"`
+	LatencyMaxMs  float64 `json:"latencyMaxMs"`
+}
+
+type report struct {
+	DurationSeconds float64      `json:"durationSeconds"`
+	TargetTPS       float64      `json:"targetTps"`
+	SentTPS         float64      `json:"sentTps"`
+	ConfirmedTPS    float64      `json:"confirmedTps"`
+	Sent            int          `json:"sent"`
+	Errors          int          `json:"errors"`
+	SkippedTicks    int          `json:"skippedTicks"`
+	Kinds           []kindReport `json:"kinds"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+func (s *stats) report(duration time.Duration, targetTPS float64) report {
+	s.Lock()
+	defer s.Unlock()
+	r := report{
+		DurationSeconds: duration.Seconds(),
+		TargetTPS:       targetTPS,
+		SkippedTicks:    s.skippedTicks,
+	}
+	confirmed := 0
+	for kind, k := range s.kinds {
+		latencies := append([]time.Duration{}, k.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		kr := kindReport{
+			Kind:         kind,
+			Sent:         k.sent,
+			Confirmed:    k.confirmed,
+			Reverted:     k.reverted,
+			Errors:       k.errors,
+			LatencyP50Ms: millis(percentile(latencies, 0.5)),
+			LatencyP90Ms: millis(percentile(latencies, 0.9)),
+			LatencyP99Ms: millis(percentile(latencies, 0.99)),
+		}
+		if len(latencies) > 0 {
+			var total time.Duration
+			for _, l := range latencies {
+				total += l
+			}
+			kr.LatencyMeanMs = millis(total / time.Duration(len(latencies)))
+			kr.LatencyMaxMs = millis(latencies[len(latencies)-1])
+		}
+		for _, count := range k.errors {
+			r.Errors += count
+		}
+		r.Sent += k.sent
+		confirmed += k.confirmed
+		r.Kinds = append(r.Kinds, kr)
+	}
+	sort.Slice(r.Kinds, func(i, j int) bool { return r.Kinds[i].Kind < r.Kinds[j].Kind })
+	if duration > 0 {
+		r.SentTPS = float64(r.Sent) / duration.Seconds()
+		r.ConfirmedTPS = float64(confirmed) / duration.Seconds()
+	}
+	return r
+}
+
+// errorRate is the fraction of attempted transactions which failed to send or
+// to be confirmed, or which reverted
+func (r report) errorRate() float64 {
+	attempts := r.Errors
+	failures := r.Errors
+	for _, k := range r.Kinds {
+		attempts += k.Sent
+		failures += k.Reverted
+	}
+	if attempts == 0 {
+		return 0
+	}
+	return float64(failures) / float64(attempts)
+}
+
+func (r report) print(jsonOutput bool) error {
+	if jsonOutput {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("Ran for %.1fs targeting %v tps\n", r.DurationSeconds, r.TargetTPS)
+	fmt.Printf("Sent %v transactions (%.2f tps), confirmed %.2f tps, %v errors, %v skipped ticks\n", r.Sent, r.SentTPS, r.ConfirmedTPS, r.Errors, r.SkippedTicks)
+	for _, k := range r.Kinds {
+		fmt.Printf("\n%v: sent %v, confirmed %v, reverted %v\n", k.Kind, k.Sent, k.Confirmed, k.Reverted)
+		fmt.Printf("  latency mean %.0fms p50 %.0fms p90 %.0fms p99 %.0fms max %.0fms\n", k.LatencyMeanMs, k.LatencyP50Ms, k.LatencyP90Ms, k.LatencyP99Ms, k.LatencyMaxMs)
+		errs := make([]string, 0, len(k.Errors))
+		for msg := range k.Errors {
+			errs = append(errs, msg)
+		}
+		sort.Strings(errs)
+		for _, msg := range errs {
+			fmt.Printf("  %v x %v\n", k.Errors[msg], msg)
+		}
+	}
+	return nil
+}
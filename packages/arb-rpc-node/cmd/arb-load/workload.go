@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbostestcontracts"
+)
+
+type txKind string
+
+const (
+	// kindTransfer sends 1 wei to a fixed address
+	kindTransfer txKind = "transfer"
+	// kindERC20 transfers one unit of the --erc20 token, which every sender
+	// must hold
+	kindERC20 txKind = "erc20"
+	// kindDeploy deploys a small test contract
+	kindDeploy txKind = "deploy"
+	// kindCalldata sends --calldata-size random bytes to a fixed address
+	kindCalldata txKind = "calldata"
+	// kindRetryable creates a retryable ticket through the L1 inbox
+	kindRetryable txKind = "retryable"
+)
+
+var allKinds = []txKind{kindTransfer, kindERC20, kindDeploy, kindCalldata, kindRetryable}
+
+var erc20TransferId = hexutil.MustDecode("0xa9059cbb")
+
+var deployData = hexutil.MustDecode(arbostestcontracts.SimpleMetaData.Bin)
+
+type weightedKind struct {
+	kind   txKind
+	weight int
+}
+
+// txMix picks the kind of each transaction in proportion to its weight
+type txMix struct {
+	kinds []weightedKind
+	total int
+}
+
+// parseMix parses a mix like "transfer=10,deploy=1"
+func parseMix(mixStr string) (txMix, error) {
+	weights := make(map[txKind]int)
+	for _, entry := range strings.Split(mixStr, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 {
+			return txMix{}, errors.Errorf("mix entry %v should be kind=weight", entry)
+		}
+		kind := txKind(parts[0])
+		known := false
+		for _, k := range allKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return txMix{}, errors.Errorf("unknown transaction kind %v", kind)
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return txMix{}, errors.Errorf("invalid weight %v for %v", parts[1], kind)
+		}
+		weights[kind] += weight
+	}
+	mix := txMix{}
+	for kind, weight := range weights {
+		if weight > 0 {
+			mix.kinds = append(mix.kinds, weightedKind{kind: kind, weight: weight})
+			mix.total += weight
+		}
+	}
+	if mix.total == 0 {
+		return txMix{}, errors.New("mix must have a positive weight")
+	}
+	sort.Slice(mix.kinds, func(i, j int) bool {
+		return mix.kinds[i].kind < mix.kinds[j].kind
+	})
+	return mix, nil
+}
+
+func (m txMix) includes(kind txKind) bool {
+	for _, k := range m.kinds {
+		if k.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (m txMix) pick(rng *rand.Rand) txKind {
+	n := rng.Intn(m.total)
+	for _, k := range m.kinds {
+		if n < k.weight {
+			return k.kind
+		}
+		n -= k.weight
+	}
+	return m.kinds[len(m.kinds)-1].kind
+}
+
+// l2Payload is the destination, value and data of an L2 transaction of the
+// given kind
+func (lt *loadTester) l2Payload(kind txKind, rng *rand.Rand) (*ethcommon.Address, *big.Int, []byte) {
+	switch kind {
+	case kindTransfer:
+		return &lt.sink, big.NewInt(1), nil
+	case kindERC20:
+		data := append([]byte{}, erc20TransferId...)
+		data = append(data, ethcommon.LeftPadBytes(lt.sink.Bytes(), 32)...)
+		data = append(data, ethcommon.LeftPadBytes([]byte{1}, 32)...)
+		return &lt.config.erc20, big.NewInt(0), data
+	case kindDeploy:
+		return nil, big.NewInt(0), deployData
+	case kindCalldata:
+		data := make([]byte, lt.config.calldataSize)
+		rng.Read(data)
+		return &lt.sink, big.NewInt(0), data
+	default:
+		panic("no L2 payload for " + string(kind))
+	}
+}
@@ -53,9 +53,12 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/callcache"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/graphql"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/nitroexport"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/rpc"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/utils"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcastclient"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcaster"
@@ -480,23 +483,61 @@ func startup() error {
 	}
 
 	srv := aggregator.NewServer(batch, l2ChainId, db)
+	callCache, err := callcache.New(config.Node.Cache.Call)
+	if err != nil {
+		return errors.Wrap(err, "error creating call cache")
+	}
 	serverConfig := web3.ServerConfig{
 		Mode:          rpcMode,
 		MaxCallAVMGas: config.Node.RPC.MaxCallGas * 100, // Multiply by 100 for arb gas to avm gas conversion
 		Tracing:       config.Node.RPC.Tracing,
 		DevopsStubs:   config.Node.RPC.EnableDevopsStubs,
+		CallCache:     callCache,
 	}
 	web3Server, err := web3.GenerateWeb3Server(srv, nil, serverConfig, mon.CoreConfig, plugins, web3InboxReaderRef)
 	if err != nil {
 		return err
 	}
+	access, err := utils.NewAccessControl(config.Node.RPC, func() (uint64, error) {
+		count, err := srv.GetBlockCount()
+		if err != nil || count == 0 {
+			return 0, err
+		}
+		return count - 1, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error setting up rpc access control")
+	}
+	rpcMetrics := utils.NewRPCMetrics(metricsConfig.Registry, config.Node.RPC.SlowRequestTime)
 	go func() {
-		err := rpc.LaunchPublicServer(ctx, web3Server, config.Node.RPC, config.Node.WS)
+		err := rpc.LaunchPublicServer(ctx, web3Server, access, rpcMetrics, config.Node.RPC, config.Node.WS)
 		if err != nil {
 			errChan <- err
 		}
 	}()
 
+	if callCache != nil && config.Node.Cache.Call.Port != "" {
+		go func() {
+			err := rpc.LaunchCallCacheServer(ctx, callCache, config.Node.Cache.Call)
+			if err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
+	if config.Node.GraphQL.Enable {
+		graphqlHandler, err := graphql.New(srv, serverConfig)
+		if err != nil {
+			return err
+		}
+		go func() {
+			err := rpc.LaunchGraphQLServer(ctx, graphqlHandler, access, config.Node.GraphQL)
+			if err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
 	if config.Node.Type() == configuration.ForwarderNodeType && config.Node.Forwarder.Target != "" {
 		go func() {
 			clnt, err := ethclient.DialContext(ctx, config.Node.Forwarder.Target)
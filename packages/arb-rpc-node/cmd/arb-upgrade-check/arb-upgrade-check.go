@@ -0,0 +1,517 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// arb-upgrade-check applies an ArbOS upgrade to a fork of a chain, so that it
+// can be verified before being sent to the real chain
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	golog "log"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/dev"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+var logger = arblog.Logger.With().Str("component", "arb-upgrade-check").Logger()
+
+// smokeSuite is run against the upgraded chain. Calls must succeed, and return
+// Result if it's given. Transfers are sent from the fork's chain owner.
+type smokeSuite struct {
+	Calls     []smokeCall     `json:"calls"`
+	Transfers []smokeTransfer `json:"transfers"`
+}
+
+type smokeCall struct {
+	Name   string            `json:"name"`
+	From   ethcommon.Address `json:"from"`
+	To     ethcommon.Address `json:"to"`
+	Data   hexutil.Bytes     `json:"data"`
+	Result *hexutil.Bytes    `json:"result"`
+}
+
+type smokeTransfer struct {
+	To    ethcommon.Address `json:"to"`
+	Value *big.Int          `json:"value"`
+}
+
+func defaultSmokeSuite() smokeSuite {
+	return smokeSuite{
+		Calls: []smokeCall{{
+			Name: "arbOSVersion",
+			To:   arbos.ARB_SYS_ADDRESS,
+			Data: arbos.ArbOSVersionData(),
+		}},
+		Transfers: []smokeTransfer{{
+			To:    common.RandAddress().ToEthAddress(),
+			Value: big.NewInt(1),
+		}},
+	}
+}
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Upgrade check failed")
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	dbDir := fs.String("dbdir", "", "node database to fork, which is copied rather than modified")
+	upgradeFile := fs.String("upgrade", "", "upgrade file to apply")
+	targetMexe := fs.String("target", "", "mexe that the upgrade produces")
+	startMexe := fs.String("start", "", "mexe that the upgrade must start from, if any")
+	smokeFile := fs.String("smoke", "", "smoke suite to run after the upgrade, which defaults to a version call and a transfer")
+	chainId64 := fs.Uint64("chainId", 42161, "chain id of chain")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if *dbDir == "" || *upgradeFile == "" || *targetMexe == "" {
+		return errors.New("--dbdir, --upgrade and --target are required")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel, gethlog.StreamHandler(os.Stderr, gethlog.TerminalFormat(true))); err != nil {
+		return err
+	}
+
+	chunks, err := arbos.LoadUpgradeChunks(*upgradeFile)
+	if err != nil {
+		return err
+	}
+	targetMach, err := cmachine.New(*targetMexe)
+	if err != nil {
+		return err
+	}
+	var startHash common.Hash
+	if *startMexe != "" {
+		startMach, err := cmachine.New(*startMexe)
+		if err != nil {
+			return err
+		}
+		startHash = startMach.CodePointHash()
+	}
+	suite := defaultSmokeSuite()
+	if *smokeFile != "" {
+		data, err := os.ReadFile(*smokeFile)
+		if err != nil {
+			return err
+		}
+		suite = smokeSuite{}
+		if err := json.Unmarshal(data, &suite); err != nil {
+			return errors.Wrap(err, "error parsing smoke suite")
+		}
+	}
+
+	forkDir, err := copyDB(*dbDir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(forkDir)
+
+	chainId := new(big.Int).SetUint64(*chainId64)
+	msgCount, err := dev.GetMessageCount(forkDir)
+	if err != nil {
+		return err
+	}
+	logger.Info().Uint64("message", msgCount).Msg("Forking chain")
+	backend, db, _, cancel, _, err := dev.NewForkNode(ctx, forkDir, chainId, common.RandAddress(), int64(msgCount), false)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	before, err := db.LatestSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	ownerKey, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(ownerKey, chainId)
+	if err != nil {
+		return err
+	}
+	if err := addForkOwner(ctx, backend, common.NewAddressFromEth(auth.From)); err != nil {
+		return err
+	}
+
+	srv := aggregator.NewServer(backend, chainId, db)
+	client := web3.NewEthClient(srv, true)
+	if err := applyUpgrade(db, client, auth, chunks, targetMach.CodePointHash(), startHash); err != nil {
+		return err
+	}
+
+	after, err := db.LatestSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := reportParams(before, after); err != nil {
+		return err
+	}
+	if err := runSmokeSuite(ctx, suite, after, auth); err != nil {
+		return err
+	}
+	fmt.Println("Upgrade check passed")
+	return nil
+}
+
+// copyDB copies the node database at src into a new temporary directory,
+// leaving src untouched by the fork
+func copyDB(src string) (string, error) {
+	dst, err := os.MkdirTemp("", "arb-upgrade-check")
+	if err != nil {
+		return "", err
+	}
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return errors.Errorf("unexpected file %v in database", path)
+		}
+		return copyFile(path, target)
+	})
+	if err != nil {
+		_ = os.RemoveAll(dst)
+		return "", errors.Wrap(err, "error copying database")
+	}
+	return dst, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// addForkOwner makes owner a chain owner on the fork and funds it, so that it
+// can send the upgrade and the smoke suite's transfers
+func addForkOwner(ctx context.Context, backend *dev.Backend, owner common.Address) error {
+	ownerAdd := message.EthDepositTx{
+		L2Message: message.NewSafeL2Message(message.ContractTransaction{
+			BasicTx: message.BasicTx{
+				MaxGas:      big.NewInt(1000000),
+				GasPriceBid: big.NewInt(2000000000),
+				DestAddress: common.NewAddressFromEth(arbos.ARB_OWNER_ADDRESS),
+				Payment:     big.NewInt(0),
+				Data:        arbos.AddChainOwnerData(owner),
+			},
+		}),
+	}
+	if _, err := backend.AddInboxMessage(ctx, ownerAdd, common.Address{}); err != nil {
+		return err
+	}
+
+	depositSize := new(big.Int).Exp(big.NewInt(10), big.NewInt(22), nil)
+	deposit := message.RetryableTx{
+		Destination:       owner,
+		Value:             big.NewInt(0),
+		Deposit:           depositSize,
+		MaxSubmissionCost: depositSize,
+		CreditBack:        owner,
+		Beneficiary:       owner,
+		MaxGas:            big.NewInt(0),
+		GasPriceBid:       big.NewInt(0),
+	}
+	_, err := backend.AddInboxMessage(ctx, deposit, message.L2RemapAccount(owner))
+	return err
+}
+
+// checkTx returns an error if tx failed. The fork node accepts failed
+// transactions, so their results must be checked.
+func checkTx(db *txdb.TxDB, tx *types.Transaction, method string) error {
+	res, _, _, err := db.GetRequest(common.NewHashFromEth(tx.Hash()))
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return errors.Errorf("%v result not found", method)
+	}
+	if res.ResultCode != evm.ReturnCode {
+		return errors.Wrapf(evm.HandleCallError(res, false), "%v failed", method)
+	}
+	return nil
+}
+
+func applyUpgrade(
+	db *txdb.TxDB,
+	client *web3.EthClient,
+	auth *bind.TransactOpts,
+	chunks []string,
+	targetHash common.Hash,
+	startHash common.Hash,
+) error {
+	arbOwner, err := arboscontracts.NewArbOwner(arbos.ARB_OWNER_ADDRESS, client)
+	if err != nil {
+		return err
+	}
+	auth.GasLimit = 10000000000
+	defer func() { auth.GasLimit = 0 }()
+
+	tx, err := arbOwner.StartCodeUpload(auth)
+	if err != nil {
+		return err
+	}
+	if err := checkTx(db, tx, "StartCodeUpload"); err != nil {
+		return err
+	}
+
+	logger.Info().Int("chunks", len(chunks)).Msg("Submitting upgrade")
+	for _, upgradeChunk := range chunks {
+		tx, err = arbOwner.ContinueCodeUpload(auth, hexutil.MustDecode(upgradeChunk))
+		if err != nil {
+			return err
+		}
+		if err := checkTx(db, tx, "ContinueCodeUpload"); err != nil {
+			return err
+		}
+	}
+
+	codeHash, err := arbOwner.GetUploadedCodeHash(&bind.CallOpts{})
+	if err != nil {
+		return err
+	}
+	if codeHash != targetHash {
+		return errors.Errorf("uploaded code hash %v doesn't match target %v", common.Hash(codeHash), targetHash)
+	}
+	fmt.Println("Uploaded code hash matches target", targetHash)
+
+	tx, err = arbOwner.FinishCodeUploadAsArbosUpgrade(auth, targetHash, startHash)
+	if err != nil {
+		return err
+	}
+	return checkTx(db, tx, "FinishCodeUploadAsArbosUpgrade")
+}
+
+type param struct {
+	name  string
+	value *big.Int
+}
+
+func readParams(snap *snapshot.Snapshot) ([]param, error) {
+	precompiles, err := arbos.NewPrecompiles(snapshot.NewContractBackend(snap))
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{}
+	version, err := precompiles.ArbSys.ArbOSVersion(opts)
+	if err != nil {
+		return nil, err
+	}
+	perL2TxWei,
+		perL1CalldataByteWei,
+		perStorageWei,
+		perArgGasBaseWei,
+		perArbGasCongestionWei,
+		perArbGasTotalWei,
+		err := precompiles.ArbGasInfo.GetPricesInWei(opts)
+	if err != nil {
+		return nil, err
+	}
+	perL2Tx, perL1CalldataByte, perStorage, err := precompiles.ArbGasInfo.GetPricesInArbGas(opts)
+	if err != nil {
+		return nil, err
+	}
+	speedLimitPerSecond, gasPoolMax, maxTxGasLimit, err := precompiles.ArbGasInfo.GetGasAccountingParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	l1GasPriceEstimate, err := precompiles.ArbGasInfo.GetL1GasPriceEstimate(opts)
+	if err != nil {
+		return nil, err
+	}
+	return []param{
+		{"ArbOS version", version},
+		{"perL2TxWei", perL2TxWei},
+		{"perL1CalldataByteWei", perL1CalldataByteWei},
+		{"perStorageWei", perStorageWei},
+		{"perArgGasBaseWei", perArgGasBaseWei},
+		{"perArbGasCongestionWei", perArbGasCongestionWei},
+		{"perArbGasTotalWei", perArbGasTotalWei},
+		{"perL2Tx", perL2Tx},
+		{"perL1CalldataByte", perL1CalldataByte},
+		{"perStorage", perStorage},
+		{"speedLimitPerSecond", speedLimitPerSecond},
+		{"gasPoolMax", gasPoolMax},
+		{"maxTxGasLimit", maxTxGasLimit},
+		{"l1GasPriceEstimate", l1GasPriceEstimate},
+	}, nil
+}
+
+func reportParams(before, after *snapshot.Snapshot) error {
+	oldParams, err := readParams(before)
+	if err != nil {
+		return errors.Wrap(err, "error reading parameters before upgrade")
+	}
+	newParams, err := readParams(after)
+	if err != nil {
+		return errors.Wrap(err, "error reading parameters after upgrade")
+	}
+	changed := 0
+	for i, oldParam := range oldParams {
+		newParam := newParams[i]
+		if oldParam.value.Cmp(newParam.value) != 0 {
+			fmt.Printf("%v: %v -> %v\n", oldParam.name, oldParam.value, newParam.value)
+			changed++
+		}
+	}
+	fmt.Println(changed, "parameters changed")
+	if oldParams[0].value.Cmp(newParams[0].value) >= 0 {
+		return errors.New("ArbOS version didn't increase")
+	}
+	return nil
+}
+
+func runSmokeSuite(ctx context.Context, suite smokeSuite, snap *snapshot.Snapshot, auth *bind.TransactOpts) error {
+	// Transfers are added to the snapshot, which is discarded afterwards
+	backend := snapshot.NewContractBackend(snap.Clone())
+	failures := 0
+	for i, call := range suite.Calls {
+		name := call.Name
+		if name == "" {
+			name = fmt.Sprintf("call %v", i)
+		}
+		to := call.To
+		ret, err := backend.CallContract(ctx, ethereum.CallMsg{From: call.From, To: &to, Data: call.Data}, nil)
+		switch {
+		case err != nil:
+			fmt.Printf("%v failed: %v\n", name, err)
+			failures++
+		case call.Result != nil && !bytes.Equal(ret, *call.Result):
+			fmt.Printf("%v returned %v but expected %v\n", name, hexutil.Bytes(ret), *call.Result)
+			failures++
+		default:
+			fmt.Printf("%v succeeded\n", name)
+		}
+	}
+	for _, transfer := range suite.Transfers {
+		if err := smokeTransferTo(ctx, backend, auth, transfer); err != nil {
+			fmt.Printf("transfer of %v to %v failed: %v\n", transfer.Value, transfer.To.Hex(), err)
+			failures++
+		} else {
+			fmt.Printf("transfer of %v to %v succeeded\n", transfer.Value, transfer.To.Hex())
+		}
+	}
+	if failures > 0 {
+		return errors.Errorf("%v smoke tests failed", failures)
+	}
+	return nil
+}
+
+func smokeTransferTo(ctx context.Context, backend *snapshot.ContractBackend, auth *bind.TransactOpts, transfer smokeTransfer) error {
+	value := transfer.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	nonce, err := backend.PendingNonceAt(ctx, auth.From)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	gas, err := backend.EstimateGas(ctx, ethereum.CallMsg{From: auth.From, To: &transfer.To, Value: value, GasPrice: gasPrice})
+	if err != nil {
+		return err
+	}
+	tx, err := auth.Signer(auth.From, types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		To:       &transfer.To,
+		Value:    value,
+	}))
+	if err != nil {
+		return err
+	}
+	if err := backend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	receipt, err := backend.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return errors.New("transaction reverted")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbostestcontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestGetBlockReceipts(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	senderKey := test.MustGenerateKey(t)
+
+	backend, _, srv, cancelDevNode := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer cancelDevNode()
+
+	senderAuth, err := bind.NewKeyedTransactorWithChainID(senderKey, backend.chainID)
+	test.FailIfError(t, err)
+
+	ethServer := web3.NewServer(srv, web3.DefaultConfig, nil)
+	client := web3.NewEthClient(srv, true)
+
+	_, tx, _, err := arbostestcontracts.DeploySimple(senderAuth, client)
+	test.FailIfError(t, err)
+
+	receipt, err := ethServer.GetTransactionReceipt(ctx, tx.Hash().Bytes(), nil)
+	test.FailIfError(t, err)
+	if receipt == nil {
+		t.Fatal("expected receipt for deployment")
+	}
+
+	blockHash := receipt.BlockHash
+	receipts, err := ethServer.GetBlockReceipts(rpc.BlockNumberOrHash{BlockHash: &blockHash})
+	test.FailIfError(t, err)
+	if len(receipts) == 0 || int(receipt.TransactionIndex) >= len(receipts) {
+		t.Fatal("expected block receipts to include deployment")
+	}
+
+	expected, err := json.Marshal(receipt)
+	test.FailIfError(t, err)
+	actual, err := json.Marshal(receipts[receipt.TransactionIndex])
+	test.FailIfError(t, err)
+	if string(expected) != string(actual) {
+		t.Errorf("block receipt doesn't match transaction receipt\nexpected %s\ngot %s", expected, actual)
+	}
+
+	blockNum := rpc.BlockNumber(receipt.BlockNumber.ToInt().Int64())
+	byNumber, err := ethServer.GetBlockReceipts(rpc.BlockNumberOrHash{BlockNumber: &blockNum})
+	test.FailIfError(t, err)
+	if len(byNumber) != len(receipts) {
+		t.Error("expected same receipts when looking up by number")
+	}
+}
@@ -8,8 +8,11 @@ require (
 	github.com/ethereum/go-ethereum v1.10.18
 	github.com/ethersphere/bee v1.6.1
 	github.com/go-redis/redis/v8 v8.11.4
+	github.com/golang-jwt/jwt/v4 v4.3.0
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.4.2
+	github.com/graph-gophers/graphql-go v1.3.0
 	github.com/hashicorp/golang-lru v0.5.5-0.20210104140557-80c98217689d
 	github.com/miguelmota/go-ethereum-hdwallet v0.1.1
 	github.com/offchainlabs/arbitrum/packages/arb-avm-cpp v0.8.0
@@ -18,6 +21,7 @@ require (
 	github.com/offchainlabs/arbitrum/packages/arb-util v0.8.0
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.26.1
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
 )
 
 replace github.com/offchainlabs/arbitrum/packages/arb-util => ../arb-util
@@ -0,0 +1,901 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graphql provides a GraphQL interface to the node's chain data,
+// following the EIP-1767 schema served by geth
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// Maximum number of blocks that can be returned by one blocks query
+const maxBlocksRange = 1000
+
+// Long is a 64 bit unsigned integer
+type Long int64
+
+// ImplementsGraphQLType returns true if Long implements the provided GraphQL type
+func (b Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data
+func (b *Long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		if strings.HasPrefix(input, "0x") {
+			value, err := hexutil.DecodeUint64(input)
+			*b = Long(value)
+			return err
+		}
+		value, err := strconv.ParseInt(input, 10, 64)
+		*b = Long(value)
+		return err
+	case int32:
+		*b = Long(input)
+	case int64:
+		*b = Long(input)
+	case float64:
+		*b = Long(input)
+	default:
+		return errors.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+type BlockNumberArgs struct {
+	Block *Long
+}
+
+// numberOr returns the block given in the arguments if there is one,
+// otherwise the given default
+func (a BlockNumberArgs) numberOr(current rpc.BlockNumberOrHash) rpc.BlockNumberOrHash {
+	if a.Block != nil {
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(*a.Block))
+	}
+	return current
+}
+
+func blockNumberOrHash(height uint64) rpc.BlockNumberOrHash {
+	return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(height))
+}
+
+// Resolver is the root resolver for queries and mutations
+type Resolver struct {
+	srv       *aggregator.Server
+	eth       *web3.Server
+	forwarder *web3.ForwarderServer
+}
+
+func (r *Resolver) blockByNumber(height uint64) (*Block, error) {
+	info, err := r.srv.BlockInfoByNumber(height)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	return &Block{r: r, info: info}, nil
+}
+
+func (r *Resolver) blockByHash(hash common.Hash) (*Block, error) {
+	info, err := r.srv.BlockInfoByHash(arbcommon.NewHashFromEth(hash))
+	if err != nil || info == nil {
+		return nil, err
+	}
+	return &Block{r: r, info: info}, nil
+}
+
+func (r *Resolver) transaction(hash common.Hash) (*Transaction, error) {
+	res, _, _, err := r.srv.GetRequestResult(arbcommon.NewHashFromEth(hash))
+	if err != nil || res == nil {
+		return nil, err
+	}
+	tx, err := evm.GetTransaction(res)
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{r: r, tx: tx}, nil
+}
+
+func (r *Resolver) runFilter(ctx context.Context, filter *filters.Filter) ([]*Log, error) {
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(logs))
+	for _, l := range logs {
+		ret = append(ret, &Log{r: r, log: l})
+	}
+	return ret, nil
+}
+
+func (r *Resolver) call(ctx context.Context, data CallData, blockNum rpc.BlockNumberOrHash) (*CallResult, error) {
+	args := data.toCallTxArgs()
+	if args.To != nil && *args.To == arbos.ARB_NODE_INTERFACE_ADDRESS {
+		// NodeInterface calls are handled by the node rather than executed
+		ret, err := r.eth.Call(ctx, args, blockNum, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &CallResult{data: ret, status: 1}, nil
+	}
+	res, err := r.eth.ExecuteCall(ctx, args, blockNum, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := Long(0)
+	if res.ResultCode == evm.ReturnCode {
+		status = 1
+	}
+	return &CallResult{
+		data:    res.ReturnData,
+		gasUsed: Long(res.GasUsed.Uint64()),
+		status:  status,
+	}, nil
+}
+
+func (r *Resolver) estimateGas(ctx context.Context, data CallData, blockNum rpc.BlockNumberOrHash) (Long, error) {
+	gas, err := r.eth.EstimateGas(ctx, data.toCallTxArgs(), &blockNum)
+	return Long(gas), err
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *common.Hash
+}) (*Block, error) {
+	if args.Number != nil && args.Hash != nil {
+		return nil, errors.New("only one of number or hash may be specified")
+	}
+	if args.Hash != nil {
+		return r.blockByHash(*args.Hash)
+	}
+	blockNum := rpc.LatestBlockNumber
+	if args.Number != nil {
+		blockNum = rpc.BlockNumber(*args.Number)
+	}
+	height, err := r.srv.BlockNum(&blockNum)
+	if err != nil {
+		return nil, err
+	}
+	return r.blockByNumber(height)
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From *Long
+	To   *Long
+}) ([]*Block, error) {
+	var from uint64
+	if args.From != nil {
+		from = uint64(*args.From)
+	}
+	toNum := rpc.LatestBlockNumber
+	if args.To != nil {
+		toNum = rpc.BlockNumber(*args.To)
+	}
+	to, err := r.srv.BlockNum(&toNum)
+	if err != nil {
+		return nil, err
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if to-from >= maxBlocksRange {
+		return nil, errors.Errorf("too many blocks requested, maximum is %v", maxBlocksRange)
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for height := from; height <= to; height++ {
+		block, err := r.blockByNumber(height)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		ret = append(ret, block)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Pending(ctx context.Context) *Pending {
+	return &Pending{r: r}
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	return r.transaction(args.Hash)
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	begin := rpc.LatestBlockNumber.Int64()
+	if args.Filter.FromBlock != nil {
+		begin = int64(*args.Filter.FromBlock)
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if args.Filter.ToBlock != nil {
+		end = int64(*args.Filter.ToBlock)
+	}
+	var addresses []common.Address
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	var topics [][]common.Hash
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	return r.runFilter(ctx, filters.NewRangeFilter(r.srv, begin, end, addresses, topics))
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	price, err := r.eth.GasPrice(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return *price, nil
+}
+
+func (r *Resolver) MaxPriorityFeePerGas(ctx context.Context) hexutil.Big {
+	return *r.eth.MaxPriorityFeePerGas()
+}
+
+func (r *Resolver) ChainID(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*r.srv.ChainId())
+}
+
+func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
+	hash, err := r.forwarder.SendRawTransaction(ctx, args.Data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// Account represents an account at a particular block
+type Account struct {
+	r        *Resolver
+	address  common.Address
+	blockNum rpc.BlockNumberOrHash
+}
+
+func (a *Account) Address(ctx context.Context) common.Address {
+	return a.address
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	balance, err := a.r.eth.GetBalance(ctx, &a.address, a.blockNum)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return *balance, nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (Long, error) {
+	count, err := a.r.forwarder.GetTransactionCount(ctx, &a.address, a.blockNum)
+	return Long(count), err
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	return a.r.eth.GetCode(ctx, &a.address, a.blockNum)
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	val, err := a.r.eth.GetStorageAt(ctx, &a.address, args.Slot.Hex(), a.blockNum)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(val), nil
+}
+
+// Log represents an individual log message. If tx is nil, the transaction
+// which emitted it is looked up when requested.
+type Log struct {
+	r   *Resolver
+	tx  *Transaction
+	log *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) (*Transaction, error) {
+	if l.tx != nil {
+		return l.tx, nil
+	}
+	tx, err := l.r.transaction(l.log.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, errors.New("transaction not found")
+	}
+	return tx, nil
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		r:        l.r,
+		address:  l.log.Address,
+		blockNum: args.numberOr(blockNumberOrHash(l.log.BlockNumber)),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return l.log.Data
+}
+
+// AccessTuple represents EIP-2930
+type AccessTuple struct {
+	address     common.Address
+	storageKeys []common.Hash
+}
+
+func (at *AccessTuple) Address(ctx context.Context) common.Address {
+	return at.address
+}
+
+func (at *AccessTuple) StorageKeys(ctx context.Context) []common.Hash {
+	return at.storageKeys
+}
+
+// FeeSet is one component of a transaction's fee stats
+type FeeSet struct {
+	set *evm.FeeSet
+}
+
+func (f *FeeSet) L1Transaction(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*f.set.L1Transaction)
+}
+
+func (f *FeeSet) L1Calldata(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*f.set.L1Calldata)
+}
+
+func (f *FeeSet) L2Storage(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*f.set.L2Storage)
+}
+
+func (f *FeeSet) L2Computation(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*f.set.L2Computation)
+}
+
+// FeeStats describes the fees charged to a transaction
+type FeeStats struct {
+	stats *evm.FeeStats
+}
+
+func (f *FeeStats) Prices(ctx context.Context) *FeeSet {
+	return &FeeSet{set: f.stats.Price}
+}
+
+func (f *FeeStats) UnitsUsed(ctx context.Context) *FeeSet {
+	return &FeeSet{set: f.stats.UnitsUsed}
+}
+
+func (f *FeeStats) Paid(ctx context.Context) *FeeSet {
+	return &FeeSet{set: f.stats.Paid}
+}
+
+// Transaction represents a transaction which has been executed. The block
+// is loaded when first needed if the transaction wasn't found through it.
+type Transaction struct {
+	r  *Resolver
+	tx *evm.ProcessedTx
+
+	mu    sync.Mutex
+	block *Block
+}
+
+func (t *Transaction) getBlock() (*Block, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.block == nil {
+		block, err := t.r.blockByNumber(t.tx.Result.IncomingRequest.L2BlockNumber.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		t.block = block
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) receipt() (*types.Receipt, error) {
+	block, err := t.getBlock()
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("transaction block not found")
+	}
+	return t.tx.Result.ToEthReceipt(arbcommon.NewHashFromEth(block.info.Header.Hash())), nil
+}
+
+func (t *Transaction) blockNum() rpc.BlockNumberOrHash {
+	return blockNumberOrHash(t.tx.Result.IncomingRequest.L2BlockNumber.Uint64())
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.tx.Result.IncomingRequest.MessageID.ToEthHash()
+}
+
+func (t *Transaction) InputData(ctx context.Context) hexutil.Bytes {
+	return t.tx.Tx.Data()
+}
+
+func (t *Transaction) Gas(ctx context.Context) Long {
+	return Long(t.tx.Tx.Gas())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.Tx.GasPrice())
+}
+
+func (t *Transaction) MaxFeePerGas(ctx context.Context) *hexutil.Big {
+	if t.tx.Tx.Type() != types.DynamicFeeTxType {
+		return nil
+	}
+	return (*hexutil.Big)(t.tx.Tx.GasFeeCap())
+}
+
+func (t *Transaction) MaxPriorityFeePerGas(ctx context.Context) *hexutil.Big {
+	if t.tx.Tx.Type() != types.DynamicFeeTxType {
+		return nil
+	}
+	return (*hexutil.Big)(t.tx.Tx.GasTipCap())
+}
+
+func (t *Transaction) EffectiveGasPrice(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(t.tx.Result.FeeStats.Price.L2Computation)
+}
+
+func (t *Transaction) Value(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.Tx.Value())
+}
+
+func (t *Transaction) Nonce(ctx context.Context) Long {
+	return Long(t.tx.Tx.Nonce())
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) *Account {
+	to := t.tx.Tx.To()
+	if to == nil {
+		return nil
+	}
+	return &Account{r: t.r, address: *to, blockNum: args.numberOr(t.blockNum())}
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		r:        t.r,
+		address:  t.tx.Result.IncomingRequest.Sender.ToEthAddress(),
+		blockNum: args.numberOr(t.blockNum()),
+	}
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	return t.getBlock()
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	index := int32(t.tx.Result.TxIndex.Uint64())
+	return &index
+}
+
+func (t *Transaction) Status(ctx context.Context) (*Long, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	status := Long(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) *Long {
+	gasUsed := Long(t.tx.Result.CalcGasUsed().Uint64())
+	return &gasUsed
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*Long, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	gasUsed := Long(receipt.CumulativeGasUsed)
+	return &gasUsed, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	if receipt.ContractAddress == (common.Address{}) {
+		return nil, nil
+	}
+	return &Account{r: t.r, address: receipt.ContractAddress, blockNum: args.numberOr(t.blockNum())}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(receipt.Logs))
+	for _, l := range receipt.Logs {
+		ret = append(ret, &Log{r: t.r, tx: t, log: l})
+	}
+	return &ret, nil
+}
+
+func (t *Transaction) Type(ctx context.Context) *int32 {
+	txType := int32(t.tx.Tx.Type())
+	return &txType
+}
+
+func (t *Transaction) AccessList(ctx context.Context) *[]*AccessTuple {
+	if t.tx.Tx.Type() == types.LegacyTxType {
+		return nil
+	}
+	accessList := t.tx.Tx.AccessList()
+	ret := make([]*AccessTuple, 0, len(accessList))
+	for _, al := range accessList {
+		ret = append(ret, &AccessTuple{address: al.Address, storageKeys: al.StorageKeys})
+	}
+	return &ret
+}
+
+func (t *Transaction) R(ctx context.Context) hexutil.Big {
+	_, r, _ := t.tx.Tx.RawSignatureValues()
+	return hexutil.Big(*r)
+}
+
+func (t *Transaction) S(ctx context.Context) hexutil.Big {
+	_, _, s := t.tx.Tx.RawSignatureValues()
+	return hexutil.Big(*s)
+}
+
+func (t *Transaction) V(ctx context.Context) hexutil.Big {
+	v, _, _ := t.tx.Tx.RawSignatureValues()
+	return hexutil.Big(*v)
+}
+
+func (t *Transaction) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	return t.tx.Tx.MarshalBinary()
+}
+
+func (t *Transaction) RawReceipt(ctx context.Context) (hexutil.Bytes, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	return receipt.MarshalBinary()
+}
+
+func (t *Transaction) L1BlockNumber(ctx context.Context) Long {
+	return Long(t.tx.Result.IncomingRequest.L1BlockNumber.Uint64())
+}
+
+func (t *Transaction) L1SequenceNumber(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(t.tx.Result.IncomingRequest.Provenance.L1SeqNum)
+}
+
+func (t *Transaction) ArbType(ctx context.Context) int32 {
+	return int32(t.tx.Kind)
+}
+
+func (t *Transaction) ReturnCode(ctx context.Context) int32 {
+	return int32(t.tx.Result.ResultCode)
+}
+
+func (t *Transaction) FeeStats(ctx context.Context) *FeeStats {
+	return &FeeStats{stats: t.tx.Result.FeeStats}
+}
+
+// Block represents an L2 block. Its results are loaded when first needed
+// since many queries only touch the header.
+type Block struct {
+	r    *Resolver
+	info *machine.BlockInfo
+
+	mu       sync.Mutex
+	blockLog *evm.BlockInfo
+	txes     []*evm.ProcessedTx
+}
+
+func (b *Block) results() (*evm.BlockInfo, []*evm.ProcessedTx, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.blockLog == nil {
+		blockLog, results, err := b.r.srv.GetMachineBlockResults(b.info)
+		if err != nil {
+			return nil, nil, err
+		}
+		if blockLog == nil {
+			return nil, nil, errors.New("block results not available")
+		}
+		b.blockLog = blockLog
+		b.txes = evm.FilterEthTxResults(results)
+	}
+	return b.blockLog, b.txes, nil
+}
+
+func (b *Block) blockNum() rpc.BlockNumberOrHash {
+	return blockNumberOrHash(b.info.Header.Number.Uint64())
+}
+
+func (b *Block) Number(ctx context.Context) Long {
+	return Long(b.info.Header.Number.Uint64())
+}
+
+func (b *Block) Hash(ctx context.Context) common.Hash {
+	return b.info.Header.Hash()
+}
+
+func (b *Block) GasLimit(ctx context.Context) Long {
+	return Long(b.info.Header.GasLimit)
+}
+
+func (b *Block) GasUsed(ctx context.Context) Long {
+	return Long(b.info.Header.GasUsed)
+}
+
+func (b *Block) BaseFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	blockLog, _, err := b.results()
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(web3.BlockBaseFee(blockLog)), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	if b.info.Header.Number.Sign() == 0 {
+		return nil, nil
+	}
+	return b.r.blockByHash(b.info.Header.ParentHash)
+}
+
+func (b *Block) Difficulty(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*b.info.Header.Difficulty)
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) hexutil.Big {
+	// Matches eth_getBlockByNumber, which reports the block's difficulty
+	return hexutil.Big(*b.info.Header.Difficulty)
+}
+
+func (b *Block) Timestamp(ctx context.Context) Long {
+	return Long(b.info.Header.Time)
+}
+
+func (b *Block) Nonce(ctx context.Context) hexutil.Bytes {
+	return b.info.Header.Nonce[:]
+}
+
+func (b *Block) MixHash(ctx context.Context) common.Hash {
+	return b.info.Header.MixDigest
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) common.Hash {
+	return b.info.Header.TxHash
+}
+
+func (b *Block) StateRoot(ctx context.Context) common.Hash {
+	return b.info.Header.Root
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) common.Hash {
+	return b.info.Header.ReceiptHash
+}
+
+func (b *Block) OmmerHash(ctx context.Context) common.Hash {
+	return b.info.Header.UncleHash
+}
+
+func (b *Block) OmmerCount(ctx context.Context) *int32 {
+	count := int32(0)
+	return &count
+}
+
+func (b *Block) Ommers(ctx context.Context) *[]*Block {
+	ret := make([]*Block, 0)
+	return &ret
+}
+
+func (b *Block) OmmerAt(ctx context.Context, args struct{ Index int32 }) *Block {
+	return nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) hexutil.Bytes {
+	return b.info.Header.Extra
+}
+
+func (b *Block) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return b.info.Header.Bloom.Bytes()
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{r: b.r, address: b.info.Header.Coinbase, blockNum: args.numberOr(b.blockNum())}
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
+	_, txes, err := b.results()
+	if err != nil {
+		return nil, err
+	}
+	count := int32(len(txes))
+	return &count, nil
+}
+
+func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+	_, txes, err := b.results()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(txes))
+	for _, tx := range txes {
+		ret = append(ret, &Transaction{r: b.r, tx: tx, block: b})
+	}
+	return &ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	_, txes, err := b.results()
+	if err != nil {
+		return nil, err
+	}
+	if args.Index < 0 || int(args.Index) >= len(txes) {
+		return nil, nil
+	}
+	return &Transaction{r: b.r, tx: txes[args.Index], block: b}, nil
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	var addresses []common.Address
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	var topics [][]common.Hash
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	return b.r.runFilter(ctx, filters.NewBlockFilter(b.r.srv, b.info.Header.Hash(), addresses, topics))
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) *Account {
+	return &Account{r: b.r, address: args.Address, blockNum: b.blockNum()}
+}
+
+func (b *Block) Call(ctx context.Context, args struct{ Data CallData }) (*CallResult, error) {
+	return b.r.call(ctx, args.Data, b.blockNum())
+}
+
+func (b *Block) EstimateGas(ctx context.Context, args struct{ Data CallData }) (Long, error) {
+	return b.r.estimateGas(ctx, args.Data, b.blockNum())
+}
+
+func (b *Block) L1BlockNumber(ctx context.Context) (Long, error) {
+	blockLog, _, err := b.results()
+	if err != nil {
+		return 0, err
+	}
+	return Long(blockLog.L1BlockNum.Uint64()), nil
+}
+
+// Pending represents the pending state. Since the sequencer doesn't expose
+// a mempool it has no transactions, but its state can still be queried.
+type Pending struct {
+	r *Resolver
+}
+
+func (p *Pending) TransactionCount(ctx context.Context) int32 {
+	return 0
+}
+
+func (p *Pending) Transactions(ctx context.Context) *[]*Transaction {
+	ret := make([]*Transaction, 0)
+	return &ret
+}
+
+func (p *Pending) Account(ctx context.Context, args struct{ Address common.Address }) *Account {
+	return &Account{
+		r:        p.r,
+		address:  args.Address,
+		blockNum: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber),
+	}
+}
+
+func (p *Pending) Call(ctx context.Context, args struct{ Data CallData }) (*CallResult, error) {
+	return p.r.call(ctx, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
+func (p *Pending) EstimateGas(ctx context.Context, args struct{ Data CallData }) (Long, error) {
+	return p.r.estimateGas(ctx, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
+// CallData encapsulates arguments to `call` or `estimateGas`
+type CallData struct {
+	From     *common.Address
+	To       *common.Address
+	Gas      *Long
+	GasPrice *hexutil.Big
+	Value    *hexutil.Big
+	Data     *hexutil.Bytes
+}
+
+func (c CallData) toCallTxArgs() web3.CallTxArgs {
+	args := web3.CallTxArgs{
+		From:     c.From,
+		To:       c.To,
+		GasPrice: c.GasPrice,
+		Value:    c.Value,
+		Data:     c.Data,
+	}
+	if c.Gas != nil {
+		gas := hexutil.Uint64(*c.Gas)
+		args.Gas = &gas
+	}
+	return args
+}
+
+// CallResult encapsulates the result of an invocation of the `call` accessor
+type CallResult struct {
+	data    hexutil.Bytes
+	gasUsed Long
+	status  Long
+}
+
+func (c *CallResult) Data(ctx context.Context) hexutil.Bytes {
+	return c.data
+}
+
+func (c *CallResult) GasUsed(ctx context.Context) Long {
+	return c.gasUsed
+}
+
+func (c *CallResult) Status(ctx context.Context) Long {
+	return c.status
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor
+// inside a block
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver
+type FilterCriteria struct {
+	FromBlock *Long
+	ToBlock   *Long
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+import (
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// TestBuildSchema checks that every field in the schema has a resolver with
+// a matching signature
+func TestBuildSchema(t *testing.T) {
+	if _, err := graphql.ParseSchema(schema, &Resolver{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLongUnmarshal(t *testing.T) {
+	inputs := []interface{}{"10", "0xa", int32(10), int64(10), float64(10)}
+	for _, input := range inputs {
+		var l Long
+		if err := l.UnmarshalGraphQL(input); err != nil {
+			t.Fatal(err)
+		}
+		if l != 10 {
+			t.Errorf("wrong value %v for input %v", l, input)
+		}
+	}
+	var l Long
+	if err := l.UnmarshalGraphQL(true); err == nil {
+		t.Error("expected error for bool input")
+	}
+}
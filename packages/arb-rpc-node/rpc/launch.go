@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"math/big"
+	"net/http"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -28,6 +29,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/callcache"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
 	utils2 "github.com/offchainlabs/arbitrum/packages/arb-rpc-node/utils"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcaster"
@@ -117,6 +119,9 @@ func SetupBatcher(
 		if err != nil {
 			return nil, nil, err
 		}
+		if config.Node.Aggregator.CompressAddresses {
+			newBatcher.EnableAddressCompression(ctx, db.LatestSnapshot)
+		}
 		return newBatcher, nil, nil
 	case StatefulBatcherMode:
 		var auth transactauth.TransactAuth
@@ -137,6 +142,9 @@ func SetupBatcher(
 		if err != nil {
 			return nil, nil, err
 		}
+		if config.Node.Aggregator.CompressAddresses {
+			newBatcher.EnableAddressCompression(ctx, db.LatestSnapshot)
+		}
 		return newBatcher, nil, nil
 	case SequencerBatcherMode:
 		rollup, err := ethbridgecontracts.NewRollupUserFacet(rollupAddress.ToEthAddress(), client)
@@ -169,6 +177,9 @@ func SetupBatcher(
 		if err != nil {
 			return nil, nil, err
 		}
+		if config.Node.Sequencer.CompressAddresses {
+			seqBatcher.EnableAddressCompression(ctx, db.LatestSnapshot)
+		}
 
 		broadcasterErrChan, err := feedBroadcaster.Start(ctx)
 		if err != nil {
@@ -180,7 +191,9 @@ func SetupBatcher(
 	}
 }
 
-func LaunchPublicServer(ctx context.Context, web3Server *rpc.Server, rpc configuration.RPC, ws configuration.WS) error {
+// LaunchPublicServer serves web3Server over HTTP and websockets, restricted
+// by access and recording rpcMetrics if they aren't nil
+func LaunchPublicServer(ctx context.Context, web3Server *rpc.Server, access *utils2.AccessControl, rpcMetrics *utils2.RPCMetrics, rpc configuration.RPC, ws configuration.WS) error {
 	if rpc.Port == ws.Port && rpc.Port != "" {
 		if rpc.Addr != ws.Addr {
 			return errors.New("if serving on same port, rpc and ws addreses must be the same")
@@ -188,19 +201,33 @@ func LaunchPublicServer(ctx context.Context, web3Server *rpc.Server, rpc configu
 		if rpc.Path == ws.Path {
 			return errors.New("if serving on same port, ws and rpc path must be different")
 		}
-		return utils2.LaunchRPCAndWS(ctx, web3Server, rpc.Addr, rpc.Port, rpc.Path, ws.Path)
+		return utils2.LaunchRPCAndWS(ctx, web3Server, access, rpcMetrics, rpc.Addr, rpc.Port, rpc.Path, ws.Path)
 	}
 
 	errChan := make(chan error, 1)
 	if rpc.Port != "" {
 		go func() {
-			errChan <- utils2.LaunchRPC(ctx, web3Server, rpc.Addr, rpc.Port, rpc.Path)
+			errChan <- utils2.LaunchRPC(ctx, utils2.RPCHandler(web3Server, access, rpcMetrics), rpc.Addr, rpc.Port, rpc.Path)
 		}()
 	}
 	if ws.Port != "" {
 		go func() {
-			errChan <- utils2.LaunchWS(ctx, web3Server, ws.Addr, ws.Port, ws.Path)
+			errChan <- utils2.LaunchWS(ctx, web3Server, access, rpcMetrics, ws.Addr, ws.Port, ws.Path)
 		}()
 	}
 	return <-errChan
 }
+
+// LaunchGraphQLServer serves handler, applying the same access control as the
+// JSON-RPC server if access isn't nil
+func LaunchGraphQLServer(ctx context.Context, handler http.Handler, access *utils2.AccessControl, graphql configuration.GraphQL) error {
+	if access != nil {
+		handler = access.GraphQLHandler(handler)
+	}
+	return utils2.LaunchRPC(ctx, handler, graphql.Addr, graphql.Port, graphql.Path)
+}
+
+// LaunchCallCacheServer serves cache to the call caches of other nodes
+func LaunchCallCacheServer(ctx context.Context, cache callcache.Cache, config configuration.CallCache) error {
+	return utils2.LaunchRPC(ctx, callcache.Handler(cache, config.Secret), config.Addr, config.Port, config.Path)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+var errNodeInterface = errors.New("NodeInterface is only available from a node")
+
+// ContractBackend lets contract bindings, such as arbos.Precompiles, run
+// directly against a snapshot. Calls run against the snapshot's current
+// state, and sent transactions are added to it, so the snapshot must be
+// uniquely owned if transactions are sent.
+type ContractBackend struct {
+	sync.Mutex
+	snap     *Snapshot
+	receipts map[ethcommon.Hash]*evm.TxResult
+}
+
+func NewContractBackend(snap *Snapshot) *ContractBackend {
+	return &ContractBackend{
+		snap:     snap,
+		receipts: make(map[ethcommon.Hash]*evm.TxResult),
+	}
+}
+
+func (b *ContractBackend) checkBlockNumber(blockNumber *big.Int) error {
+	if blockNumber != nil && blockNumber.Cmp(b.snap.Height().AsInt()) != 0 {
+		return errors.Errorf("snapshot is at block %v, not %v", b.snap.Height(), blockNumber)
+	}
+	return nil
+}
+
+func (b *ContractBackend) CodeAt(ctx context.Context, contract ethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	b.Lock()
+	defer b.Unlock()
+	if err := b.checkBlockNumber(blockNumber); err != nil {
+		return nil, err
+	}
+	return b.snap.GetCode(ctx, common.NewAddressFromEth(contract))
+}
+
+func (b *ContractBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if call.To != nil && *call.To == arbos.ARB_NODE_INTERFACE_ADDRESS {
+		return nil, errNodeInterface
+	}
+	b.Lock()
+	defer b.Unlock()
+	if err := b.checkBlockNumber(blockNumber); err != nil {
+		return nil, err
+	}
+	gas := call.Gas
+	if gas == 0 {
+		gas = 1 << 30
+	}
+	gasPrice := call.GasPrice
+	if gasPrice == nil || gasPrice.Sign() <= 0 {
+		gasPrice = b.snap.MaxGasPriceBid()
+	}
+	payment := call.Value
+	if payment == nil {
+		payment = big.NewInt(0)
+	}
+	var dest common.Address
+	if call.To != nil {
+		dest = common.NewAddressFromEth(*call.To)
+	}
+	msg := message.ContractTransaction{
+		BasicTx: message.BasicTx{
+			MaxGas:      new(big.Int).SetUint64(gas),
+			GasPriceBid: gasPrice,
+			DestAddress: dest,
+			Payment:     payment,
+			Data:        call.Data,
+		},
+	}
+	res, _, err := b.snap.Call(ctx, msg, common.NewAddressFromEth(call.From), math.MaxUint64, false)
+	if err != nil {
+		return nil, err
+	}
+	if res.ResultCode != evm.ReturnCode {
+		return nil, evm.HandleCallError(res, false)
+	}
+	return res.ReturnData, nil
+}
+
+func (b *ContractBackend) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	b.Lock()
+	defer b.Unlock()
+	if err := b.checkBlockNumber(number); err != nil {
+		return nil, err
+	}
+	return &types.Header{
+		Number: b.snap.Height().AsInt(),
+		Time:   b.snap.time.Timestamp.Uint64(),
+	}, nil
+}
+
+func (b *ContractBackend) PendingCodeAt(ctx context.Context, account ethcommon.Address) ([]byte, error) {
+	return b.CodeAt(ctx, account, nil)
+}
+
+func (b *ContractBackend) PendingNonceAt(ctx context.Context, account ethcommon.Address) (uint64, error) {
+	b.Lock()
+	defer b.Unlock()
+	count, err := b.snap.GetTransactionCount(ctx, common.NewAddressFromEth(account))
+	if err != nil {
+		return 0, err
+	}
+	return count.Uint64(), nil
+}
+
+func (b *ContractBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	b.Lock()
+	defer b.Unlock()
+	prices, err := b.snap.GetPricesInWei(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prices[5], nil
+}
+
+func (b *ContractBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.SuggestGasPrice(ctx)
+}
+
+func (b *ContractBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	if call.To != nil && *call.To == arbos.ARB_NODE_INTERFACE_ADDRESS {
+		return 0, errNodeInterface
+	}
+	b.Lock()
+	defer b.Unlock()
+	gasPrice := call.GasPrice
+	if gasPrice == nil || gasPrice.Sign() <= 0 {
+		gasPrice = b.snap.MaxGasPriceBid()
+	}
+	payment := call.Value
+	if payment == nil {
+		payment = big.NewInt(0)
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		GasPrice: gasPrice,
+		Gas:      call.Gas,
+		To:       call.To,
+		Value:    payment,
+		Data:     call.Data,
+	})
+	res, _, err := b.snap.EstimateGas(ctx, tx, common.Address{}, common.NewAddressFromEth(call.From), math.MaxUint64, false)
+	if err != nil {
+		return 0, err
+	}
+	if res.ResultCode != evm.ReturnCode {
+		return 0, evm.HandleCallError(res, false)
+	}
+	// Leave the same margin as a node's estimate
+	if res.FeeStats.Price.L2Computation.Sign() == 0 {
+		return res.GasUsed.Uint64() + 10000, nil
+	}
+	used := res.FeeStats.TargetGasUsed()
+	used.Mul(used, big.NewInt(11))
+	used.Div(used, big.NewInt(10))
+	return used.Uint64() + 100, nil
+}
+
+// SendTransaction adds tx to the snapshot. Like a node, it only fails if the
+// transaction couldn't be included, and the result is available from
+// TransactionReceipt.
+func (b *ContractBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.snap.chainId == nil {
+		return errors.New("snapshot's ArbOS version is too old to accept signed transactions")
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(b.snap.chainId), tx)
+	if err != nil {
+		return err
+	}
+	msg, err := message.NewL2Message(message.SignedTransaction{Tx: tx})
+	if err != nil {
+		return err
+	}
+	res, err := b.snap.AddMessage(ctx, msg, common.NewAddressFromEth(sender), common.NewHashFromEth(tx.Hash()))
+	if err != nil {
+		return err
+	}
+	b.receipts[tx.Hash()] = res
+	return nil
+}
+
+// TransactionReceipt returns the receipt of a transaction sent through this
+// backend
+func (b *ContractBackend) TransactionReceipt(_ context.Context, txHash ethcommon.Hash) (*types.Receipt, error) {
+	b.Lock()
+	defer b.Unlock()
+	res, ok := b.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return res.ToEthReceipt(common.Hash{}), nil
+}
+
+func (b *ContractBackend) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, errors.New("snapshot doesn't support filtering logs")
+}
+
+func (b *ContractBackend) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("snapshot doesn't support subscribing to logs")
+}
@@ -589,6 +589,53 @@ func (s *Snapshot) ChainId(ctx context.Context) (*big.Int, error) {
 	return arbos.ParseChainIdResult(res.ReturnData)
 }
 
+// GetRetryableTimeout returns the time the given retryable ticket expires, or
+// zero if there is no live ticket with that ID
+func (s *Snapshot) GetRetryableTimeout(ctx context.Context, ticketId common.Hash) (*big.Int, error) {
+	res, err := s.basicCall(ctx, arbos.GetTimeoutData(ticketId), common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS))
+	if err != nil {
+		return nil, err
+	}
+	if res.ResultCode == evm.RevertCode {
+		return big.NewInt(0), nil
+	}
+	if err := checkValidResult(res); err != nil {
+		return nil, err
+	}
+	return arbos.ParseGetTimeoutResult(res.ReturnData)
+}
+
+// AddressTableSize returns the number of addresses registered in the
+// ArbAddressTable precompile
+func (s *Snapshot) AddressTableSize(ctx context.Context) (uint64, error) {
+	res, err := s.basicCall(ctx, arbos.AddressTableSizeData(), common.NewAddressFromEth(arbos.ARB_ADDRESS_TABLE_ADDRESS))
+	if err != nil {
+		return 0, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return 0, err
+	}
+	size, err := arbos.ParseAddressTableSizeResult(res.ReturnData)
+	if err != nil {
+		return 0, err
+	}
+	return size.Uint64(), nil
+}
+
+// AddressTableLookupIndex returns the address registered at the given index
+// of the ArbAddressTable precompile
+func (s *Snapshot) AddressTableLookupIndex(ctx context.Context, index uint64) (common.Address, error) {
+	data := arbos.AddressTableLookupIndexData(new(big.Int).SetUint64(index))
+	res, err := s.basicCall(ctx, data, common.NewAddressFromEth(arbos.ARB_ADDRESS_TABLE_ADDRESS))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return common.Address{}, err
+	}
+	return arbos.ParseAddressTableLookupIndexResult(res.ReturnData)
+}
+
 func (s *Snapshot) GetPricesInWei(ctx context.Context) ([6]*big.Int, error) {
 	res, err := s.basicCall(ctx, arbos.GetPricesInWeiData(), common.NewAddressFromEth(arbos.ARB_GAS_INFO_ADDRESS))
 	if err != nil {
@@ -0,0 +1,138 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"encoding/binary"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+var addressIndexPrefix = []byte("a")
+
+type AddressIndexEntry struct {
+	BlockNumber uint64
+	TxIndex     uint64
+	RequestId   common.Hash
+}
+
+// AddressIndex maps the sender and recipient of each transaction to the
+// transaction's request ID. Entries are keyed by address, block number and
+// transaction index so that they can be iterated in chain order and removed
+// again from the transaction result alone when logs are reorged out.
+type AddressIndex struct {
+	txIndex
+}
+
+func NewAddressIndex(db ethdb.Database, nextBlock uint64) (*AddressIndex, error) {
+	idx, err := openTxIndex(db, "address", nextBlock)
+	if err != nil {
+		return nil, err
+	}
+	return &AddressIndex{txIndex: idx}, nil
+}
+
+func addressIndexKey(address common.Address, blockNum uint64, txIndex uint64) []byte {
+	key := make([]byte, 0, len(addressIndexPrefix)+len(address)+indexPositionLength)
+	key = append(key, addressIndexPrefix...)
+	key = append(key, address[:]...)
+	key = append(key, indexPosition(blockNum, txIndex)...)
+	return key
+}
+
+// indexedAddresses returns the addresses a transaction result is indexed
+// under, which are its sender, the recipient of the transaction if it could
+// be decoded, and the contract it created if any
+func indexedAddresses(res *evm.TxResult, tx *evm.ProcessedTx) []common.Address {
+	addresses := []common.Address{res.IncomingRequest.Sender}
+	add := func(address common.Address) {
+		for _, existing := range addresses {
+			if existing == address {
+				return
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	if tx == nil {
+		return addresses
+	}
+	if tx.Tx.To() != nil {
+		add(common.NewAddressFromEth(*tx.Tx.To()))
+	} else if res.ResultCode == evm.ReturnCode {
+		if address, ok := res.GetCreatedContractAddress(); ok {
+			add(common.NewAddressFromEth(address))
+		}
+	}
+	return addresses
+}
+
+func (idx *AddressIndex) add(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	blockNum := res.IncomingRequest.L2BlockNumber.Uint64()
+	txIndex := res.TxIndex.Uint64()
+	for _, address := range indexedAddresses(res, tx) {
+		key := addressIndexKey(address, blockNum, txIndex)
+		if err := batch.Put(key, res.IncomingRequest.MessageID.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *AddressIndex) remove(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	blockNum := res.IncomingRequest.L2BlockNumber.Uint64()
+	txIndex := res.TxIndex.Uint64()
+	for _, address := range indexedAddresses(res, tx) {
+		if err := batch.Delete(addressIndexKey(address, blockNum, txIndex)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTransactions returns up to limit transactions involving the given
+// address between fromBlock and toBlock inclusive, failing if fromBlock is
+// before the start of the index. If cursor is non-nil, iteration resumes
+// from the position it describes. If there are more transactions remaining,
+// the cursor to fetch them with is also returned.
+func (idx *AddressIndex) GetTransactions(
+	address common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+	cursor []byte,
+	limit int,
+) ([]AddressIndexEntry, []byte, error) {
+	if err := idx.checkCovered(fromBlock); err != nil {
+		return nil, nil, err
+	}
+	prefix := append(append([]byte{}, addressIndexPrefix...), address[:]...)
+	entries := make([]AddressIndexEntry, 0)
+	next, err := iterateIndex(idx.db, prefix, indexPositionLength, fromBlock, toBlock, cursor, limit, func(suffix []byte, value []byte) {
+		entries = append(entries, AddressIndexEntry{
+			BlockNumber: binary.BigEndian.Uint64(suffix[:8]),
+			TxIndex:     binary.BigEndian.Uint64(suffix[8:]),
+			RequestId:   common.NewHashFromEth(ethcommon.BytesToHash(value)),
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, next, nil
+}
@@ -0,0 +1,142 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func indexTestResult(sender common.Address, to common.Address, blockNum int64, txIndex int64) (*evm.TxResult, *evm.ProcessedTx) {
+	res := &evm.TxResult{
+		IncomingRequest: evm.IncomingRequest{
+			Sender:        sender,
+			MessageID:     common.RandHash(),
+			L2BlockNumber: big.NewInt(blockNum),
+		},
+		ResultCode: evm.ReturnCode,
+		TxIndex:    big.NewInt(txIndex),
+	}
+	ethTo := to.ToEthAddress()
+	tx := &evm.ProcessedTx{
+		Result: res,
+		Tx:     types.NewTx(&types.LegacyTx{To: &ethTo, GasPrice: big.NewInt(0), Value: big.NewInt(0)}),
+	}
+	return res, tx
+}
+
+func TestAddressIndex(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	index, err := NewAddressIndex(db, 0)
+	test.FailIfError(t, err)
+
+	account := common.RandAddress()
+	other := common.RandAddress()
+	results := make([]*evm.TxResult, 0)
+	txes := make([]*evm.ProcessedTx, 0)
+	for i := int64(0); i < 5; i++ {
+		sender, to := account, other
+		if i%2 == 1 {
+			sender, to = other, account
+		}
+		res, tx := indexTestResult(sender, to, i, 1)
+		results = append(results, res)
+		txes = append(txes, tx)
+	}
+	unrelated, unrelatedTx := indexTestResult(other, common.RandAddress(), 2, 0)
+
+	batch := db.NewBatch()
+	for i, res := range results {
+		test.FailIfError(t, index.add(batch, res, txes[i]))
+	}
+	test.FailIfError(t, index.add(batch, unrelated, unrelatedTx))
+	test.FailIfError(t, batch.Write())
+
+	entries, cursor, err := index.GetTransactions(account, 1, 3, nil, 2)
+	test.FailIfError(t, err)
+	if len(entries) != 2 || cursor == nil {
+		t.Fatal("expected first page of two entries with cursor, got", len(entries))
+	}
+	if entries[0].RequestId != results[1].IncomingRequest.MessageID ||
+		entries[1].RequestId != results[2].IncomingRequest.MessageID {
+		t.Error("wrong entries on first page")
+	}
+	entries, cursor, err = index.GetTransactions(account, 1, 3, cursor, 2)
+	test.FailIfError(t, err)
+	if len(entries) != 1 || cursor != nil {
+		t.Fatal("expected final page of one entry, got", len(entries))
+	}
+	if entries[0].BlockNumber != 3 || entries[0].TxIndex != 1 || entries[0].RequestId != results[3].IncomingRequest.MessageID {
+		t.Error("wrong entry on last page")
+	}
+
+	batch = db.NewBatch()
+	for i := len(results) - 1; i >= 3; i-- {
+		test.FailIfError(t, index.remove(batch, results[i], txes[i]))
+	}
+	test.FailIfError(t, batch.Write())
+
+	entries, _, err = index.GetTransactions(account, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 3 {
+		t.Fatal("expected 3 entries after reorg, got", len(entries))
+	}
+	entries, _, err = index.GetTransactions(other, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 4 {
+		t.Error("expected 4 entries for other address after reorg, got", len(entries))
+	}
+
+	if _, _, err := index.GetTransactions(account, 0, 10, []byte{1}, 10); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
+func TestAddressIndexStart(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+
+	// Enabling the index on an existing database starts it at the next block
+	index, err := NewAddressIndex(db, 5)
+	test.FailIfError(t, err)
+	if index.StartBlock() != 5 {
+		t.Fatal("wrong start block", index.StartBlock())
+	}
+
+	// Reopening the index keeps its original start
+	index, err = NewAddressIndex(db, 8)
+	test.FailIfError(t, err)
+	if index.StartBlock() != 5 {
+		t.Fatal("start block changed on reopen", index.StartBlock())
+	}
+
+	account := common.RandAddress()
+	if _, _, err := index.GetTransactions(account, 4, 10, nil, 10); err == nil {
+		t.Error("expected error for range before start of index")
+	}
+	if _, _, err := index.GetTransactions(account, 5, 10, nil, 10); err != nil {
+		t.Error("unexpected error for range covered by index", err)
+	}
+}
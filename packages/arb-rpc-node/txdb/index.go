@@ -0,0 +1,155 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+)
+
+// indexStartPrefix is followed by the name of an index in the key storing
+// the first block the index covers
+var indexStartPrefix = []byte("s")
+
+// resultIndex is a secondary index of transaction results maintained by
+// TxDB. Entries must be derived from the result alone so that they can be
+// removed again when the result is reorged out.
+type resultIndex interface {
+	add(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error
+	remove(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error
+}
+
+// txIndex holds the state shared by every resultIndex. An index only covers
+// the blocks processed after it was enabled, so the first of them is stored
+// when the index is created and lookups of earlier blocks are refused rather
+// than silently returning partial results.
+type txIndex struct {
+	db         ethdb.Database
+	name       string
+	startBlock uint64
+}
+
+// openTxIndex loads the start of the named index, starting it at nextBlock
+// if it doesn't exist yet
+func openTxIndex(db ethdb.Database, name string, nextBlock uint64) (txIndex, error) {
+	key := append(append([]byte{}, indexStartPrefix...), name...)
+	idx := txIndex{db: db, name: name}
+	exists, err := db.Has(key)
+	if err != nil {
+		return txIndex{}, err
+	}
+	if !exists {
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], nextBlock)
+		if err := db.Put(key, data[:]); err != nil {
+			return txIndex{}, err
+		}
+		if nextBlock > 0 {
+			logger.Warn().
+				Str("index", name).
+				Uint64("startBlock", nextBlock).
+				Msg("index enabled on existing database only covers new blocks")
+		}
+		idx.startBlock = nextBlock
+		return idx, nil
+	}
+	data, err := db.Get(key)
+	if err != nil {
+		return txIndex{}, err
+	}
+	if len(data) != 8 {
+		return txIndex{}, errors.Errorf("invalid start of %v index", name)
+	}
+	idx.startBlock = binary.BigEndian.Uint64(data)
+	return idx, nil
+}
+
+// StartBlock returns the first block covered by the index
+func (idx *txIndex) StartBlock() uint64 {
+	return idx.startBlock
+}
+
+// checkCovered returns an error if fromBlock is before the start of the index
+func (idx *txIndex) checkCovered(fromBlock uint64) error {
+	if fromBlock < idx.startBlock {
+		return errors.Errorf("%v index only covers blocks from %v", idx.name, idx.startBlock)
+	}
+	return nil
+}
+
+// Length of the position suffix of index keys, made up of the block number
+// followed by the index of the transaction in the block
+const indexPositionLength = 16
+
+func indexPosition(blockNum uint64, txIndex uint64) []byte {
+	position := make([]byte, indexPositionLength)
+	binary.BigEndian.PutUint64(position[:8], blockNum)
+	binary.BigEndian.PutUint64(position[8:], txIndex)
+	return position
+}
+
+// iterateIndex calls handle with the key suffix and value of up to limit
+// entries under prefix, in key order. Each suffix starts with the position
+// of the transaction the entry refers to, and only entries from blocks
+// fromBlock to toBlock inclusive are visited. If cursor is non-nil,
+// iteration resumes from the suffix it holds. If entries remain after limit
+// have been visited, the suffix of the next one is returned as a cursor.
+func iterateIndex(
+	db ethdb.Iteratee,
+	prefix []byte,
+	suffixLength int,
+	fromBlock uint64,
+	toBlock uint64,
+	cursor []byte,
+	limit int,
+	handle func(suffix []byte, value []byte),
+) ([]byte, error) {
+	start := indexPosition(fromBlock, 0)
+	if cursor != nil {
+		if len(cursor) != suffixLength {
+			return nil, errors.New("invalid cursor")
+		}
+		if bytes.Compare(cursor, start) > 0 {
+			start = cursor
+		}
+	}
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	count := 0
+	for it.Next() {
+		suffix := it.Key()[len(prefix):]
+		if len(suffix) != suffixLength {
+			return nil, errors.Errorf("unexpected index key length %v", len(it.Key()))
+		}
+		if binary.BigEndian.Uint64(suffix[:8]) > toBlock {
+			break
+		}
+		if count == limit {
+			return append([]byte{}, suffix...), nil
+		}
+		handle(suffix, it.Value())
+		count++
+	}
+	return nil, it.Error()
+}
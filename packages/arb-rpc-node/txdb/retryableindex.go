@@ -0,0 +1,157 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"encoding/binary"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+var retryableIndexPrefix = []byte("r")
+
+type RetryableEventKind uint8
+
+const (
+	RetryableCreated RetryableEventKind = iota
+	RetryableRedeemAttempted
+	RetryableLifetimeExtended
+	RetryableCanceled
+)
+
+type RetryableIndexEntry struct {
+	Kind        RetryableEventKind
+	BlockNumber uint64
+	TxIndex     uint64
+	RequestId   common.Hash
+}
+
+// RetryableIndex records every transaction which created, tried to redeem,
+// extended the lifetime of or canceled each retryable ticket. Like the
+// address index, entries are keyed by ticket ID followed by the position of
+// the transaction so they can be recomputed and removed on reorgs.
+type RetryableIndex struct {
+	txIndex
+}
+
+func NewRetryableIndex(db ethdb.Database, nextBlock uint64) (*RetryableIndex, error) {
+	idx, err := openTxIndex(db, "retryable", nextBlock)
+	if err != nil {
+		return nil, err
+	}
+	return &RetryableIndex{txIndex: idx}, nil
+}
+
+type retryableEvent struct {
+	ticketId common.Hash
+	kind     RetryableEventKind
+}
+
+func retryableIndexKey(event retryableEvent, blockNum uint64, txIndex uint64) []byte {
+	key := make([]byte, 0, len(retryableIndexPrefix)+32+indexPositionLength+1)
+	key = append(key, retryableIndexPrefix...)
+	key = append(key, event.ticketId[:]...)
+	key = append(key, indexPosition(blockNum, txIndex)...)
+	key = append(key, byte(event.kind))
+	return key
+}
+
+// retryableEvents returns the retryable tickets a transaction result relates
+// to. Redeem attempts are found from the transaction itself since failed
+// attempts don't emit any events.
+func retryableEvents(res *evm.TxResult, tx *evm.ProcessedTx) []retryableEvent {
+	var events []retryableEvent
+	if res.IncomingRequest.Kind == message.RetryableType &&
+		(res.ResultCode == evm.ReturnCode || res.ResultCode == evm.NoGasForAutoRedeem) {
+		events = append(events, retryableEvent{
+			ticketId: message.RetryableTicketID(res.IncomingRequest.MessageID),
+			kind:     RetryableCreated,
+		})
+	}
+	if tx != nil && tx.Tx.To() != nil && *tx.Tx.To() == arbos.ARB_RETRYABLE_ADDRESS {
+		if ticketId, ok := arbos.ParseRedeemData(tx.Tx.Data()); ok {
+			events = append(events, retryableEvent{ticketId: ticketId, kind: RetryableRedeemAttempted})
+		}
+	}
+	retryableAddress := common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS)
+	for _, evmLog := range res.EVMLogs {
+		if evmLog.Address != retryableAddress || len(evmLog.Topics) < 2 {
+			continue
+		}
+		switch evmLog.Topics[0].ToEthHash() {
+		case arbos.RetryLifetimeExtendedEvent.ID:
+			events = append(events, retryableEvent{ticketId: evmLog.Topics[1], kind: RetryableLifetimeExtended})
+		case arbos.RetryCanceledEvent.ID:
+			events = append(events, retryableEvent{ticketId: evmLog.Topics[1], kind: RetryableCanceled})
+		}
+	}
+	return events
+}
+
+func (idx *RetryableIndex) add(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	blockNum := res.IncomingRequest.L2BlockNumber.Uint64()
+	txIndex := res.TxIndex.Uint64()
+	for _, event := range retryableEvents(res, tx) {
+		key := retryableIndexKey(event, blockNum, txIndex)
+		if err := batch.Put(key, res.IncomingRequest.MessageID.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *RetryableIndex) remove(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	blockNum := res.IncomingRequest.L2BlockNumber.Uint64()
+	txIndex := res.TxIndex.Uint64()
+	for _, event := range retryableEvents(res, tx) {
+		if err := batch.Delete(retryableIndexKey(event, blockNum, txIndex)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTicketEvents returns the indexed events for the given ticket in the
+// order they occurred. Tickets created before the start of the index have
+// no creation event and may be missing earlier events.
+func (idx *RetryableIndex) GetTicketEvents(ticketId common.Hash) ([]RetryableIndexEntry, error) {
+	prefix := append(append([]byte{}, retryableIndexPrefix...), ticketId[:]...)
+	it := idx.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	entries := make([]RetryableIndexEntry, 0)
+	for it.Next() {
+		suffix := it.Key()[len(prefix):]
+		if len(suffix) != indexPositionLength+1 {
+			return nil, errors.Errorf("unexpected retryable index key length %v", len(it.Key()))
+		}
+		entries = append(entries, RetryableIndexEntry{
+			Kind:        RetryableEventKind(suffix[indexPositionLength]),
+			BlockNumber: binary.BigEndian.Uint64(suffix[:8]),
+			TxIndex:     binary.BigEndian.Uint64(suffix[8:16]),
+			RequestId:   common.NewHashFromEth(ethcommon.BytesToHash(it.Value())),
+		})
+	}
+	return entries, it.Error()
+}
@@ -0,0 +1,100 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestRetryableIndex(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	index, err := NewRetryableIndex(db, 0)
+	test.FailIfError(t, err)
+
+	creation := &evm.TxResult{
+		IncomingRequest: evm.IncomingRequest{
+			Kind:          message.RetryableType,
+			MessageID:     common.RandHash(),
+			L2BlockNumber: big.NewInt(5),
+		},
+		ResultCode: evm.ReturnCode,
+		TxIndex:    big.NewInt(0),
+	}
+	ticketId := message.RetryableTicketID(creation.IncomingRequest.MessageID)
+
+	autoRedeem, autoRedeemTx := indexTestResult(common.RandAddress(), common.Address{}, 5, 1)
+	autoRedeem.IncomingRequest.MessageID = message.RetryableAutoRedeemID(creation.IncomingRequest.MessageID)
+	autoRedeem.ResultCode = evm.RevertCode
+	autoRedeemTx.Tx = types.NewTx(&types.LegacyTx{
+		To:       &arbos.ARB_RETRYABLE_ADDRESS,
+		GasPrice: big.NewInt(0),
+		Value:    big.NewInt(0),
+		Data:     arbos.RedeemData(ticketId),
+	})
+
+	cancel, cancelTx := indexTestResult(common.RandAddress(), common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS), 8, 0)
+	cancel.EVMLogs = []evm.Log{{
+		Address: common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS),
+		Topics:  []common.Hash{common.NewHashFromEth(arbos.RetryCanceledEvent.ID), ticketId},
+	}}
+
+	unrelated, unrelatedTx := indexTestResult(common.RandAddress(), common.RandAddress(), 6, 0)
+
+	batch := db.NewBatch()
+	test.FailIfError(t, index.add(batch, creation, nil))
+	test.FailIfError(t, index.add(batch, autoRedeem, autoRedeemTx))
+	test.FailIfError(t, index.add(batch, unrelated, unrelatedTx))
+	test.FailIfError(t, index.add(batch, cancel, cancelTx))
+	test.FailIfError(t, batch.Write())
+
+	entries, err := index.GetTicketEvents(ticketId)
+	test.FailIfError(t, err)
+	expectedKinds := []RetryableEventKind{RetryableCreated, RetryableRedeemAttempted, RetryableCanceled}
+	expectedIds := []common.Hash{
+		creation.IncomingRequest.MessageID,
+		autoRedeem.IncomingRequest.MessageID,
+		cancel.IncomingRequest.MessageID,
+	}
+	if len(entries) != len(expectedKinds) {
+		t.Fatal("expected", len(expectedKinds), "entries but got", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Kind != expectedKinds[i] || entry.RequestId != expectedIds[i] {
+			t.Error("wrong entry", i, entry)
+		}
+	}
+
+	batch = db.NewBatch()
+	test.FailIfError(t, index.remove(batch, cancel, cancelTx))
+	test.FailIfError(t, batch.Write())
+	entries, err = index.GetTicketEvents(ticketId)
+	test.FailIfError(t, err)
+	if len(entries) != 2 || entries[1].Kind != RetryableRedeemAttempted {
+		t.Error("expected cancellation to be removed")
+	}
+}
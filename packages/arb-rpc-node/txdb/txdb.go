@@ -27,7 +27,9 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/trie"
 	lru "github.com/hashicorp/golang-lru"
@@ -64,6 +66,12 @@ type TxDB struct {
 	snapshotLRUCache   *lru.Cache
 	blockInfoLRUCache  *lru.Cache
 	snapshotTimedCache *blockcache.BlockCache
+
+	indexDB         ethdb.Database
+	indexes         []resultIndex
+	addressIndex    *AddressIndex
+	retryableIndex  *RetryableIndex
+	withdrawalIndex *WithdrawalIndex
 }
 
 func New(
@@ -100,6 +108,38 @@ func New(
 		snapshotTimedCache: snapshotTimedCache,
 		allowSlowLookup:    nodeConfig.Cache.AllowSlowLookup,
 	}
+	if nodeConfig.Index.Addresses || nodeConfig.Index.Retryables || nodeConfig.Index.Withdrawals {
+		db.indexDB, err = rawdb.NewLevelDBDatabase(nodeConfig.Index.Path, 0, 0, "", false)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error opening index database")
+		}
+		// Indexes created now start from the next block to be processed
+		nextBlock, err := as.BlockCount()
+		if err != nil {
+			return nil, nil, err
+		}
+		if nodeConfig.Index.Addresses {
+			db.addressIndex, err = NewAddressIndex(db.indexDB, nextBlock)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error opening address index")
+			}
+			db.indexes = append(db.indexes, db.addressIndex)
+		}
+		if nodeConfig.Index.Retryables {
+			db.retryableIndex, err = NewRetryableIndex(db.indexDB, nextBlock)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error opening retryable index")
+			}
+			db.indexes = append(db.indexes, db.retryableIndex)
+		}
+		if nodeConfig.Index.Withdrawals {
+			db.withdrawalIndex, err = NewWithdrawalIndex(db.indexDB, nextBlock)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "error opening withdrawal index")
+			}
+			db.indexes = append(db.indexes, db.withdrawalIndex)
+		}
+	}
 	logReader := core.NewLogReader(db, arbCore, big.NewInt(0), big.NewInt(int64(nodeConfig.LogProcessCount)), nodeConfig.LogIdleSleep)
 	errChan := logReader.Start(ctx)
 	db.logReader = logReader
@@ -108,6 +148,29 @@ func New(
 
 func (db *TxDB) Close() {
 	db.logReader.Stop()
+	if db.indexDB != nil {
+		if err := db.indexDB.Close(); err != nil {
+			logger.Warn().Err(err).Msg("error closing index database")
+		}
+	}
+}
+
+// AddressIndex returns the index of transactions by address, or nil if the
+// index isn't enabled
+func (db *TxDB) AddressIndex() *AddressIndex {
+	return db.addressIndex
+}
+
+// RetryableIndex returns the index of retryable ticket events, or nil if the
+// index isn't enabled
+func (db *TxDB) RetryableIndex() *RetryableIndex {
+	return db.retryableIndex
+}
+
+// WithdrawalIndex returns the index of L2 to L1 messages by address, or nil
+// if the index isn't enabled
+func (db *TxDB) WithdrawalIndex() *WithdrawalIndex {
+	return db.withdrawalIndex
 }
 
 func (db *TxDB) GetBlockResults(block *machine.BlockInfo) (*evm.BlockInfo, []*evm.TxResult, error) {
@@ -170,6 +233,10 @@ func (db *TxDB) AddLogs(initialLogIndex *big.Int, avmLogs []core.ValueAndInbox)
 	logIndex := initialLogIndex.Uint64()
 	var lastBlockAdded *evm.BlockInfo
 	var lastBlockHeader *types.Header
+	var indexBatch ethdb.Batch
+	if db.indexDB != nil {
+		indexBatch = db.indexDB.NewBatch()
+	}
 	for _, avmLog := range avmLogs {
 		res, err := evm.NewResultFromValue(avmLog.Value)
 		if err != nil {
@@ -188,18 +255,26 @@ func (db *TxDB) AddLogs(initialLogIndex *big.Int, avmLogs []core.ValueAndInbox)
 			err = db.as.SaveMessageBatch(res.BatchNumber, logIndex)
 		case *evm.TxResult:
 			monitor.GlobalMonitor.GotLog(res.IncomingRequest.MessageID)
-			tx, err := evm.GetTransaction(res)
-			if err != nil {
-				logger.Warn().Err(err).Msg("error pulling transaction from receipt")
+			tx, txErr := evm.GetTransaction(res)
+			if txErr != nil {
+				logger.Warn().Err(txErr).Msg("error pulling transaction from receipt")
 			} else {
 				db.newTxsFeed.Send(ethcore.NewTxsEvent{Txs: []*types.Transaction{tx.Tx}})
 			}
+			if indexBatch != nil {
+				err = db.indexResult(indexBatch, res, tx)
+			}
 		}
 		if err != nil {
 			return err
 		}
 		logIndex++
 	}
+	if indexBatch != nil {
+		if err := indexBatch.Write(); err != nil {
+			return err
+		}
+	}
 
 	if lastBlockAdded != nil {
 		log := logger.Info().
@@ -227,6 +302,10 @@ func (db *TxDB) DeleteLogs(avmLogs []core.ValueAndInbox) error {
 	// Collect all logs that will be removed so they can be sent to rmLogs subscription
 	var reorgBlockHeight uint64
 	blockReceiptFound := false
+	var indexBatch ethdb.Batch
+	if db.indexDB != nil {
+		indexBatch = db.indexDB.NewBatch()
+	}
 	for _, avmLog := range avmLogs {
 		// L2 transaction receipts already provided in reverse
 		res, err := evm.NewResultFromValue(avmLog.Value)
@@ -243,6 +322,14 @@ func (db *TxDB) DeleteLogs(avmLogs []core.ValueAndInbox) error {
 			continue
 		}
 
+		if indexBatch != nil {
+			// Results that aren't transactions were only indexed under their sender
+			tx, _ := evm.GetTransaction(txRes)
+			if err := db.unindexResult(indexBatch, txRes, tx); err != nil {
+				return err
+			}
+		}
+
 		currentBlockHeight := txRes.IncomingRequest.L2BlockNumber.Uint64()
 		logBlockInfo, err := db.GetBlock(currentBlockHeight)
 		if err != nil {
@@ -264,6 +351,12 @@ func (db *TxDB) DeleteLogs(avmLogs []core.ValueAndInbox) error {
 		}
 	}
 
+	if indexBatch != nil {
+		if err := indexBatch.Write(); err != nil {
+			return err
+		}
+	}
+
 	if blockReceiptFound {
 		// Reset block height
 		err := db.as.Reorg(reorgBlockHeight)
@@ -289,6 +382,24 @@ func (db *TxDB) DeleteLogs(avmLogs []core.ValueAndInbox) error {
 	return nil
 }
 
+func (db *TxDB) indexResult(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	for _, idx := range db.indexes {
+		if err := idx.add(batch, res, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *TxDB) unindexResult(batch ethdb.KeyValueWriter, res *evm.TxResult, tx *evm.ProcessedTx) error {
+	for _, idx := range db.indexes {
+		if err := idx.remove(batch, res, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (db *TxDB) handleBlockReceipt(blockInfo *evm.BlockInfo) (*types.Header, error) {
 	logger.Debug().
 		Uint64("number", blockInfo.BlockNum.Uint64()).
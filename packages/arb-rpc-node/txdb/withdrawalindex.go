@@ -0,0 +1,161 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"encoding/binary"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+var (
+	withdrawalSenderPrefix      = []byte("ws")
+	withdrawalDestinationPrefix = []byte("wd")
+)
+
+// Length of the suffix of withdrawal index keys, which is the position of
+// the transaction followed by the index of the withdrawal's log within it
+const withdrawalSuffixLength = indexPositionLength + 8
+
+type WithdrawalIndexEntry struct {
+	BlockNumber uint64
+	TxIndex     uint64
+	LogIndex    uint64
+	RequestId   common.Hash
+}
+
+// WithdrawalIndex maps the L2 sender and L1 destination of every L2 to L1
+// message to the transaction which sent it. The batch number and index of
+// each message are found from the L2ToL1Transaction event it emitted, so
+// looking up the withdrawals from a known transaction needs no index.
+type WithdrawalIndex struct {
+	txIndex
+}
+
+func NewWithdrawalIndex(db ethdb.Database, nextBlock uint64) (*WithdrawalIndex, error) {
+	idx, err := openTxIndex(db, "withdrawal", nextBlock)
+	if err != nil {
+		return nil, err
+	}
+	return &WithdrawalIndex{txIndex: idx}, nil
+}
+
+func withdrawalIndexKey(prefix []byte, address ethcommon.Address, blockNum, txIndex, logIndex uint64) []byte {
+	key := make([]byte, 0, len(prefix)+len(address)+withdrawalSuffixLength)
+	key = append(key, prefix...)
+	key = append(key, address[:]...)
+	key = append(key, indexPosition(blockNum, txIndex)...)
+	var logIndexBytes [8]byte
+	binary.BigEndian.PutUint64(logIndexBytes[:], logIndex)
+	return append(key, logIndexBytes[:]...)
+}
+
+func withdrawalIndexKeys(res *evm.TxResult) [][]byte {
+	blockNum := res.IncomingRequest.L2BlockNumber.Uint64()
+	txIndex := res.TxIndex.Uint64()
+	var keys [][]byte
+	for i, evmLog := range res.EVMLogs {
+		if evmLog.Address.ToEthAddress() != arbos.ARB_SYS_ADDRESS {
+			continue
+		}
+		ev, err := arbos.ParseL2ToL1TransactionLog(common.NewEthHashesFromHashes(evmLog.Topics), evmLog.Data)
+		if err != nil {
+			continue
+		}
+		keys = append(keys,
+			withdrawalIndexKey(withdrawalSenderPrefix, ev.Caller, blockNum, txIndex, uint64(i)),
+			withdrawalIndexKey(withdrawalDestinationPrefix, ev.Destination, blockNum, txIndex, uint64(i)),
+		)
+	}
+	return keys
+}
+
+func (idx *WithdrawalIndex) add(batch ethdb.KeyValueWriter, res *evm.TxResult, _ *evm.ProcessedTx) error {
+	for _, key := range withdrawalIndexKeys(res) {
+		if err := batch.Put(key, res.IncomingRequest.MessageID.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *WithdrawalIndex) remove(batch ethdb.KeyValueWriter, res *evm.TxResult, _ *evm.ProcessedTx) error {
+	for _, key := range withdrawalIndexKeys(res) {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBySender returns up to limit withdrawals sent from the given L2 address
+// between fromBlock and toBlock inclusive, along with a cursor to continue
+// from if more remain. It fails if fromBlock is before the start of the index.
+func (idx *WithdrawalIndex) GetBySender(
+	sender common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+	cursor []byte,
+	limit int,
+) ([]WithdrawalIndexEntry, []byte, error) {
+	return idx.get(withdrawalSenderPrefix, sender, fromBlock, toBlock, cursor, limit)
+}
+
+// GetByDestination returns up to limit withdrawals to the given L1 address
+// between fromBlock and toBlock inclusive, along with a cursor to continue
+// from if more remain. It fails if fromBlock is before the start of the index.
+func (idx *WithdrawalIndex) GetByDestination(
+	destination common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+	cursor []byte,
+	limit int,
+) ([]WithdrawalIndexEntry, []byte, error) {
+	return idx.get(withdrawalDestinationPrefix, destination, fromBlock, toBlock, cursor, limit)
+}
+
+func (idx *WithdrawalIndex) get(
+	keyPrefix []byte,
+	address common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+	cursor []byte,
+	limit int,
+) ([]WithdrawalIndexEntry, []byte, error) {
+	if err := idx.checkCovered(fromBlock); err != nil {
+		return nil, nil, err
+	}
+	prefix := append(append([]byte{}, keyPrefix...), address[:]...)
+	entries := make([]WithdrawalIndexEntry, 0)
+	next, err := iterateIndex(idx.db, prefix, withdrawalSuffixLength, fromBlock, toBlock, cursor, limit, func(suffix []byte, value []byte) {
+		entries = append(entries, WithdrawalIndexEntry{
+			BlockNumber: binary.BigEndian.Uint64(suffix[:8]),
+			TxIndex:     binary.BigEndian.Uint64(suffix[8:16]),
+			LogIndex:    binary.BigEndian.Uint64(suffix[16:]),
+			RequestId:   common.NewHashFromEth(ethcommon.BytesToHash(value)),
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, next, nil
+}
@@ -0,0 +1,171 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package txdb
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func withdrawalTestLog(t *testing.T, caller common.Address, destination common.Address) evm.Log {
+	arbsys, err := abi.JSON(strings.NewReader(arboscontracts.ArbSysABI))
+	test.FailIfError(t, err)
+	data, err := arbsys.Events["L2ToL1Transaction"].Inputs.NonIndexed().Pack(
+		caller.ToEthAddress(),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		[]byte{},
+	)
+	test.FailIfError(t, err)
+	return evm.Log{
+		Address: common.NewAddressFromEth(arbos.ARB_SYS_ADDRESS),
+		Topics: []common.Hash{
+			common.NewHashFromEth(arbos.L2ToL1TransactionID),
+			common.NewHashFromEth(ethcommon.BytesToHash(destination.Bytes())),
+			common.RandHash(),
+			common.RandHash(),
+		},
+		Data: data,
+	}
+}
+
+func TestWithdrawalIndex(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	index, err := NewWithdrawalIndex(db, 0)
+	test.FailIfError(t, err)
+	txDB := &TxDB{indexes: []resultIndex{index}}
+
+	sender := common.RandAddress()
+	destination := common.RandAddress()
+	results := make([]*evm.TxResult, 0)
+	for i := int64(0); i < 4; i++ {
+		res, _ := indexTestResult(sender, common.NewAddressFromEth(arbos.ARB_SYS_ADDRESS), i, 0)
+		res.EVMLogs = []evm.Log{
+			// Logs from other contracts are skipped even with the same topic
+			{Address: common.RandAddress(), Topics: []common.Hash{common.NewHashFromEth(arbos.L2ToL1TransactionID)}},
+			withdrawalTestLog(t, sender, destination),
+		}
+		results = append(results, res)
+	}
+	// A transaction sending two messages to different destinations
+	otherDestination := common.RandAddress()
+	multi, _ := indexTestResult(sender, common.NewAddressFromEth(arbos.ARB_SYS_ADDRESS), 4, 2)
+	multi.EVMLogs = []evm.Log{
+		withdrawalTestLog(t, sender, otherDestination),
+		withdrawalTestLog(t, sender, destination),
+	}
+	results = append(results, multi)
+	unrelated, _ := indexTestResult(common.RandAddress(), common.RandAddress(), 2, 1)
+
+	batch := db.NewBatch()
+	for _, res := range results {
+		test.FailIfError(t, txDB.indexResult(batch, res, nil))
+	}
+	test.FailIfError(t, txDB.indexResult(batch, unrelated, nil))
+	test.FailIfError(t, batch.Write())
+
+	entries, cursor, err := index.GetBySender(sender, 1, 4, nil, 3)
+	test.FailIfError(t, err)
+	if len(entries) != 3 || cursor == nil {
+		t.Fatal("expected first page of three entries with cursor, got", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.BlockNumber != uint64(i+1) || entry.LogIndex != 1 || entry.RequestId != results[i+1].IncomingRequest.MessageID {
+			t.Error("wrong entry on first page", i, entry)
+		}
+	}
+	entries, cursor, err = index.GetBySender(sender, 1, 4, cursor, 3)
+	test.FailIfError(t, err)
+	if len(entries) != 2 || cursor != nil {
+		t.Fatal("expected final page of two entries, got", len(entries))
+	}
+	if entries[0].BlockNumber != 4 || entries[0].TxIndex != 2 || entries[0].LogIndex != 0 ||
+		entries[1].LogIndex != 1 || entries[1].RequestId != multi.IncomingRequest.MessageID {
+		t.Error("wrong entries on final page", entries)
+	}
+
+	entries, _, err = index.GetByDestination(destination, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 5 {
+		t.Fatal("expected 5 withdrawals to destination, got", len(entries))
+	}
+	entries, _, err = index.GetByDestination(otherDestination, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 1 || entries[0].RequestId != multi.IncomingRequest.MessageID || entries[0].LogIndex != 0 {
+		t.Fatal("wrong withdrawals to other destination", entries)
+	}
+
+	// Deleting the last two blocks as DeleteLogs does on a reorg
+	batch = db.NewBatch()
+	for i := len(results) - 1; i >= 3; i-- {
+		test.FailIfError(t, txDB.unindexResult(batch, results[i], nil))
+	}
+	test.FailIfError(t, batch.Write())
+
+	entries, _, err = index.GetBySender(sender, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 3 {
+		t.Error("expected 3 withdrawals from sender after reorg, got", len(entries))
+	}
+	entries, _, err = index.GetByDestination(destination, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 3 {
+		t.Error("expected 3 withdrawals to destination after reorg, got", len(entries))
+	}
+	entries, _, err = index.GetByDestination(otherDestination, 0, 10, nil, 10)
+	test.FailIfError(t, err)
+	if len(entries) != 0 {
+		t.Error("expected no withdrawals to other destination after reorg, got", len(entries))
+	}
+
+	if _, _, err := index.GetBySender(sender, 0, 10, []byte{1}, 10); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
+func TestWithdrawalIndexStart(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	index, err := NewWithdrawalIndex(db, 3)
+	test.FailIfError(t, err)
+
+	address := common.RandAddress()
+	if _, _, err := index.GetBySender(address, 2, 10, nil, 10); err == nil {
+		t.Error("expected error for sender range before start of index")
+	}
+	if _, _, err := index.GetByDestination(address, 2, 10, nil, 10); err == nil {
+		t.Error("expected error for destination range before start of index")
+	}
+	if _, _, err := index.GetByDestination(address, 3, 10, nil, 10); err != nil {
+		t.Error("unexpected error for range covered by index", err)
+	}
+}
@@ -0,0 +1,457 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+const (
+	// Same limits geth applies to HTTP and websocket requests
+	maxRequestContentLength = 1024 * 1024 * 5
+	wsMessageSizeLimit      = 15 * 1024 * 1024
+
+	// Number of callers whose rate limit state is remembered
+	maxTrackedCallers = 100000
+
+	methodNotAllowedCode = -32601
+	rateLimitedCode      = -32005
+)
+
+// Position of the block parameter of methods which read state, used to
+// detect queries against old state
+var blockParamIndex = map[string]int{
+	"eth_call":                1,
+	"eth_estimateGas":         1,
+	"eth_createAccessList":    1,
+	"eth_getBalance":          1,
+	"eth_getCode":             1,
+	"eth_getTransactionCount": 1,
+	"eth_getStorageAt":        2,
+}
+
+type caller struct {
+	// id identifies the caller for rate limiting purposes. It is derived
+	// from the credentials if the caller authenticated, otherwise it is the
+	// caller's IP address.
+	id            string
+	authenticated bool
+}
+
+type jsonrpcCall struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonrpcErrorResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   jsonrpcError    `json:"error"`
+}
+
+type rejection struct {
+	code    int
+	message string
+	status  int
+}
+
+func (r *rejection) response(id json.RawMessage) jsonrpcErrorResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return jsonrpcErrorResponse{
+		Version: "2.0",
+		ID:      id,
+		Error:   jsonrpcError{Code: r.code, Message: r.message},
+	}
+}
+
+// limiterSet keeps a token bucket for each caller, forgetting the least
+// recently seen callers once too many are being tracked
+type limiterSet struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters *lru.Cache
+}
+
+func newLimiterSet(limit float64, burst int) (*limiterSet, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if burst <= 0 {
+		return nil, errors.New("rate limit burst must be positive")
+	}
+	limiters, err := lru.New(maxTrackedCallers)
+	if err != nil {
+		return nil, err
+	}
+	return &limiterSet{limit: rate.Limit(limit), burst: burst, limiters: limiters}, nil
+}
+
+func (l *limiterSet) allow(id string, cost int) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var limiter *rate.Limiter
+	if existing, ok := l.limiters.Get(id); ok {
+		limiter = existing.(*rate.Limiter)
+	} else {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters.Add(id, limiter)
+	}
+	return limiter.AllowN(time.Now(), cost)
+}
+
+// AccessControl authenticates JSON-RPC callers, restricts the methods they
+// can call and rate limits them according to the cost of their calls.
+// Authenticated callers are limited per key and anonymous callers per IP.
+type AccessControl struct {
+	apiKeys           map[string]bool
+	jwtSecret         []byte
+	allowAnonymous    bool
+	allow             []string
+	deny              []string
+	anonymousDeny     []string
+	costs             map[string]int
+	archiveCost       int
+	archiveDepth      uint64
+	trustForwardedFor bool
+	perKey            *limiterSet
+	perIP             *limiterSet
+	latestBlock       func() (uint64, error)
+}
+
+// NewAccessControl creates the access control described by config, or
+// returns nil if config doesn't restrict access at all. latestBlock is used
+// to decide whether a state query should be charged the archive cost.
+func NewAccessControl(config configuration.RPC, latestBlock func() (uint64, error)) (*AccessControl, error) {
+	auth := config.Auth
+	methods := config.Methods
+	limits := config.RateLimit
+	if len(auth.APIKeys) == 0 && auth.JWTSecret == "" && auth.AllowAnonymous &&
+		len(methods.Allow) == 0 && len(methods.Deny) == 0 && len(methods.AnonymousDeny) == 0 &&
+		limits.PerKey <= 0 && limits.PerIP <= 0 {
+		return nil, nil
+	}
+
+	costs := make(map[string]int)
+	maxCost := 1
+	for _, entry := range limits.MethodCosts {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid method cost %v, expected method=cost", entry)
+		}
+		cost, err := strconv.Atoi(parts[1])
+		if err != nil || cost <= 0 {
+			return nil, errors.Errorf("invalid cost in method cost %v", entry)
+		}
+		costs[parts[0]] = cost
+		if cost > maxCost {
+			maxCost = cost
+		}
+	}
+	if limits.ArchiveCost > maxCost {
+		maxCost = limits.ArchiveCost
+	}
+
+	perKey, err := newLimiterSet(limits.PerKey, limits.PerKeyBurst)
+	if err != nil {
+		return nil, err
+	}
+	perIP, err := newLimiterSet(limits.PerIP, limits.PerIPBurst)
+	if err != nil {
+		return nil, err
+	}
+	// A call costing more than the burst size could never be made
+	if (perKey != nil && maxCost > perKey.burst) || (perIP != nil && maxCost > perIP.burst) {
+		return nil, errors.Errorf("rate limit burst must be at least the largest method cost %v", maxCost)
+	}
+
+	apiKeys := make(map[string]bool)
+	for _, key := range auth.APIKeys {
+		apiKeys[key] = true
+	}
+	var jwtSecret []byte
+	if auth.JWTSecret != "" {
+		jwtSecret = []byte(auth.JWTSecret)
+	}
+
+	return &AccessControl{
+		apiKeys:           apiKeys,
+		jwtSecret:         jwtSecret,
+		allowAnonymous:    auth.AllowAnonymous,
+		allow:             methods.Allow,
+		deny:              methods.Deny,
+		anonymousDeny:     methods.AnonymousDeny,
+		costs:             costs,
+		archiveCost:       limits.ArchiveCost,
+		archiveDepth:      limits.ArchiveDepth,
+		trustForwardedFor: limits.TrustForwardedFor,
+		perKey:            perKey,
+		perIP:             perIP,
+		latestBlock:       latestBlock,
+	}, nil
+}
+
+// remoteIP returns the address used for per IP rate limiting. Behind a
+// trusted proxy that's the rightmost X-Forwarded-For entry, which the proxy
+// appended itself; anything to the left of it came from the client.
+func (a *AccessControl) remoteIP(r *http.Request) string {
+	if a.trustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if forwarded := strings.TrimSpace(entries[len(entries)-1]); forwarded != "" {
+				return forwarded
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// authenticate identifies the caller from the bearer token in the
+// Authorization header or the apikey query parameter. Invalid credentials
+// are rejected rather than treated as anonymous.
+func (a *AccessControl) authenticate(r *http.Request) (caller, error) {
+	token := r.URL.Query().Get("apikey")
+	if header := r.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return caller{}, errors.New("unsupported authorization scheme")
+		}
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	if token == "" {
+		if !a.allowAnonymous {
+			return caller{}, errors.New("authentication required")
+		}
+		return caller{id: "ip:" + a.remoteIP(r)}, nil
+	}
+	if a.apiKeys[token] {
+		return caller{id: "key:" + token, authenticated: true}, nil
+	}
+	if a.jwtSecret != nil && strings.Count(token, ".") == 2 {
+		parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.Errorf("unexpected signing method %v", t.Header["alg"])
+			}
+			return a.jwtSecret, nil
+		})
+		if err != nil {
+			return caller{}, errors.Wrap(err, "invalid token")
+		}
+		subject := ""
+		if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
+			subject, _ = claims["sub"].(string)
+		}
+		return caller{id: "jwt:" + subject, authenticated: true}, nil
+	}
+	return caller{}, errors.New("invalid API key")
+}
+
+func methodMatches(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if pattern == method {
+			return true
+		}
+		if strings.HasSuffix(pattern, "_*") && strings.HasPrefix(method, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AccessControl) methodAllowed(c caller, method string) bool {
+	if len(a.allow) > 0 && !methodMatches(a.allow, method) {
+		return false
+	}
+	if methodMatches(a.deny, method) {
+		return false
+	}
+	return c.authenticated || !methodMatches(a.anonymousDeny, method)
+}
+
+// isArchiveQuery returns true if the call reads state from a block more than
+// archiveDepth blocks old. Queries by block hash are always treated as
+// archive queries since their height isn't known without a lookup.
+func (a *AccessControl) isArchiveQuery(call jsonrpcCall) bool {
+	index, ok := blockParamIndex[call.Method]
+	if !ok || a.latestBlock == nil {
+		return false
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(call.Params, &params); err != nil || len(params) <= index {
+		return false
+	}
+	var blockNum rpc.BlockNumberOrHash
+	if err := json.Unmarshal(params[index], &blockNum); err != nil {
+		return false
+	}
+	if _, ok := blockNum.Hash(); ok {
+		return true
+	}
+	number, ok := blockNum.Number()
+	if !ok || number < 0 {
+		// latest or pending
+		return false
+	}
+	latest, err := a.latestBlock()
+	if err != nil {
+		return false
+	}
+	return uint64(number)+a.archiveDepth < latest
+}
+
+func (a *AccessControl) cost(call jsonrpcCall) int {
+	if a.archiveCost > 0 && a.isArchiveQuery(call) {
+		return a.archiveCost
+	}
+	if cost, ok := a.costs[call.Method]; ok {
+		return cost
+	}
+	if i := strings.Index(call.Method, "_"); i >= 0 {
+		if cost, ok := a.costs[call.Method[:i]+"_*"]; ok {
+			return cost
+		}
+	}
+	return 1
+}
+
+// check decides whether the caller may make the given calls, charging
+// their total cost to the caller's rate limit if so
+func (a *AccessControl) check(c caller, calls []jsonrpcCall) *rejection {
+	methods := make([]string, 0, len(calls))
+	total := 0
+	for _, call := range calls {
+		methods = append(methods, call.Method)
+		total += a.cost(call)
+	}
+	return a.admit(c, methods, total)
+}
+
+// admit decides whether the caller may call all of the given methods,
+// charging total to the caller's rate limit if so
+func (a *AccessControl) admit(c caller, methods []string, total int) *rejection {
+	for _, method := range methods {
+		if !a.methodAllowed(c, method) {
+			return &rejection{
+				code:    methodNotAllowedCode,
+				message: "method " + method + " is not allowed",
+				status:  http.StatusForbidden,
+			}
+		}
+	}
+	limiter := a.perIP
+	if c.authenticated {
+		limiter = a.perKey
+	}
+	if !limiter.allow(c.id, total) {
+		return &rejection{
+			code:    rateLimitedCode,
+			message: "rate limit exceeded",
+			status:  http.StatusTooManyRequests,
+		}
+	}
+	return nil
+}
+
+// parseCalls parses a single JSON-RPC call or a batch of them
+func parseCalls(body []byte) ([]jsonrpcCall, json.RawMessage, error) {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []jsonrpcCall
+		if err := json.Unmarshal(trimmed, &calls); err != nil {
+			return nil, nil, err
+		}
+		return calls, nil, nil
+	}
+	var call jsonrpcCall
+	if err := json.Unmarshal(trimmed, &call); err != nil {
+		return nil, nil, err
+	}
+	return []jsonrpcCall{call}, call.ID, nil
+}
+
+func writeRejection(w http.ResponseWriter, rej *rejection, id json.RawMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rej.status)
+	if err := json.NewEncoder(w).Encode(rej.response(id)); err != nil {
+		logger.Warn().Err(err).Msg("error writing rejection")
+	}
+}
+
+// HTTPHandler applies access control to JSON-RPC requests over HTTP before
+// passing them on to next. A batch is rejected as a whole if any of its
+// calls is disallowed.
+func (a *AccessControl) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestContentLength))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		calls, id, err := parseCalls(body)
+		if err != nil {
+			// Let the server produce the usual parse error
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rej := a.check(c, calls); rej != nil {
+			writeRejection(w, rej, id)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
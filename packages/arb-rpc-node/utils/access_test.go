@@ -0,0 +1,148 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func accessTestRequest(t *testing.T, handler http.Handler, token string, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.RemoteAddr = "10.0.0.1:1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAccessControl(t *testing.T) {
+	config := configuration.RPC{
+		Auth: configuration.RPCAuth{
+			APIKeys:        []string{"key1"},
+			JWTSecret:      "secret",
+			AllowAnonymous: true,
+		},
+		Methods: configuration.RPCMethods{
+			Deny:          []string{"debug_*"},
+			AnonymousDeny: []string{"arbtrace_*"},
+		},
+		RateLimit: configuration.RPCRateLimit{
+			PerIP:       1,
+			PerIPBurst:  5,
+			MethodCosts: []string{"eth_getLogs=5"},
+		},
+	}
+	access, err := NewAccessControl(config, nil)
+	test.FailIfError(t, err)
+	handler := access.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(method string) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`
+	}
+
+	if code := accessTestRequest(t, handler, "badkey", call("eth_chainId")); code != http.StatusUnauthorized {
+		t.Error("expected invalid key to be rejected, got", code)
+	}
+	if code := accessTestRequest(t, handler, "key1", call("debug_traceTransaction")); code != http.StatusForbidden {
+		t.Error("expected denied method to be rejected, got", code)
+	}
+	if code := accessTestRequest(t, handler, "key1", call("arbtrace_filter")); code != http.StatusOK {
+		t.Error("expected authenticated trace call to succeed, got", code)
+	}
+	if code := accessTestRequest(t, handler, "", call("arbtrace_filter")); code != http.StatusForbidden {
+		t.Error("expected anonymous trace call to be rejected, got", code)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	test.FailIfError(t, err)
+	if code := accessTestRequest(t, handler, token, call("arbtrace_filter")); code != http.StatusOK {
+		t.Error("expected call with valid JWT to succeed, got", code)
+	}
+
+	// Anonymous callers share a burst of 5 per IP, and eth_getLogs costs 5
+	if code := accessTestRequest(t, handler, "", call("eth_getLogs")); code != http.StatusOK {
+		t.Error("expected first anonymous call to succeed, got", code)
+	}
+	if code := accessTestRequest(t, handler, "", call("eth_chainId")); code != http.StatusTooManyRequests {
+		t.Error("expected anonymous caller to be rate limited, got", code)
+	}
+	// Authenticated callers aren't subject to the per IP limit
+	if code := accessTestRequest(t, handler, "key1", call("eth_chainId")); code != http.StatusOK {
+		t.Error("expected authenticated call to succeed, got", code)
+	}
+}
+
+func TestAccessControlForwardedFor(t *testing.T) {
+	config := configuration.RPC{
+		Auth: configuration.RPCAuth{AllowAnonymous: true},
+		RateLimit: configuration.RPCRateLimit{
+			PerIP:             1,
+			PerIPBurst:        1,
+			TrustForwardedFor: true,
+		},
+	}
+	access, err := NewAccessControl(config, nil)
+	test.FailIfError(t, err)
+	handler := access.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The proxy appends the real client address after whatever the client sent
+	if code := call("1.1.1.1, 192.0.2.1"); code != http.StatusOK {
+		t.Error("expected first call to succeed, got", code)
+	}
+	if code := call("2.2.2.2, 192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Error("expected forged X-Forwarded-For entry to be ignored, got", code)
+	}
+	if code := call("1.1.1.1, 192.0.2.2"); code != http.StatusOK {
+		t.Error("expected call from another client to succeed, got", code)
+	}
+}
+
+func TestAccessControlDisabled(t *testing.T) {
+	access, err := NewAccessControl(configuration.RPC{Auth: configuration.RPCAuth{AllowAnonymous: true}}, nil)
+	test.FailIfError(t, err)
+	if access != nil {
+		t.Error("expected no access control without any restrictions configured")
+	}
+}
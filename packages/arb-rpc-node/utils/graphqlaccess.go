@@ -0,0 +1,351 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Pseudo-method every GraphQL request is checked and charged as, so that
+// GraphQL can be denied or priced as a whole
+const graphqlMethod = "graphql"
+
+// Most tokens a query may have once its fragment spreads are expanded.
+// Fragments which spread each other several times grow exponentially, so
+// the expansion has to be bounded.
+const maxGraphqlTokens = 100000
+
+// Error code for queries which are rejected before they are charged
+const invalidRequestCode = -32600
+
+// GraphQL fields which do the work of a JSON-RPC method. Each occurrence of
+// one of these fields in a query is checked and charged as a call to that
+// method.
+var graphqlFieldMethods = map[string]string{
+	"block":              "eth_getBlockByNumber",
+	"blocks":             "eth_getBlockByNumber",
+	"transaction":        "eth_getTransactionByHash",
+	"logs":               "eth_getLogs",
+	"balance":            "eth_getBalance",
+	"transactionCount":   "eth_getTransactionCount",
+	"code":               "eth_getCode",
+	"storage":            "eth_getStorageAt",
+	"call":               "eth_call",
+	"estimateGas":        "eth_estimateGas",
+	"sendRawTransaction": "eth_sendRawTransaction",
+}
+
+type graphqlTokenKind int
+
+const (
+	graphqlName graphqlTokenKind = iota
+	graphqlNumber
+	graphqlString
+	graphqlPunctuator
+)
+
+type graphqlToken struct {
+	kind graphqlTokenKind
+	text string
+}
+
+func isGraphqlNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isGraphqlNameChar(c byte) bool {
+	return isGraphqlNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// tokenizeGraphql splits a GraphQL document into tokens, dropping
+// whitespace, commas and comments. It is only precise enough to find field
+// and argument names since the server parses the document properly.
+func tokenizeGraphql(query string) []graphqlToken {
+	var tokens []graphqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			start := i
+			if len(query) >= i+3 && query[i:i+3] == `"""` {
+				end := strings.Index(query[i+3:], `"""`)
+				if end < 0 {
+					i = len(query)
+				} else {
+					i += 3 + end + 3
+				}
+			} else {
+				i++
+				for i < len(query) && query[i] != '"' && query[i] != '\n' {
+					if query[i] == '\\' {
+						i++
+					}
+					i++
+				}
+				i++
+			}
+			if i > len(query) {
+				i = len(query)
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlString, text: query[start:i]})
+		case isGraphqlNameStart(c):
+			start := i
+			for i < len(query) && isGraphqlNameChar(query[i]) {
+				i++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlName, text: query[start:i]})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(query) && (isGraphqlNameChar(query[i]) || query[i] == '.' || query[i] == '+' || query[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlNumber, text: query[start:i]})
+		case c == '.' && len(query) >= i+3 && query[i:i+3] == "...":
+			tokens = append(tokens, graphqlToken{kind: graphqlPunctuator, text: "..."})
+			i += 3
+		default:
+			tokens = append(tokens, graphqlToken{kind: graphqlPunctuator, text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isGraphqlPunctuator(tokens []graphqlToken, i int, text string) bool {
+	return i >= 0 && i < len(tokens) && tokens[i].kind == graphqlPunctuator && tokens[i].text == text
+}
+
+// expandGraphqlFragments replaces each fragment spread in tokens with the
+// selection set of the fragment it names and drops the fragment definitions,
+// so that the fields of a fragment are charged each time it is used. Spreads
+// of unknown fragments and cyclic spreads are dropped since the server
+// rejects those queries anyway.
+func expandGraphqlFragments(tokens []graphqlToken) ([]graphqlToken, error) {
+	fragments := make(map[string][]graphqlToken)
+	var operations []graphqlToken
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		if depth == 0 && tokens[i].kind == graphqlName && tokens[i].text == "fragment" &&
+			!isGraphqlPunctuator(tokens, i-1, "$") && i+1 < len(tokens) && tokens[i+1].kind == graphqlName {
+			name := tokens[i+1].text
+			start := i + 2
+			for start < len(tokens) && !isGraphqlPunctuator(tokens, start, "{") {
+				start++
+			}
+			end := start
+			for fragmentDepth := 0; end < len(tokens); end++ {
+				if isGraphqlPunctuator(tokens, end, "{") {
+					fragmentDepth++
+				} else if isGraphqlPunctuator(tokens, end, "}") {
+					fragmentDepth--
+					if fragmentDepth == 0 {
+						end++
+						break
+					}
+				}
+			}
+			fragments[name] = tokens[start:end]
+			i = end - 1
+			continue
+		}
+		if isGraphqlPunctuator(tokens, i, "{") {
+			depth++
+		} else if isGraphqlPunctuator(tokens, i, "}") {
+			depth--
+		}
+		operations = append(operations, tokens[i])
+	}
+
+	expanded := make([]graphqlToken, 0, len(operations))
+	active := make(map[string]bool)
+	var expand func(tokens []graphqlToken) error
+	expand = func(tokens []graphqlToken) error {
+		for i := 0; i < len(tokens); i++ {
+			if isGraphqlPunctuator(tokens, i, "...") && i+1 < len(tokens) &&
+				tokens[i+1].kind == graphqlName && tokens[i+1].text != "on" {
+				name := tokens[i+1].text
+				i++
+				fragment, ok := fragments[name]
+				if !ok || active[name] {
+					continue
+				}
+				active[name] = true
+				if err := expand(fragment); err != nil {
+					return err
+				}
+				delete(active, name)
+				continue
+			}
+			if len(expanded) >= maxGraphqlTokens {
+				return errors.Errorf("query is longer than %v tokens with its fragments expanded", maxGraphqlTokens)
+			}
+			expanded = append(expanded, tokens[i])
+		}
+		return nil
+	}
+	if err := expand(operations); err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// isOldBlock returns true if a block argument selects state more than
+// archiveDepth blocks old. Anything other than a literal block number, such
+// as a hash or a variable, is treated as old since its height isn't known
+// without resolving it.
+func (a *AccessControl) isOldBlock(value graphqlToken) bool {
+	if value.kind != graphqlNumber {
+		return true
+	}
+	number, err := strconv.ParseUint(value.text, 10, 64)
+	if err != nil {
+		return true
+	}
+	latest, err := a.latestBlock()
+	if err != nil {
+		return false
+	}
+	return number+a.archiveDepth < latest
+}
+
+// graphqlCalls returns the methods a GraphQL query does the work of along
+// with their total cost. Fragment spreads are expanded first, so a field is
+// charged once for each place it appears in the expanded query. It is still
+// charged once however many objects it is resolved for, so list fields
+// should be priced with that in mind.
+func (a *AccessControl) graphqlCalls(query string) ([]string, int, error) {
+	tokens, err := expandGraphqlFragments(tokenizeGraphql(query))
+	if err != nil {
+		return nil, 0, err
+	}
+	methods := []string{graphqlMethod}
+	var stateMethods []string
+	total := a.cost(jsonrpcCall{Method: graphqlMethod})
+	oldState := false
+	depth := 0
+	field := ""
+	for i, token := range tokens {
+		switch {
+		case isGraphqlPunctuator(tokens, i, "("):
+			depth++
+		case isGraphqlPunctuator(tokens, i, ")"):
+			depth--
+		case token.kind != graphqlName || isGraphqlPunctuator(tokens, i-1, "$"):
+			// Neither a field nor an argument name
+		case depth == 0:
+			if isGraphqlPunctuator(tokens, i+1, ":") {
+				// An alias for the following field
+				continue
+			}
+			field = token.text
+			method, ok := graphqlFieldMethods[field]
+			if !ok {
+				continue
+			}
+			methods = append(methods, method)
+			if _, ok := blockParamIndex[method]; ok {
+				stateMethods = append(stateMethods, method)
+			} else {
+				total += a.cost(jsonrpcCall{Method: method})
+			}
+		case isGraphqlPunctuator(tokens, i+1, ":") && i+2 < len(tokens):
+			// An argument of the current field
+			if a.archiveCost > 0 && a.latestBlock != nil &&
+				(field == "block" || field == "blocks" || token.text == "block") &&
+				a.isOldBlock(tokens[i+2]) {
+				oldState = true
+			}
+		}
+	}
+	for _, method := range stateMethods {
+		if oldState {
+			total += a.archiveCost
+		} else {
+			total += a.cost(jsonrpcCall{Method: method})
+		}
+	}
+	return methods, total, nil
+}
+
+func writeGraphqlRejection(w http.ResponseWriter, rej *rejection) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rej.status)
+	response := struct {
+		Errors []jsonrpcError `json:"errors"`
+	}{Errors: []jsonrpcError{{Code: rej.code, Message: rej.message}}}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Warn().Err(err).Msg("error writing rejection")
+	}
+}
+
+// GraphQLHandler applies access control to GraphQL requests before passing
+// them on to next. Requests are checked and charged for the JSON-RPC methods
+// whose work their fields do, and state read from old blocks is charged the
+// archive cost.
+func (a *AccessControl) GraphQLHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var params struct {
+			Query string `json:"query"`
+		}
+		if r.Method == http.MethodGet {
+			params.Query = r.URL.Query().Get("query")
+		} else {
+			body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestContentLength))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			// An invalid body is still charged as a request so that the
+			// server can produce the usual error
+			_ = json.Unmarshal(body, &params)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		methods, total, err := a.graphqlCalls(params.Query)
+		if err != nil {
+			writeGraphqlRejection(w, &rejection{
+				code:    invalidRequestCode,
+				message: err.Error(),
+				status:  http.StatusBadRequest,
+			})
+			return
+		}
+		if rej := a.admit(c, methods, total); rej != nil {
+			writeGraphqlRejection(w, rej)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestGraphQLCalls(t *testing.T) {
+	config := configuration.RPC{
+		Auth: configuration.RPCAuth{AllowAnonymous: true},
+		RateLimit: configuration.RPCRateLimit{
+			PerIP:        1,
+			PerIPBurst:   100,
+			MethodCosts:  []string{"eth_getLogs=5", "eth_call=3"},
+			ArchiveCost:  20,
+			ArchiveDepth: 10,
+		},
+	}
+	access, err := NewAccessControl(config, func() (uint64, error) { return 100, nil })
+	test.FailIfError(t, err)
+
+	testCases := []struct {
+		query string
+		cost  int
+	}{
+		{`{ block { number } }`, 2},
+		{`{ block { logs(filter: {}) { data } } }`, 7},
+		{`{ recent: block(number: 95) { account(address: "0x00") { balance } call(data: {}) { data } } }`, 6},
+		{`{ block(number: 50) { account(address: "0x00") { balance } } }`, 22},
+		{`query($hash: Bytes32) { block(hash: $hash) { account(address: "0x00") { code } } }`, 22},
+		{`{ logs(filter: { fromBlock: 1 }) { account(block: 2) { storage(slot: "0x00") } } }`, 26},
+		{`# call estimateGas
+{ block { hash extraData } "call" }`, 2},
+		{`fragment F on Block { account(address: "0x00") { balance } } { a: block { ...F } b: block { ...F } }`, 5},
+		{`{ block { ...A ...A } } fragment A on Block { ...B ...B } fragment B on Block { account(address: "0x00") { code } }`, 6},
+		{`{ block { ... on Block { number } ...Missing } }`, 2},
+		{`{ block { ...A } } fragment A on Block { ...A }`, 2},
+	}
+	for _, tc := range testCases {
+		_, cost, err := access.graphqlCalls(tc.query)
+		test.FailIfError(t, err)
+		if cost != tc.cost {
+			t.Errorf("expected cost %v for %v, got %v", tc.cost, tc.query, cost)
+		}
+	}
+
+	// Each fragment doubles the size of the query it expands to
+	query := `{ block { ...F29 } } fragment F0 on Block { number }`
+	for i := 1; i < 30; i++ {
+		query += fmt.Sprintf(" fragment F%v on Block { ...F%v ...F%v }", i, i-1, i-1)
+	}
+	if _, _, err := access.graphqlCalls(query); err == nil {
+		t.Error("expected exponentially expanding query to be rejected")
+	}
+}
+
+func TestGraphQLAccessControl(t *testing.T) {
+	config := configuration.RPC{
+		Auth: configuration.RPCAuth{
+			APIKeys:        []string{"key1"},
+			AllowAnonymous: true,
+		},
+		Methods: configuration.RPCMethods{
+			AnonymousDeny: []string{"eth_sendRawTransaction"},
+		},
+		RateLimit: configuration.RPCRateLimit{
+			PerIP:       1,
+			PerIPBurst:  5,
+			MethodCosts: []string{"eth_getLogs=4"},
+		},
+	}
+	access, err := NewAccessControl(config, nil)
+	test.FailIfError(t, err)
+	handler := access.GraphQLHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(query string) string {
+		data, err := json.Marshal(map[string]string{"query": query})
+		test.FailIfError(t, err)
+		return string(data)
+	}
+
+	if code := accessTestRequest(t, handler, "badkey", request(`{ block { number } }`)); code != http.StatusUnauthorized {
+		t.Error("expected invalid key to be rejected, got", code)
+	}
+	mutation := request(`mutation { sendRawTransaction(data: "0x00") }`)
+	if code := accessTestRequest(t, handler, "", mutation); code != http.StatusForbidden {
+		t.Error("expected anonymous mutation to be rejected, got", code)
+	}
+	if code := accessTestRequest(t, handler, "key1", mutation); code != http.StatusOK {
+		t.Error("expected authenticated mutation to succeed, got", code)
+	}
+
+	// The request and its logs field cost 5, using the whole burst
+	if code := accessTestRequest(t, handler, "", request(`{ logs(filter: {}) { data } }`)); code != http.StatusOK {
+		t.Error("expected first anonymous query to succeed, got", code)
+	}
+	if code := accessTestRequest(t, handler, "", request(`{ gasPrice }`)); code != http.StatusTooManyRequests {
+		t.Error("expected anonymous caller to be rate limited, got", code)
+	}
+}
@@ -53,20 +53,34 @@ func LaunchRPC(ctx context.Context, handler http.Handler, addr, port, path strin
 	return launchServer(ctx, r, addr, port, "rpc")
 }
 
-func LaunchWS(ctx context.Context, server *rpc.Server, addr, port, path string) error {
+// RPCHandler returns the handler for JSON-RPC over HTTP, applying access
+// control and recording metrics if either is enabled. Requests rejected by
+// access control aren't recorded.
+func RPCHandler(server *rpc.Server, access *AccessControl, rpcMetrics *RPCMetrics) http.Handler {
+	var handler http.Handler = server
+	if rpcMetrics != nil {
+		handler = rpcMetrics.HTTPHandler(handler)
+	}
+	if access != nil {
+		handler = access.HTTPHandler(handler)
+	}
+	return handler
+}
+
+func LaunchWS(ctx context.Context, server *rpc.Server, access *AccessControl, rpcMetrics *RPCMetrics, addr, port, path string) error {
 	r := mux.NewRouter()
 	wsRoutes, err := setupPaths(r, path)
 	if err != nil {
 		return err
 	}
-	wsHandler := server.WebsocketHandler([]string{"*"})
+	wsHandler := newWebsocketHandler(server, access, rpcMetrics)
 	for _, route := range wsRoutes {
 		route.Handler(wsHandler)
 	}
 	return launchServer(ctx, r, addr, port, "websocket")
 }
 
-func LaunchRPCAndWS(ctx context.Context, server *rpc.Server, addr, port, rpcPath, wsPath string) error {
+func LaunchRPCAndWS(ctx context.Context, server *rpc.Server, access *AccessControl, rpcMetrics *RPCMetrics, addr, port, rpcPath, wsPath string) error {
 	r := mux.NewRouter()
 	rpcRoutes, err := setupPaths(r, rpcPath)
 	if err != nil {
@@ -76,10 +90,11 @@ func LaunchRPCAndWS(ctx context.Context, server *rpc.Server, addr, port, rpcPath
 	if err != nil {
 		return err
 	}
+	rpcHandler := RPCHandler(server, access, rpcMetrics)
 	for _, route := range rpcRoutes {
-		route.Handler(server).Methods("GET", "POST", "OPTIONS")
+		route.Handler(rpcHandler).Methods("GET", "POST", "OPTIONS")
 	}
-	wsHandler := server.WebsocketHandler([]string{"*"})
+	wsHandler := newWebsocketHandler(server, access, rpcMetrics)
 	for _, route := range wsRoutes {
 		route.Handler(wsHandler)
 	}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// Params of slow requests are truncated to this many bytes when logged
+	maxLoggedParamsLength = 1024
+
+	// Longest method name given its own metrics
+	maxMethodNameLength = 64
+
+	unknownMethod = "unknown"
+)
+
+// RPCMetrics records request counts, error counts and latencies for each
+// JSON-RPC method, and logs requests which take longer than a threshold.
+// Calls in an HTTP batch are each attributed the duration of the whole batch.
+type RPCMetrics struct {
+	registry      metrics.Registry
+	slowThreshold time.Duration
+}
+
+// NewRPCMetrics returns nil if metrics are disabled and slow request logging
+// is turned off, since there would be nothing to record
+func NewRPCMetrics(registry metrics.Registry, slowThreshold time.Duration) *RPCMetrics {
+	if !metrics.Enabled && slowThreshold <= 0 {
+		return nil
+	}
+	return &RPCMetrics{
+		registry:      registry,
+		slowThreshold: slowThreshold,
+	}
+}
+
+type jsonrpcResponse struct {
+	ID    json.RawMessage `json:"id"`
+	Error *jsonrpcError   `json:"error,omitempty"`
+}
+
+// parseResponses parses a single JSON-RPC response or a batch of them
+func parseResponses(body []byte) ([]jsonrpcResponse, error) {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var responses []jsonrpcResponse
+		if err := json.Unmarshal(trimmed, &responses); err != nil {
+			return nil, err
+		}
+		return responses, nil
+	}
+	var response jsonrpcResponse
+	if err := json.Unmarshal(trimmed, &response); err != nil {
+		return nil, err
+	}
+	return []jsonrpcResponse{response}, nil
+}
+
+// metricName returns the name under which a method's metrics are recorded.
+// Method names come from callers, so names the server doesn't know are
+// grouped together to keep the number of metrics bounded.
+func metricName(method string, errCode int) string {
+	if errCode == methodNotAllowedCode || len(method) == 0 || len(method) > maxMethodNameLength {
+		return unknownMethod
+	}
+	for _, c := range method {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !isDigit && c != '_' {
+			return unknownMethod
+		}
+	}
+	return method
+}
+
+func truncateParams(params json.RawMessage) string {
+	if len(params) <= maxLoggedParamsLength {
+		return string(params)
+	}
+	return string(params[:maxLoggedParamsLength]) + "..."
+}
+
+func (m *RPCMetrics) record(call jsonrpcCall, rpcErr *jsonrpcError, elapsed time.Duration) {
+	errCode := 0
+	if rpcErr != nil {
+		errCode = rpcErr.Code
+	}
+	prefix := "arbitrum/rpc/" + metricName(call.Method, errCode)
+	metrics.GetOrRegisterCounter(prefix+"/requests", m.registry).Inc(1)
+	if rpcErr != nil {
+		metrics.GetOrRegisterCounter(prefix+"/errors", m.registry).Inc(1)
+	}
+	metrics.GetOrRegisterTimer(prefix+"/duration", m.registry).Update(elapsed)
+
+	if m.slowThreshold > 0 && elapsed >= m.slowThreshold {
+		event := logger.Warn().
+			Str("method", call.Method).
+			Dur("elapsed", elapsed).
+			Str("params", truncateParams(call.Params))
+		if rpcErr != nil {
+			event = event.Int("code", rpcErr.Code).Str("error", rpcErr.Message)
+		}
+		event.Msg("slow rpc request")
+	}
+}
+
+// recordResponses matches responses to the calls which produced them by id
+func (m *RPCMetrics) recordResponses(calls []jsonrpcCall, responseBody []byte, elapsed time.Duration) {
+	responses, err := parseResponses(responseBody)
+	if err != nil {
+		responses = nil
+	}
+	failures := make(map[string]*jsonrpcError, len(responses))
+	for _, response := range responses {
+		failures[string(response.ID)] = response.Error
+	}
+	for _, call := range calls {
+		if len(call.ID) == 0 {
+			// Notifications get no response
+			m.record(call, nil, elapsed)
+			continue
+		}
+		m.record(call, failures[string(call.ID)], elapsed)
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// HTTPHandler records metrics for JSON-RPC requests over HTTP served by next
+func (m *RPCMetrics) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestContentLength))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		calls, _, err := parseCalls(body)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &responseRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		m.recordResponses(calls, recorder.body.Bytes(), time.Since(start))
+	})
+}
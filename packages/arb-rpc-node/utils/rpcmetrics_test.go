@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestRPCMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() {
+		metrics.Enabled = enabled
+	}()
+
+	registry := metrics.NewRegistry()
+	rpcMetrics := NewRPCMetrics(registry, time.Hour)
+	handler := rpcMetrics.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"jsonrpc":"2.0","id":1,"result":"0x1"},
+			{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"execution reverted"}},
+			{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"the method foo_bar does not exist/is not available"}}
+		]`))
+	}))
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]},
+		{"jsonrpc":"2.0","id":2,"method":"eth_call","params":[]},
+		{"jsonrpc":"2.0","id":3,"method":"foo_bar","params":[]}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	counts := map[string]int64{
+		"arbitrum/rpc/eth_chainId/requests": 1,
+		"arbitrum/rpc/eth_chainId/errors":   0,
+		"arbitrum/rpc/eth_call/requests":    1,
+		"arbitrum/rpc/eth_call/errors":      1,
+		"arbitrum/rpc/unknown/requests":     1,
+		"arbitrum/rpc/unknown/errors":       1,
+		"arbitrum/rpc/foo_bar/requests":     0,
+	}
+	for name, expected := range counts {
+		var count int64
+		if counter, ok := registry.Get(name).(metrics.Counter); ok {
+			count = counter.Count()
+		}
+		if count != expected {
+			t.Errorf("expected %v to be %v, got %v", name, expected, count)
+		}
+	}
+	if timer, ok := registry.Get("arbitrum/rpc/eth_call/duration").(metrics.Timer); !ok || timer.Count() != 1 {
+		t.Error("expected eth_call duration to be recorded")
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	if name := metricName("eth_getBalance", 0); name != "eth_getBalance" {
+		t.Error("unexpected name", name)
+	}
+	if name := metricName("eth_getBalance\n", 0); name != unknownMethod {
+		t.Error("expected invalid method name to be grouped, got", name)
+	}
+	if name := metricName(strings.Repeat("a", maxMethodNameLength+1), 0); name != unknownMethod {
+		t.Error("expected long method name to be grouped, got", name)
+	}
+}
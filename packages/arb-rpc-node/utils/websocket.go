@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+type pendingCall struct {
+	call  jsonrpcCall
+	start time.Time
+}
+
+// pendingCalls tracks calls received over a websocket connection until the
+// server answers them
+type pendingCalls struct {
+	mu    sync.Mutex
+	calls map[string]pendingCall
+}
+
+func (p *pendingCalls) add(calls []jsonrpcCall) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, call := range calls {
+		if len(call.ID) == 0 {
+			continue
+		}
+		p.calls[string(call.ID)] = pendingCall{call: call, start: now}
+	}
+}
+
+func (p *pendingCalls) remove(id json.RawMessage) (pendingCall, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, ok := p.calls[string(id)]
+	if ok {
+		delete(p.calls, string(id))
+	}
+	return pending, ok
+}
+
+// newWebsocketHandler serves JSON-RPC over websockets, checking every
+// message against access and recording metrics for each call. Callers are
+// authenticated during the handshake, and disallowed messages are answered
+// with an error without reaching the server. If neither is enabled the
+// server's own handler is used.
+func newWebsocketHandler(server *rpc.Server, access *AccessControl, rpcMetrics *RPCMetrics) http.Handler {
+	if access == nil && rpcMetrics == nil {
+		return server.WebsocketHandler([]string{"*"})
+	}
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     func(*http.Request) bool { return true },
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c caller
+		if access != nil {
+			var err error
+			c, err = access.authenticate(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Debug().Err(err).Msg("websocket upgrade failed")
+			return
+		}
+		conn.SetReadLimit(wsMessageSizeLimit)
+
+		pending := &pendingCalls{calls: make(map[string]pendingCall)}
+
+		// The server and rejections both write to the connection
+		var writeMu sync.Mutex
+		encode := func(v interface{}) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if rpcMetrics != nil {
+				if responses, err := parseResponses(data); err == nil {
+					for _, response := range responses {
+						if call, ok := pending.remove(response.ID); ok {
+							rpcMetrics.record(call.call, response.Error, time.Since(call.start))
+						}
+					}
+				}
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return conn.WriteMessage(websocket.TextMessage, data)
+		}
+		decode := func(v interface{}) error {
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return err
+				}
+				calls, id, err := parseCalls(data)
+				if err == nil {
+					if access != nil {
+						if rej := access.check(c, calls); rej != nil {
+							if err := encode(rej.response(id)); err != nil {
+								return err
+							}
+							continue
+						}
+					}
+					if rpcMetrics != nil {
+						pending.add(calls)
+					}
+				}
+				return json.Unmarshal(data, v)
+			}
+		}
+		server.ServeCodec(rpc.NewFuncCodec(conn, encode, decode), 0)
+	})
+}
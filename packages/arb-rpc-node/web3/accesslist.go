@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+)
+
+// precompiles are left out of access lists, as in geth
+var precompiles = func() map[common.Address]bool {
+	ret := make(map[common.Address]bool)
+	for _, address := range vm.PrecompiledAddressesBerlin {
+		ret[address] = true
+	}
+	arbosPrecompiles := []common.Address{
+		arbos.ARB_SYS_ADDRESS,
+		arbos.ARB_INFO_ADDRESS,
+		arbos.ARB_ADDRESS_TABLE_ADDRESS,
+		arbos.ARB_BLS_ADDRESS,
+		arbos.ARB_FUNCTION_TABLE_ADDRESS,
+		arbos.ARB_TEST_ADDRESS,
+		arbos.ARB_OWNER_ADDRESS,
+		arbos.ARB_GAS_INFO_ADDRESS,
+		arbos.ARB_AGGREGATOR_ADDRESS,
+		arbos.ARB_RETRYABLE_ADDRESS,
+		arbos.ARB_STATISTICS_ADDRESS,
+		arbos.ARB_NODE_INTERFACE_ADDRESS,
+	}
+	for _, address := range arbosPrecompiles {
+		ret[address] = true
+	}
+	return ret
+}()
+
+type AccessListResult struct {
+	AccessList types.AccessList `json:"accessList"`
+	Error      string           `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	// StorageKeysMissing is set since the storage slots the call used aren't
+	// known, so the storageKeys of every entry are left empty
+	StorageKeysMissing bool `json:"storageKeysMissing"`
+}
+
+// CreateAccessList runs the given call with tracing enabled and returns the
+// contracts it touched. Only addresses are returned: ArbOS traces calls and
+// contract creations but not storage accesses, so the storageKeys of every
+// entry are empty and the result is marked with storageKeysMissing rather
+// than passed off as complete. As in geth, the sender, the top level
+// recipient and precompiles are left out of the list.
+func (s *Server) CreateAccessList(ctx context.Context, args CallTxArgs, optBlockNum *rpc.BlockNumberOrHash) (*AccessListResult, error) {
+	blockNum := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if optBlockNum != nil {
+		blockNum = *optBlockNum
+	}
+	snap, err := s.getSnapshotForNumberOrHash(ctx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if snap.ArbosVersion() >= 42 && (args.GasPrice == nil || args.GasPrice.ToInt().Sign() <= 0) {
+		args.GasPrice = (*hexutil.Big)(big.NewInt(1 << 60))
+	}
+
+	from, msg := buildCallMsg(args)
+	res, debugPrints, err := snap.CallWithOverrides(ctx, msg, from, nil, s.maxAVMGas, true)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := extractTrace(debugPrints)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := trace.FrameTree()
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[common.Address]bool{from.ToEthAddress(): true}
+	if args.To != nil {
+		excluded[*args.To] = true
+	}
+	if args.To == nil && res.ResultCode == evm.ReturnCode {
+		if created, ok := res.GetCreatedContractAddress(); ok {
+			excluded[created] = true
+		}
+	}
+	ret := &AccessListResult{
+		AccessList:         accessListFromFrame(frame, excluded),
+		GasUsed:            hexutil.Uint64(res.GasUsed.Uint64()),
+		StorageKeysMissing: true,
+	}
+	if res.ResultCode != evm.ReturnCode {
+		ret.Error = evm.HandleCallError(res, s.ganacheMode).Error()
+	}
+	return ret, nil
+}
+
+// accessListFromFrame lists the addresses called or created within frame in
+// the order they were first touched
+func accessListFromFrame(frame evm.Frame, excluded map[common.Address]bool) types.AccessList {
+	accessList := make(types.AccessList, 0)
+	seen := make(map[common.Address]bool)
+	add := func(address common.Address) {
+		if excluded[address] || seen[address] || isPrecompile(address) {
+			return
+		}
+		seen[address] = true
+		accessList = append(accessList, types.AccessTuple{
+			Address:     address,
+			StorageKeys: []common.Hash{},
+		})
+	}
+
+	var visit func(frame evm.Frame)
+	visit = func(frame evm.Frame) {
+		if frame == nil {
+			return
+		}
+		switch frame := frame.(type) {
+		case *evm.CreateFrame:
+			add(frame.Create.ContractAddress.ToEthAddress())
+		case *evm.Create2Frame:
+			add(frame.Create.ContractAddress.ToEthAddress())
+		}
+		callFrame := frame.GetCallFrame()
+		if callFrame.Call.To != nil {
+			add(callFrame.Call.To.ToEthAddress())
+		}
+		for _, nested := range callFrame.Nested {
+			visit(nested)
+		}
+	}
+	visit(frame)
+	return accessList
+}
+
+func isPrecompile(address common.Address) bool {
+	return precompiles[address]
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func callFrame(to common.Address, nested ...evm.Frame) *evm.CallFrame {
+	return &evm.CallFrame{
+		Call:   &evm.CallTrace{To: &to},
+		Nested: nested,
+	}
+}
+
+func TestAccessListFromFrame(t *testing.T) {
+	sender := common.RandAddress()
+	target := common.RandAddress()
+	inner := common.RandAddress()
+	created := common.RandAddress()
+	lowAddress := common.NewAddressFromEth(ethcommon.HexToAddress("0x20"))
+
+	createFrame := &evm.CreateFrame{
+		Create:    &evm.CreateTrace{ContractAddress: created},
+		CallFrame: &evm.CallFrame{Call: &evm.CallTrace{}},
+	}
+	frame := callFrame(
+		target,
+		callFrame(inner),
+		callFrame(common.NewAddressFromEth(arbos.ARB_SYS_ADDRESS)),
+		callFrame(common.NewAddressFromEth(ethcommon.HexToAddress("0x01"))),
+		callFrame(lowAddress, callFrame(inner)),
+		callFrame(sender),
+		createFrame,
+	)
+	excluded := map[ethcommon.Address]bool{
+		sender.ToEthAddress(): true,
+		target.ToEthAddress(): true,
+	}
+
+	accessList := accessListFromFrame(frame, excluded)
+	expected := []common.Address{inner, lowAddress, created}
+	if len(accessList) != len(expected) {
+		t.Fatalf("expected %v entries but got %v", len(expected), accessList)
+	}
+	for i, address := range expected {
+		if accessList[i].Address != address.ToEthAddress() {
+			t.Errorf("entry %v: expected %v but got %v", i, address, accessList[i].Address)
+		}
+		if accessList[i].StorageKeys == nil || len(accessList[i].StorageKeys) != 0 {
+			t.Errorf("entry %v: expected empty storage keys", i)
+		}
+	}
+}
+
+func TestIsPrecompile(t *testing.T) {
+	precompile := []ethcommon.Address{
+		ethcommon.HexToAddress("0x01"),
+		ethcommon.HexToAddress("0x09"),
+		arbos.ARB_SYS_ADDRESS,
+		arbos.ARB_RETRYABLE_ADDRESS,
+		arbos.ARB_NODE_INTERFACE_ADDRESS,
+	}
+	for _, address := range precompile {
+		if !isPrecompile(address) {
+			t.Errorf("expected %v to be a precompile", address)
+		}
+	}
+	notPrecompile := []ethcommon.Address{
+		{},
+		ethcommon.HexToAddress("0x20"),
+		ethcommon.HexToAddress("0x6A"),
+		ethcommon.HexToAddress("0xFF"),
+		ethcommon.HexToAddress("0x0164"),
+	}
+	for _, address := range notPrecompile {
+		if isPrecompile(address) {
+			t.Errorf("expected %v not to be a precompile", address)
+		}
+	}
+}
@@ -18,13 +18,24 @@ package web3
 
 import (
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/monitor"
 )
 
+// Maximum number of transactions returned by one call to
+// arb_getTransactionsByAddress
+const maxAddressTransactions = 1000
+
 type Arb struct {
-	srv *aggregator.Server
+	srv    *aggregator.Server
+	outbox *ethbridge.OutboxWatcher
 }
 
 func (a *Arb) GetAggregator() *batcher.AggregatorInfo {
@@ -36,3 +47,57 @@ func (a *Arb) GetAggregator() *batcher.AggregatorInfo {
 	}
 	return &batcher.AggregatorInfo{Address: ret}
 }
+
+// GetTransactionLifecycle returns the times the given transaction reached
+// each stage of processing as observed by this node, or null if it isn't a
+// recent transaction submitted through this node
+func (a *Arb) GetTransactionLifecycle(txHash ethcommon.Hash) *monitor.TransactionLifecycle {
+	return monitor.GlobalMonitor.GetTransactionLifecycle(common.NewHashFromEth(txHash))
+}
+
+// GetTransactionsByAddress returns the transactions sent by, sent to or
+// creating the given address between fromBlock and toBlock inclusive. If
+// more transactions remain than fit in one response, nextCursor is set and
+// can be passed back in to continue from where the response left off.
+func (a *Arb) GetTransactionsByAddress(
+	address ethcommon.Address,
+	fromBlock rpc.BlockNumber,
+	toBlock rpc.BlockNumber,
+	cursor *hexutil.Bytes,
+) (*AddressTransactionsResult, error) {
+	index := a.srv.AddressIndex()
+	if index == nil {
+		return nil, errors.New("address index is not enabled on this node")
+	}
+	from, err := a.srv.BlockNum(&fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.srv.BlockNum(&toBlock)
+	if err != nil {
+		return nil, err
+	}
+	var start []byte
+	if cursor != nil {
+		start = *cursor
+	}
+	entries, next, err := index.GetTransactions(common.NewAddressFromEth(address), from, to, start, maxAddressTransactions)
+	if err != nil {
+		return nil, err
+	}
+	ret := &AddressTransactionsResult{
+		Transactions: make([]*AddressTransactionResult, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		ret.Transactions = append(ret.Transactions, &AddressTransactionResult{
+			BlockNumber:      hexutil.Uint64(entry.BlockNumber),
+			TransactionIndex: hexutil.Uint64(entry.TxIndex),
+			Hash:             entry.RequestId.ToEthHash(),
+		})
+	}
+	if next != nil {
+		nextCursor := hexutil.Bytes(next)
+		ret.NextCursor = &nextCursor
+	}
+	return ret, nil
+}
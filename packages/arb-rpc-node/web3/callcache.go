@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/callcache"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
+	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// cachedCall is everything other than the block which affects a call's result
+type cachedCall struct {
+	Args      CallTxArgs                                   `json:"args"`
+	Overrides *map[common.Address]snapshot.EthCallOverride `json:"overrides"`
+	MaxAVMGas uint64                                       `json:"maxAVMGas"`
+}
+
+// executeCachedCall looks up the result of the call in the call cache before
+// executing it. The block is resolved to its hash first, so the snapshot,
+// which is the expensive part of a call against an old block, is only
+// loaded if the result isn't cached.
+func (s *Server) executeCachedCall(ctx context.Context, callArgs CallTxArgs, blockNum rpc.BlockNumberOrHash, overrides *map[common.Address]snapshot.EthCallOverride) (*evm.TxResult, error) {
+	info, err := s.blockInfoForNumberOrHash(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, errors.New("block not found")
+	}
+	call, err := json.Marshal(cachedCall{
+		Args:      callArgs,
+		Overrides: overrides,
+		MaxAVMGas: s.maxAVMGas,
+	})
+	if err != nil {
+		return nil, err
+	}
+	key := callcache.Key(arbcommon.NewHashFromEth(info.Header.Hash()), call)
+
+	data, err := s.callCache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		var res evm.TxResult
+		decodeErr := gob.NewDecoder(bytes.NewReader(data)).Decode(&res)
+		if decodeErr == nil {
+			return &res, nil
+		}
+		logger.Warn().Err(decodeErr).Hex("key", key.Bytes()).Msg("ignoring invalid call cache entry")
+	}
+
+	snap, err := s.srv.GetSnapshot(ctx, info.Header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errors.Errorf("unsupported block number %v", info.Header.Number.Uint64())
+	}
+	res, err := s.executeCall(ctx, snap, callArgs, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(res); err != nil {
+		logger.Warn().Err(err).Msg("failed to encode call result for cache")
+		return res, nil
+	}
+	if err := s.callCache.Add(ctx, key, buf.Bytes()); err != nil {
+		logger.Warn().Err(err).Msg("failed to add call result to cache")
+	}
+	return res, nil
+}
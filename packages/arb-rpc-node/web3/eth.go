@@ -33,6 +33,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/callcache"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
 	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
@@ -57,6 +58,7 @@ type Server struct {
 	maxAVMGas             uint64
 	aggregator            *arbcommon.Address
 	sequencerInboxWatcher *ethbridge.SequencerInboxWatcher
+	callCache             callcache.Cache
 }
 
 const DefaultMaxAVMGas = 500000000
@@ -86,6 +88,7 @@ func NewServer(
 		maxAVMGas:             maxGas,
 		aggregator:            srv.Aggregator(),
 		sequencerInboxWatcher: sequencerInboxWatcher,
+		callCache:             config.CallCache,
 	}
 }
 
@@ -219,10 +222,30 @@ func (s *Server) Call(ctx context.Context, callArgs CallTxArgs, blockNum rpc.Blo
 		return HandleNodeInterfaceCall(ctx, s, data, blockNum)
 	}
 
+	res, err := s.ExecuteCall(ctx, callArgs, blockNum, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if res.ResultCode != evm.ReturnCode {
+		return nil, evm.HandleCallError(res, s.ganacheMode)
+	}
+	return res.ReturnData, nil
+}
+
+// ExecuteCall runs the given call against the state at blockNum and returns
+// its full result, leaving it to the caller to decide how to handle reverts
+func (s *Server) ExecuteCall(ctx context.Context, callArgs CallTxArgs, blockNum rpc.BlockNumberOrHash, overrides *map[common.Address]snapshot.EthCallOverride) (*evm.TxResult, error) {
+	if s.callCache != nil && (blockNum.BlockNumber == nil || *blockNum.BlockNumber != rpc.PendingBlockNumber) {
+		return s.executeCachedCall(ctx, callArgs, blockNum, overrides)
+	}
 	snap, err := s.getSnapshotForNumberOrHash(ctx, blockNum)
 	if err != nil {
 		return nil, err
 	}
+	return s.executeCall(ctx, snap, callArgs, overrides)
+}
+
+func (s *Server) executeCall(ctx context.Context, snap *snapshot.Snapshot, callArgs CallTxArgs, overrides *map[common.Address]snapshot.EthCallOverride) (*evm.TxResult, error) {
 	if snap.ArbosVersion() >= 42 && (callArgs.GasPrice == nil || callArgs.GasPrice.ToInt().Sign() <= 0) {
 		callArgs.GasPrice = (*hexutil.Big)(big.NewInt(1 << 60))
 	}
@@ -230,13 +253,7 @@ func (s *Server) Call(ctx context.Context, callArgs CallTxArgs, blockNum rpc.Blo
 	from, msg := buildCallMsg(callArgs)
 
 	res, _, err := snap.CallWithOverrides(ctx, msg, from, overrides, s.maxAVMGas, false)
-	if err != nil {
-		return nil, err
-	}
-	if res.ResultCode != evm.ReturnCode {
-		return nil, evm.HandleCallError(res, s.ganacheMode)
-	}
-	return res.ReturnData, nil
+	return res, err
 }
 
 func (s *Server) EstimateGas(ctx context.Context, args CallTxArgs, optBlockNum *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
@@ -386,20 +403,12 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 		return nil, err
 	}
 
-	receipt := res.ToEthReceipt(arbcommon.NewHashFromEth(info.Header.Hash()))
-
 	tx, err := evm.GetTransaction(res)
 	if err != nil {
 		return nil, err
 	}
+	receipt := makeReceiptResult(tx, info.Header.Hash())
 
-	var contractAddress *common.Address
-	emptyAddress := common.Address{}
-	if receipt.ContractAddress != emptyAddress {
-		contractAddress = &receipt.ContractAddress
-	}
-
-	var l1InboxBatchInfo *L1InboxBatchInfo
 	if opts != nil && opts.ReturnL1InboxBatchInfo {
 		if s.sequencerInboxWatcher == nil {
 			return nil, errors.New("RPC L1 lookups disabled")
@@ -429,7 +438,7 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 			blockNum := new(big.Int).SetUint64(rawLog.BlockNumber)
 			confirmations := new(big.Int).Sub(currentBlockHeight, blockNum)
 			if confirmations.Sign() >= 0 {
-				l1InboxBatchInfo = &L1InboxBatchInfo{
+				receipt.L1InboxBatchInfo = &L1InboxBatchInfo{
 					Confirmations: (*hexutil.Big)(confirmations),
 					BlockNumber:   (*hexutil.Big)(blockNum),
 					LogAddress:    rawLog.Address,
@@ -440,6 +449,39 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 		}
 	}
 
+	return receipt, nil
+}
+
+// GetBlockReceipts returns the receipts of every transaction in the given
+// block, loading the block's results only once
+func (s *Server) GetBlockReceipts(blockNum rpc.BlockNumberOrHash) ([]*GetTransactionReceiptResult, error) {
+	info, err := s.blockInfoForNumberOrHash(blockNum)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	_, results, err := s.srv.GetMachineBlockResults(info)
+	if err != nil || results == nil {
+		return nil, err
+	}
+	blockHash := info.Header.Hash()
+	processedTxes := evm.FilterEthTxResults(results)
+	receipts := make([]*GetTransactionReceiptResult, 0, len(processedTxes))
+	for _, tx := range processedTxes {
+		receipts = append(receipts, makeReceiptResult(tx, blockHash))
+	}
+	return receipts, nil
+}
+
+func makeReceiptResult(tx *evm.ProcessedTx, blockHash common.Hash) *GetTransactionReceiptResult {
+	res := tx.Result
+	receipt := res.ToEthReceipt(arbcommon.NewHashFromEth(blockHash))
+
+	var contractAddress *common.Address
+	emptyAddress := common.Address{}
+	if receipt.ContractAddress != emptyAddress {
+		contractAddress = &receipt.ContractAddress
+	}
+
 	return &GetTransactionReceiptResult{
 		TransactionHash:   receipt.TxHash,
 		TransactionIndex:  hexutil.Uint64(receipt.TransactionIndex),
@@ -462,9 +504,8 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 			UnitsUsed: feeSetToFeeSetResult(res.FeeStats.UnitsUsed),
 			Paid:      feeSetToFeeSetResult(res.FeeStats.Paid),
 		},
-		L1BlockNumber:    (*hexutil.Big)(res.IncomingRequest.L1BlockNumber),
-		L1InboxBatchInfo: l1InboxBatchInfo,
-	}, nil
+		L1BlockNumber: (*hexutil.Big)(res.IncomingRequest.L1BlockNumber),
+	}
 }
 
 func feeSetToFeeSetResult(feeset *evm.FeeSet) *FeeSetResult {
@@ -548,6 +589,7 @@ func makeBlockResult(blockLog *evm.BlockInfo, header *types.Header, transactions
 		Timestamp:        (*hexutil.Uint64)(&header.Time),
 		Transactions:     transactions,
 		Uncles:           &uncles,
+		BaseFeePerGas:    (*hexutil.Big)(BlockBaseFee(blockLog)),
 
 		L1BlockNumber: (*hexutil.Big)(blockLog.L1BlockNum),
 	}
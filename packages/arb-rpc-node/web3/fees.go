@@ -0,0 +1,202 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+)
+
+// Maximum number of blocks that can be requested in one eth_feeHistory call
+const maxFeeHistoryBlocks = 1024
+
+// Maximum number of reward percentiles that can be requested at once
+const maxFeeHistoryPercentiles = 100
+
+// BlockBaseFee returns the ArbGas price charged for L2 computation in the
+// given block, which is the closest equivalent of an EIP-1559 base fee
+func BlockBaseFee(blockLog *evm.BlockInfo) *big.Int {
+	if blockLog.GasSummary == nil {
+		return big.NewInt(0)
+	}
+	return blockLog.GasSummary.PricePerArbGasTotal
+}
+
+// MaxPriorityFeePerGas always returns zero since ArbOS doesn't give any
+// priority to transactions offering to pay more than the current price
+func (s *Server) MaxPriorityFeePerGas() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(0))
+}
+
+func (s *Server) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, newestBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	if len(rewardPercentiles) > maxFeeHistoryPercentiles {
+		return nil, errors.Errorf("too many reward percentiles, maximum is %v", maxFeeHistoryPercentiles)
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return nil, errors.Errorf("invalid reward percentile %v", p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return nil, errors.Errorf("reward percentiles must be ascending, got %v after %v", p, rewardPercentiles[i-1])
+		}
+	}
+
+	newest, err := s.srv.BlockNum(&newestBlock)
+	if err != nil {
+		return nil, err
+	}
+	count := uint64(blockCount)
+	if count > maxFeeHistoryBlocks {
+		count = maxFeeHistoryBlocks
+	}
+	if count > newest+1 {
+		count = newest + 1
+	}
+	if count == 0 {
+		return &FeeHistoryResult{OldestBlock: (*hexutil.Big)(big.NewInt(0))}, nil
+	}
+	oldest := newest + 1 - count
+
+	ret := &FeeHistoryResult{
+		OldestBlock:   (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+		BaseFeePerGas: make([]*hexutil.Big, 0, count+1),
+		GasUsedRatio:  make([]float64, 0, count),
+	}
+	if len(rewardPercentiles) > 0 {
+		ret.Reward = make([][]*hexutil.Big, 0, count)
+	}
+	for height := oldest; height <= newest; height++ {
+		info, err := s.srv.BlockInfoByNumber(height)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, errors.Errorf("block %v not found", height)
+		}
+		var blockLog *evm.BlockInfo
+		var results []*evm.TxResult
+		if len(rewardPercentiles) > 0 {
+			blockLog, results, err = s.srv.GetMachineBlockResults(info)
+		} else {
+			blockLog, err = s.srv.BlockLogFromInfo(info)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blockLog == nil {
+			return nil, errors.Errorf("block %v not found", height)
+		}
+
+		baseFee := BlockBaseFee(blockLog)
+		ret.BaseFeePerGas = append(ret.BaseFeePerGas, (*hexutil.Big)(baseFee))
+		gasUsedRatio := 0.0
+		if info.Header.GasLimit > 0 {
+			gasUsedRatio = float64(info.Header.GasUsed) / float64(info.Header.GasLimit)
+		}
+		ret.GasUsedRatio = append(ret.GasUsedRatio, gasUsedRatio)
+		if len(rewardPercentiles) > 0 {
+			ret.Reward = append(ret.Reward, blockRewards(results, baseFee, rewardPercentiles))
+		}
+	}
+
+	nextBaseFee, err := s.nextBaseFee(ctx, newest)
+	if err != nil {
+		return nil, err
+	}
+	ret.BaseFeePerGas = append(ret.BaseFeePerGas, (*hexutil.Big)(nextBaseFee))
+	return ret, nil
+}
+
+// nextBaseFee returns the base fee of the block following the given one,
+// using the current ArbGas price if that block hasn't been produced yet
+func (s *Server) nextBaseFee(ctx context.Context, height uint64) (*big.Int, error) {
+	info, err := s.srv.BlockInfoByNumber(height + 1)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		blockLog, err := s.srv.BlockLogFromInfo(info)
+		if err != nil {
+			return nil, err
+		}
+		if blockLog != nil {
+			return BlockBaseFee(blockLog), nil
+		}
+	}
+	snap, err := s.srv.PendingSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := snap.GetPricesInWei(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prices[5], nil
+}
+
+type txReward struct {
+	gasUsed *big.Int
+	reward  *big.Int
+}
+
+// blockRewards calculates the given percentiles of the amount paid per unit
+// of ArbGas above the base fee, weighted by the gas used by each transaction
+func blockRewards(results []*evm.TxResult, baseFee *big.Int, percentiles []float64) []*hexutil.Big {
+	ret := make([]*hexutil.Big, len(percentiles))
+	rewards := make([]txReward, 0, len(results))
+	totalGasUsed := big.NewInt(0)
+	for _, res := range results {
+		if res.FeeStats == nil || res.FeeStats.Price == nil {
+			continue
+		}
+		reward := new(big.Int).Sub(res.FeeStats.Price.L2Computation, baseFee)
+		if reward.Sign() < 0 {
+			reward.SetInt64(0)
+		}
+		rewards = append(rewards, txReward{gasUsed: res.GasUsed, reward: reward})
+		totalGasUsed.Add(totalGasUsed, res.GasUsed)
+	}
+	if len(rewards) == 0 {
+		for i := range ret {
+			ret[i] = (*hexutil.Big)(big.NewInt(0))
+		}
+		return ret
+	}
+	sort.SliceStable(rewards, func(i, j int) bool {
+		return rewards[i].reward.Cmp(rewards[j].reward) < 0
+	})
+
+	totalGas := new(big.Float).SetInt(totalGasUsed)
+	txIndex := 0
+	sumGasUsed := new(big.Int).Set(rewards[0].gasUsed)
+	for i, p := range percentiles {
+		threshold, _ := new(big.Float).Mul(totalGas, big.NewFloat(p/100)).Int(nil)
+		for sumGasUsed.Cmp(threshold) < 0 && txIndex < len(rewards)-1 {
+			txIndex++
+			sumGasUsed.Add(sumGasUsed, rewards[txIndex].gasUsed)
+		}
+		ret[i] = (*hexutil.Big)(rewards[txIndex].reward)
+	}
+	return ret
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+)
+
+func txResultWithPrice(gasUsed int64, price int64) *evm.TxResult {
+	return &evm.TxResult{
+		GasUsed: big.NewInt(gasUsed),
+		FeeStats: &evm.FeeStats{
+			Price: &evm.FeeSet{
+				L1Transaction: big.NewInt(0),
+				L1Calldata:    big.NewInt(0),
+				L2Storage:     big.NewInt(0),
+				L2Computation: big.NewInt(price),
+			},
+		},
+	}
+}
+
+func TestBlockRewards(t *testing.T) {
+	baseFee := big.NewInt(100)
+	results := []*evm.TxResult{
+		txResultWithPrice(50, 130),
+		txResultWithPrice(10, 90),
+		txResultWithPrice(40, 110),
+	}
+	rewards := blockRewards(results, baseFee, []float64{0, 10, 11, 50, 60, 100})
+	expected := []int64{0, 0, 10, 10, 30, 30}
+	for i, reward := range rewards {
+		if reward.ToInt().Int64() != expected[i] {
+			t.Errorf("percentile %v: expected reward %v but got %v", i, expected[i], reward.ToInt())
+		}
+	}
+
+	empty := blockRewards(nil, baseFee, []float64{25, 75})
+	if len(empty) != 2 || empty[0].ToInt().Sign() != 0 || empty[1].ToInt().Sign() != 0 {
+		t.Error("expected zero rewards for empty block")
+	}
+}
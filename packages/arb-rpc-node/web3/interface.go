@@ -43,10 +43,18 @@ type GetBlockResult struct {
 	Timestamp        *hexutil.Uint64   `json:"timestamp"`
 	Transactions     interface{}       `json:"transactions"`
 	Uncles           *[]hexutil.Bytes  `json:"uncles"`
+	BaseFeePerGas    *hexutil.Big      `json:"baseFeePerGas"`
 
 	L1BlockNumber *hexutil.Big `json:"l1BlockNumber"`
 }
 
+type FeeHistoryResult struct {
+	OldestBlock   *hexutil.Big     `json:"oldestBlock"`
+	Reward        [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio  []float64        `json:"gasUsedRatio"`
+}
+
 type CallTxArgs struct {
 	From       *common.Address `json:"from"`
 	To         *common.Address `json:"to"`
@@ -130,3 +138,59 @@ type TransactionResult struct {
 	ArbSubType      *hexutil.Uint64 `json:"arbSubType"`
 	L1BlockNumber   *hexutil.Big    `json:"l1BlockNumber"`
 }
+
+type AddressTransactionResult struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Hash             common.Hash    `json:"hash"`
+}
+
+type AddressTransactionsResult struct {
+	Transactions []*AddressTransactionResult `json:"transactions"`
+	NextCursor   *hexutil.Bytes              `json:"nextCursor"`
+}
+
+type RetryableRedeemAttemptResult struct {
+	TxHash      common.Hash    `json:"txHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	AutoRedeem  bool           `json:"autoRedeem"`
+	Successful  bool           `json:"successful"`
+}
+
+type RetryableTicketResult struct {
+	TicketId            common.Hash                     `json:"ticketId"`
+	Status              string                          `json:"status"`
+	CreationTxHash      common.Hash                     `json:"creationTxHash"`
+	CreationBlockNumber hexutil.Uint64                  `json:"creationBlockNumber"`
+	RedeemAttempts      []*RetryableRedeemAttemptResult `json:"redeemAttempts"`
+	RedeemedTxHash      *common.Hash                    `json:"redeemedTxHash"`
+	CancelTxHash        *common.Hash                    `json:"cancelTxHash"`
+	Timeout             *hexutil.Big                    `json:"timeout"`
+}
+
+type L2ToL1ProofResult struct {
+	Nodes []common.Hash `json:"nodes"`
+	Path  *hexutil.Big  `json:"path"`
+}
+
+type WithdrawalResult struct {
+	TxHash        common.Hash        `json:"txHash"`
+	BlockNumber   hexutil.Uint64     `json:"blockNumber"`
+	UniqueId      *hexutil.Big       `json:"uniqueId"`
+	L2Sender      common.Address     `json:"l2Sender"`
+	L1Destination common.Address     `json:"l1Destination"`
+	BatchNumber   *hexutil.Big       `json:"batchNumber"`
+	IndexInBatch  hexutil.Uint64     `json:"indexInBatch"`
+	ArbBlockNum   *hexutil.Big       `json:"arbBlockNum"`
+	EthBlockNum   *hexutil.Big       `json:"ethBlockNum"`
+	Timestamp     *hexutil.Big       `json:"timestamp"`
+	Value         *hexutil.Big       `json:"value"`
+	Data          hexutil.Bytes      `json:"data"`
+	Confirmed     *bool              `json:"confirmed"`
+	Proof         *L2ToL1ProofResult `json:"proof"`
+}
+
+type WithdrawalsResult struct {
+	Withdrawals []*WithdrawalResult `json:"withdrawals"`
+	NextCursor  *hexutil.Bytes      `json:"nextCursor"`
+}
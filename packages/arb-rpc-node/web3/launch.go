@@ -27,6 +27,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/callcache"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 )
 
@@ -39,14 +40,18 @@ type ServerConfig struct {
 	MaxCallAVMGas uint64
 	Tracing       configuration.Tracing
 	DevopsStubs   bool
+	// CallCache holds results of calls against past blocks if it isn't nil
+	CallCache callcache.Cache
 }
 
 func GenerateWeb3Server(server *aggregator.Server, privateKeys []*ecdsa.PrivateKey, config ServerConfig, coreConfig *configuration.Core, plugins map[string]interface{}, inboxReader *monitor.InboxReader) (*rpc.Server, error) {
 	s := rpc.NewServer()
 
 	var sequencerInboxWatcher *ethbridge.SequencerInboxWatcher
+	var outboxWatcher *ethbridge.OutboxWatcher
 	if inboxReader != nil {
 		sequencerInboxWatcher = inboxReader.GetSequencerInboxWatcher()
+		outboxWatcher = inboxReader.GetOutboxWatcher()
 	}
 
 	ethServer := NewServer(server, config, sequencerInboxWatcher)
@@ -69,7 +74,7 @@ func GenerateWeb3Server(server *aggregator.Server, privateKeys []*ecdsa.PrivateK
 			return nil, err
 		}
 
-		if err := s.RegisterName("arb", &Arb{srv: server}); err != nil {
+		if err := s.RegisterName("arb", &Arb{srv: server, outbox: outboxWatcher}); err != nil {
 			return nil, err
 		}
 
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"context"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+const (
+	RetryableStatusCreated          = "created"
+	RetryableStatusAutoRedeemFailed = "autoRedeemFailed"
+	RetryableStatusRedeemed         = "redeemed"
+	RetryableStatusExpired          = "expired"
+	RetryableStatusCancelled        = "cancelled"
+)
+
+// GetRetryableTicket returns the current state of the given retryable ticket
+// along with the transactions that created it and tried to redeem or cancel
+// it, or null if no ticket with that ID was ever created
+func (a *Arb) GetRetryableTicket(ctx context.Context, ticketId ethcommon.Hash) (*RetryableTicketResult, error) {
+	index := a.srv.RetryableIndex()
+	if index == nil {
+		return nil, errors.New("retryable index is not enabled on this node")
+	}
+	entries, err := index.GetTicketEvents(common.NewHashFromEth(ticketId))
+	if err != nil {
+		return nil, err
+	}
+
+	var creation *txdb.RetryableIndexEntry
+	for i := range entries {
+		if entries[i].Kind == txdb.RetryableCreated {
+			creation = &entries[i]
+			break
+		}
+	}
+	if creation == nil {
+		if index.StartBlock() == 0 {
+			return nil, nil
+		}
+		// The ticket may have been created before the index started
+		snap, err := a.srv.LatestSnapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := snap.GetRetryableTimeout(ctx, common.NewHashFromEth(ticketId))
+		if err != nil {
+			return nil, err
+		}
+		if timeout.Sign() != 0 {
+			return nil, errors.Errorf("ticket was created before the retryable index started at block %v", index.StartBlock())
+		}
+		return nil, nil
+	}
+	creationRes, _, _, err := a.srv.GetRequestResult(creation.RequestId)
+	if err != nil {
+		return nil, err
+	}
+	if creationRes == nil {
+		// The creation must have been reorged out since it was looked up
+		return nil, nil
+	}
+
+	ret := &RetryableTicketResult{
+		TicketId:            ticketId,
+		CreationTxHash:      creation.RequestId.ToEthHash(),
+		CreationBlockNumber: hexutil.Uint64(creation.BlockNumber),
+		RedeemAttempts:      make([]*RetryableRedeemAttemptResult, 0),
+	}
+	// A redeem only schedules the ticket, which then runs as its own request
+	// under the ticket ID, so the ticket is redeemed only if that succeeded
+	ticketRes, _, _, err := a.srv.GetRequestResult(common.NewHashFromEth(ticketId))
+	if err != nil {
+		return nil, err
+	}
+	redeemed := ticketRes != nil && ticketRes.ResultCode == evm.ReturnCode
+	if redeemed {
+		redeemedTxHash := ticketId
+		ret.RedeemedTxHash = &redeemedTxHash
+	}
+
+	autoRedeemId := message.RetryableAutoRedeemID(creation.RequestId)
+	autoRedeemFailed := creationRes.ResultCode == evm.NoGasForAutoRedeem
+	var redeemingAttempt *RetryableRedeemAttemptResult
+	for _, entry := range entries {
+		switch entry.Kind {
+		case txdb.RetryableRedeemAttempted:
+			res, _, _, err := a.srv.GetRequestResult(entry.RequestId)
+			if err != nil {
+				return nil, err
+			}
+			attempt := &RetryableRedeemAttemptResult{
+				TxHash:      entry.RequestId.ToEthHash(),
+				BlockNumber: hexutil.Uint64(entry.BlockNumber),
+				AutoRedeem:  entry.RequestId == autoRedeemId,
+			}
+			// The ticket runs right after the redeem which scheduled it, so
+			// that redeem is the last one to go through by the ticket's block
+			if redeemed && res != nil && res.ResultCode == evm.ReturnCode &&
+				entry.BlockNumber <= ticketRes.IncomingRequest.L2BlockNumber.Uint64() {
+				redeemingAttempt = attempt
+			}
+			if attempt.AutoRedeem && !redeemed {
+				autoRedeemFailed = true
+			}
+			ret.RedeemAttempts = append(ret.RedeemAttempts, attempt)
+		case txdb.RetryableCanceled:
+			cancelTxHash := entry.RequestId.ToEthHash()
+			ret.CancelTxHash = &cancelTxHash
+		}
+	}
+	if redeemingAttempt != nil {
+		redeemingAttempt.Successful = true
+	}
+
+	switch {
+	case ret.CancelTxHash != nil:
+		ret.Status = RetryableStatusCancelled
+	case ret.RedeemedTxHash != nil:
+		ret.Status = RetryableStatusRedeemed
+	default:
+		snap, err := a.srv.LatestSnapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := snap.GetRetryableTimeout(ctx, common.NewHashFromEth(ticketId))
+		if err != nil {
+			return nil, err
+		}
+		if timeout.Sign() == 0 {
+			ret.Status = RetryableStatusExpired
+		} else {
+			ret.Timeout = (*hexutil.Big)(timeout)
+			if autoRedeemFailed {
+				ret.Status = RetryableStatusAutoRedeemFailed
+			} else {
+				ret.Status = RetryableStatusCreated
+			}
+		}
+	}
+	return ret, nil
+}
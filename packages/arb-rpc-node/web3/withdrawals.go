@@ -0,0 +1,239 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"context"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+)
+
+// Maximum number of withdrawals returned by one call to
+// arb_getWithdrawalsBySender or arb_getWithdrawalsByDestination
+const maxWithdrawals = 100
+
+// GetWithdrawalsBySender lists the L2 to L1 messages sent by the given L2
+// address between fromBlock and toBlock inclusive, along with their outbox
+// proofs if their batch has been produced. If more withdrawals remain than
+// fit in one response, nextCursor is set and can be passed back in to
+// continue from where the response left off.
+func (a *Arb) GetWithdrawalsBySender(
+	ctx context.Context,
+	sender ethcommon.Address,
+	fromBlock rpc.BlockNumber,
+	toBlock rpc.BlockNumber,
+	cursor *hexutil.Bytes,
+) (*WithdrawalsResult, error) {
+	return a.getIndexedWithdrawals(ctx, fromBlock, toBlock, cursor, func(index *txdb.WithdrawalIndex, from, to uint64, start []byte) ([]txdb.WithdrawalIndexEntry, []byte, error) {
+		return index.GetBySender(common.NewAddressFromEth(sender), from, to, start, maxWithdrawals)
+	})
+}
+
+// GetWithdrawalsByDestination lists the L2 to L1 messages sent to the given
+// L1 address, in the same way as GetWithdrawalsBySender
+func (a *Arb) GetWithdrawalsByDestination(
+	ctx context.Context,
+	destination ethcommon.Address,
+	fromBlock rpc.BlockNumber,
+	toBlock rpc.BlockNumber,
+	cursor *hexutil.Bytes,
+) (*WithdrawalsResult, error) {
+	return a.getIndexedWithdrawals(ctx, fromBlock, toBlock, cursor, func(index *txdb.WithdrawalIndex, from, to uint64, start []byte) ([]txdb.WithdrawalIndexEntry, []byte, error) {
+		return index.GetByDestination(common.NewAddressFromEth(destination), from, to, start, maxWithdrawals)
+	})
+}
+
+// GetWithdrawalsByTransaction returns the L2 to L1 messages sent by the
+// given transaction. This doesn't depend on the withdrawal index being
+// enabled.
+func (a *Arb) GetWithdrawalsByTransaction(ctx context.Context, txHash ethcommon.Hash) ([]*WithdrawalResult, error) {
+	res, _, _, err := a.srv.GetRequestResult(common.NewHashFromEth(txHash))
+	if err != nil || res == nil {
+		return nil, err
+	}
+	lookup := newWithdrawalLookup(a)
+	ret := make([]*WithdrawalResult, 0)
+	for i := range res.EVMLogs {
+		withdrawal, err := lookup.withdrawal(ctx, res, uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		if withdrawal != nil {
+			ret = append(ret, withdrawal)
+		}
+	}
+	return ret, nil
+}
+
+func (a *Arb) getIndexedWithdrawals(
+	ctx context.Context,
+	fromBlock rpc.BlockNumber,
+	toBlock rpc.BlockNumber,
+	cursor *hexutil.Bytes,
+	get func(index *txdb.WithdrawalIndex, from, to uint64, start []byte) ([]txdb.WithdrawalIndexEntry, []byte, error),
+) (*WithdrawalsResult, error) {
+	index := a.srv.WithdrawalIndex()
+	if index == nil {
+		return nil, errors.New("withdrawal index is not enabled on this node")
+	}
+	from, err := a.srv.BlockNum(&fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.srv.BlockNum(&toBlock)
+	if err != nil {
+		return nil, err
+	}
+	var start []byte
+	if cursor != nil {
+		start = *cursor
+	}
+	entries, next, err := get(index, from, to, start)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := newWithdrawalLookup(a)
+	ret := &WithdrawalsResult{
+		Withdrawals: make([]*WithdrawalResult, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		res, _, _, err := a.srv.GetRequestResult(entry.RequestId)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			// Reorged out since the index was read
+			continue
+		}
+		withdrawal, err := lookup.withdrawal(ctx, res, entry.LogIndex)
+		if err != nil {
+			return nil, err
+		}
+		if withdrawal != nil {
+			ret.Withdrawals = append(ret.Withdrawals, withdrawal)
+		}
+	}
+	if next != nil {
+		nextCursor := hexutil.Bytes(next)
+		ret.NextCursor = &nextCursor
+	}
+	return ret, nil
+}
+
+// withdrawalLookup caches the message batches and their L1 confirmation
+// status while producing the results of a single call
+type withdrawalLookup struct {
+	a         *Arb
+	batches   map[string]*evm.MerkleRootResult
+	confirmed map[string]bool
+}
+
+func newWithdrawalLookup(a *Arb) *withdrawalLookup {
+	return &withdrawalLookup{
+		a:         a,
+		batches:   make(map[string]*evm.MerkleRootResult),
+		confirmed: make(map[string]bool),
+	}
+}
+
+// withdrawal returns the L2 to L1 message emitted in the given log of res,
+// or nil if that log isn't an L2ToL1Transaction event
+func (w *withdrawalLookup) withdrawal(ctx context.Context, res *evm.TxResult, logIndex uint64) (*WithdrawalResult, error) {
+	if logIndex >= uint64(len(res.EVMLogs)) {
+		return nil, nil
+	}
+	evmLog := res.EVMLogs[logIndex]
+	if evmLog.Address.ToEthAddress() != arbos.ARB_SYS_ADDRESS {
+		return nil, nil
+	}
+	ev, err := arbos.ParseL2ToL1TransactionLog(common.NewEthHashesFromHashes(evmLog.Topics), evmLog.Data)
+	if err != nil {
+		return nil, nil
+	}
+
+	ret := &WithdrawalResult{
+		TxHash:        res.IncomingRequest.MessageID.ToEthHash(),
+		BlockNumber:   hexutil.Uint64(res.IncomingRequest.L2BlockNumber.Uint64()),
+		UniqueId:      (*hexutil.Big)(ev.UniqueId),
+		L2Sender:      ev.Caller,
+		L1Destination: ev.Destination,
+		BatchNumber:   (*hexutil.Big)(ev.BatchNumber),
+		IndexInBatch:  hexutil.Uint64(ev.IndexInBatch.Uint64()),
+		ArbBlockNum:   (*hexutil.Big)(ev.ArbBlockNum),
+		EthBlockNum:   (*hexutil.Big)(ev.EthBlockNum),
+		Timestamp:     (*hexutil.Big)(ev.Timestamp),
+		Value:         (*hexutil.Big)(ev.Callvalue),
+		Data:          ev.Data,
+	}
+	if err := w.addBatchInfo(ctx, ret, ev); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (w *withdrawalLookup) addBatchInfo(ctx context.Context, ret *WithdrawalResult, ev *arboscontracts.ArbSysL2ToL1Transaction) error {
+	key := ev.BatchNumber.String()
+	batch, ok := w.batches[key]
+	if !ok {
+		var err error
+		batch, err = w.a.srv.GetMessageBatch(ev.BatchNumber)
+		if err != nil {
+			return err
+		}
+		w.batches[key] = batch
+	}
+	if batch == nil {
+		// The batch hasn't been produced yet so it can't have been confirmed
+		confirmed := false
+		ret.Confirmed = &confirmed
+		return nil
+	}
+
+	proof, err := batch.GenerateProof(ev.IndexInBatch.Uint64())
+	if err != nil {
+		return err
+	}
+	ret.Proof = &L2ToL1ProofResult{
+		Nodes: common.NewEthHashesFromHashes(proof.Nodes),
+		Path:  (*hexutil.Big)(protocol.PathSliceToInt(proof.Path)),
+	}
+
+	if w.a.outbox == nil {
+		return nil
+	}
+	confirmed, ok := w.confirmed[key]
+	if !ok {
+		confirmed, err = w.a.outbox.OutboxEntryExists(ctx, ev.BatchNumber)
+		if err != nil {
+			return err
+		}
+		w.confirmed[key] = confirmed
+	}
+	ret.Confirmed = &confirmed
+	return nil
+}
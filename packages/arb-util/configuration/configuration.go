@@ -170,9 +170,10 @@ type Lockout struct {
 }
 
 type Aggregator struct {
-	InboxAddress string `koanf:"inbox-address"`
-	MaxBatchTime int64  `koanf:"max-batch-time"`
-	Stateful     bool   `koanf:"stateful"`
+	CompressAddresses bool   `koanf:"compress-addresses"`
+	InboxAddress      string `koanf:"inbox-address"`
+	MaxBatchTime      int64  `koanf:"max-batch-time"`
+	Stateful          bool   `koanf:"stateful"`
 }
 
 type Tracing struct {
@@ -185,15 +186,42 @@ type NitroExport struct {
 	BaseDir string `koanf:"basedir"`
 }
 
+type RPCAuth struct {
+	APIKeys        []string `koanf:"api-keys"`
+	JWTSecret      string   `koanf:"jwt-secret"`
+	AllowAnonymous bool     `koanf:"allow-anonymous"`
+}
+
+type RPCMethods struct {
+	Allow         []string `koanf:"allow"`
+	Deny          []string `koanf:"deny"`
+	AnonymousDeny []string `koanf:"anonymous-deny"`
+}
+
+type RPCRateLimit struct {
+	PerKey            float64  `koanf:"per-key"`
+	PerKeyBurst       int      `koanf:"per-key-burst"`
+	PerIP             float64  `koanf:"per-ip"`
+	PerIPBurst        int      `koanf:"per-ip-burst"`
+	MethodCosts       []string `koanf:"method-costs"`
+	ArchiveCost       int      `koanf:"archive-cost"`
+	ArchiveDepth      uint64   `koanf:"archive-depth"`
+	TrustForwardedFor bool     `koanf:"trust-forwarded-for"`
+}
+
 type RPC struct {
-	Addr              string      `koanf:"addr"`
-	Port              string      `koanf:"port"`
-	Path              string      `koanf:"path"`
-	EnableL1Calls     bool        `koanf:"enable-l1-calls"`
-	Tracing           Tracing     `koanf:"tracing"`
-	NitroExport       NitroExport `koanf:"nitroexport"`
-	MaxCallGas        uint64      `koanf:"max-call-gas"`
-	EnableDevopsStubs bool        `koanf:"enable-devops-stubs"`
+	Addr              string        `koanf:"addr"`
+	Port              string        `koanf:"port"`
+	Path              string        `koanf:"path"`
+	EnableL1Calls     bool          `koanf:"enable-l1-calls"`
+	Tracing           Tracing       `koanf:"tracing"`
+	NitroExport       NitroExport   `koanf:"nitroexport"`
+	MaxCallGas        uint64        `koanf:"max-call-gas"`
+	EnableDevopsStubs bool          `koanf:"enable-devops-stubs"`
+	Auth              RPCAuth       `koanf:"auth"`
+	Methods           RPCMethods    `koanf:"methods"`
+	RateLimit         RPCRateLimit  `koanf:"rate-limit"`
+	SlowRequestTime   time.Duration `koanf:"slow-request-time"`
 }
 
 type S3 struct {
@@ -219,6 +247,7 @@ type SequencerDangerous struct {
 }
 
 type Sequencer struct {
+	CompressAddresses                 bool               `koanf:"compress-addresses"`
 	CreateBatchBlockInterval          int64              `koanf:"create-batch-block-interval"`
 	ContinueBatchPostingBlockInterval int64              `koanf:"continue-batch-posting-block-interval"`
 	DelayedMessagesTargetDelay        int64              `koanf:"delayed-messages-target-delay"`
@@ -237,6 +266,13 @@ type WS struct {
 	Path string `koanf:"path"`
 }
 
+type GraphQL struct {
+	Enable bool   `koanf:"enable"`
+	Addr   string `koanf:"addr"`
+	Port   string `koanf:"port"`
+	Path   string `koanf:"path"`
+}
+
 type Forwarder struct {
 	Target      string `koanf:"target"`
 	Submitter   string `koanf:"submitter-address"`
@@ -271,6 +307,11 @@ type InboxReader struct {
 	DelayBlocks              int64         `koanf:"delay-blocks"`
 	Paranoid                 bool          `koanf:"paranoid"`
 	SequencerSignatureExpiry time.Duration `koanf:"sequencer-signature-expiry"`
+	BlocksToFetch            uint64        `koanf:"blocks-to-fetch"`
+	MinBlocksToFetch         uint64        `koanf:"min-blocks-to-fetch"`
+	MaxBlocksToFetch         uint64        `koanf:"max-blocks-to-fetch"`
+	SaveCheckpoint           bool          `koanf:"save-checkpoint"`
+	CheckpointInterval       time.Duration `koanf:"checkpoint-interval"`
 }
 
 type Node struct {
@@ -278,7 +319,9 @@ type Node struct {
 	Cache           NodeCache     `koanf:"cache"`
 	ChainID         uint64        `koanf:"chain-id"`
 	Forwarder       Forwarder     `koanf:"forwarder"`
+	GraphQL         GraphQL       `koanf:"graphql"`
 	InboxReader     InboxReader   `koanf:"inbox-reader"`
+	Index           NodeIndex     `koanf:"index"`
 	LogProcessCount int           `koanf:"log-process-count"`
 	LogIdleSleep    time.Duration `koanf:"log-idle-sleep"`
 	RPC             RPC           `koanf:"rpc"`
@@ -311,14 +354,32 @@ func (c *Node) Type() NodeType {
 	}
 }
 
+type CallCache struct {
+	Size    int           `koanf:"size"`
+	Peers   []string      `koanf:"peers"`
+	Timeout time.Duration `koanf:"timeout"`
+	Secret  string        `koanf:"secret"`
+	Addr    string        `koanf:"addr"`
+	Port    string        `koanf:"port"`
+	Path    string        `koanf:"path"`
+}
+
 type NodeCache struct {
 	AllowSlowLookup  bool          `koanf:"allow-slow-lookup"`
+	Call             CallCache     `koanf:"call"`
 	LRUSize          int           `koanf:"lru-size"`
 	BlockInfoLRUSize int           `koanf:"block-info-lru-size"`
 	TimedInitialSize int           `koanf:"timed-initial-size"`
 	TimedExpire      time.Duration `koanf:"timed-expire"`
 }
 
+type NodeIndex struct {
+	Path        string `koanf:"path"`
+	Addresses   bool   `koanf:"addresses"`
+	Retryables  bool   `koanf:"retryables"`
+	Withdrawals bool   `koanf:"withdrawals"`
+}
+
 type Persistent struct {
 	Chain        string `koanf:"chain"`
 	GlobalConfig string `koanf:"global-config"`
@@ -513,8 +574,13 @@ func DefaultNodeSettings() *Node {
 			TimedExpire:     20 * time.Minute,
 		},
 		InboxReader: InboxReader{
-			DelayBlocks: 4,
-			Paranoid:    false,
+			DelayBlocks:        4,
+			Paranoid:           false,
+			BlocksToFetch:      100,
+			MinBlocksToFetch:   2,
+			MaxBlocksToFetch:   5000,
+			SaveCheckpoint:     true,
+			CheckpointInterval: 30 * time.Second,
 		},
 		LogProcessCount: 100,
 		LogIdleSleep:    10 * time.Millisecond, // 10 for dev, 100 for server
@@ -542,10 +608,17 @@ func ParseCLI(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *b
 func ParseDBTool() (*Config, error) {
 	f := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 
+	return ParseDBToolArgs(f, os.Args[1:])
+}
+
+// ParseDBToolArgs parses args into a database tool configuration. Any flags
+// specific to the requested operation should be added to f beforehand, and
+// can be read back from f after parsing.
+func ParseDBToolArgs(f *flag.FlagSet, args []string) (*Config, error) {
 	AddPersistent(f)
 	AddCore(f, 0)
 
-	k, err := beginCommonParse(f)
+	k, err := beginCommonParseArgs(f, args)
 	if err != nil {
 		return nil, err
 	}
@@ -582,11 +655,19 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.Bool("validator.dont-challenge", false, "don't challenge any other validators' assertions")
 	f.String("validator.withdraw-destination", "", "the address to withdraw funds to (defaults to the wallet address)")
 
+	f.Bool("node.aggregator.compress-addresses", false, "replace transaction destinations registered in the ArbOS address table with their index")
 	f.String("node.aggregator.inbox-address", "", "address of the inbox contract")
 	f.Int("node.aggregator.max-batch-time", 10, "max-batch-time=NumSeconds")
 	f.Bool("node.aggregator.stateful", false, "enable pending state tracking")
 
 	f.Bool("node.cache.allow-slow-lookup", false, "load L2 block from disk if not in memory cache")
+	f.Int("node.cache.call.size", 0, "number of eth_call results against past blocks to hold in memory (0 = disabled)")
+	f.StringSlice("node.cache.call.peers", []string{}, "URLs of other nodes' call caches to look up and share call results with")
+	f.Duration("node.cache.call.timeout", 500*time.Millisecond, "timeout for requests to call cache peers")
+	f.String("node.cache.call.secret", "", "secret shared with call cache peers, required to add results to the served cache (empty = served read only)")
+	f.String("node.cache.call.addr", "127.0.0.1", "address to serve the call cache to peers on")
+	f.String("node.cache.call.port", "", "port to serve the call cache to peers on (empty = not served)")
+	f.String("node.cache.call.path", "/callcache", "path to serve the call cache to peers on")
 	f.Int("node.cache.lru-size", 1000, "number of recently used L2 blocks to hold in lru memory cache")
 	f.Int("node.cache.block-info-lru-size", 100_000, "number of recently used L2 block info to hold in lru memory cache")
 	f.Duration("node.cache.timed-expire", 20*time.Minute, "length of time to hold L2 blocks in timed memory cache")
@@ -596,9 +677,24 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.String("node.forwarder.submitter-address", "", "address of the node that will submit your transaction to the chain")
 	f.String("node.forwarder.rpc-mode", "full", "RPC mode: either full, non-mutating (no eth_sendRawTransaction), or forwarding-only (only requests forwarded upstream are permitted)")
 
+	f.Bool("node.graphql.enable", false, "enable the GraphQL endpoint (EIP-1767)")
+	f.String("node.graphql.addr", "0.0.0.0", "GraphQL address")
+	f.Int("node.graphql.port", 8549, "GraphQL port")
+	f.String("node.graphql.path", "/graphql", "GraphQL path")
+
 	f.Int64("node.inbox-reader.delay-blocks", 4, "number of L1 blocks to wait for confirmation before updating L2 state")
 	f.Bool("node.inbox-reader.paranoid", false, "if enabled, check for reorgs before searching for messages")
 	f.Duration("node.inbox-reader.sequencer-signature-expiry", 10*time.Minute, "length of time between verifying sequencer feed signing address on-chain")
+	f.Uint64("node.inbox-reader.blocks-to-fetch", 100, "initial number of L1 blocks to query for inbox messages at a time")
+	f.Uint64("node.inbox-reader.min-blocks-to-fetch", 2, "minimum number of L1 blocks to query for inbox messages at a time")
+	f.Uint64("node.inbox-reader.max-blocks-to-fetch", 5000, "maximum number of L1 blocks to query for inbox messages at a time")
+	f.Bool("node.inbox-reader.save-checkpoint", true, "persist the last processed L1 block so restarts and reorg recovery can resume from it")
+	f.Duration("node.inbox-reader.checkpoint-interval", 30*time.Second, "minimum time between writes of the inbox reader checkpoint")
+
+	f.String("node.index.path", "indexdb", "directory to store secondary indexes in, relative to the chain directory if not absolute")
+	f.Bool("node.index.addresses", false, "index transactions by sender and recipient address, starting from the first block processed after it is enabled")
+	f.Bool("node.index.retryables", false, "index retryable ticket creation, redemption and cancellation, starting from the first block processed after it is enabled")
+	f.Bool("node.index.withdrawals", false, "index L2 to L1 messages by L2 sender and L1 destination, starting from the first block processed after it is enabled")
 
 	f.Duration("node.log-idle-sleep", 100*time.Millisecond, "milliseconds for log reader to sleep between reading logs")
 	f.Int("node.log-process-count", 100, "maximum number of logs to process at a time")
@@ -611,10 +707,27 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.String("node.rpc.tracing.namespace", "arbtrace", "rpc namespace for tracing api")
 	f.Uint64("node.rpc.max-call-gas", 5000000, "Max computational arbgas limit when processing eth_call and eth_estimateGas")
 	f.Bool("node.rpc.enable-devops-stubs", false, "Enable fake versions of eth_syncing and eth_netPeers")
+	f.Duration("node.rpc.slow-request-time", 0, "log requests taking longer than this along with their params (0 = disabled)")
 
 	f.Bool("node.rpc.nitroexport.enable", false, "Enable rpcs for nitro export (stored locally on node)")
 	f.String("node.rpc.nitroexport.basedir", "", "Base dir for nitro export")
 
+	f.StringSlice("node.rpc.auth.api-keys", []string{}, "API keys accepted as bearer tokens or in the apikey query parameter")
+	f.String("node.rpc.auth.jwt-secret", "", "HMAC secret used to verify JWT bearer tokens (JWT authentication disabled if empty)")
+	f.Bool("node.rpc.auth.allow-anonymous", true, "allow requests which don't provide an API key or JWT")
+	f.StringSlice("node.rpc.methods.allow", []string{}, "if set, only these methods may be called (namespace_* matches a whole namespace)")
+	f.StringSlice("node.rpc.methods.deny", []string{}, "methods which may not be called (namespace_* matches a whole namespace)")
+	f.StringSlice("node.rpc.methods.anonymous-deny", []string{}, "methods which may not be called without authenticating (namespace_* matches a whole namespace)")
+	f.Float64("node.rpc.rate-limit.per-key", 0, "cost units per second allowed for each authenticated caller (0 = unlimited)")
+	f.Int("node.rpc.rate-limit.per-key-burst", 100, "maximum cost units an authenticated caller can use at once")
+	f.Float64("node.rpc.rate-limit.per-ip", 0, "cost units per second allowed for each anonymous IP address (0 = unlimited)")
+	f.Int("node.rpc.rate-limit.per-ip-burst", 100, "maximum cost units an anonymous IP address can use at once")
+	f.StringSlice("node.rpc.rate-limit.method-costs", []string{}, "cost of methods as method=cost, all other methods cost 1 (namespace_* matches a whole namespace)")
+	f.Int("node.rpc.rate-limit.archive-cost", 0, "cost of state queries against blocks older than archive-depth (0 = same as the method's cost)")
+	f.Uint64("node.rpc.rate-limit.archive-depth", 128, "number of blocks behind the latest at which state queries are charged the archive cost")
+	f.Bool("node.rpc.rate-limit.trust-forwarded-for", false, "use the last X-Forwarded-For entry, added by the proxy, as the caller's IP address (only enable behind a single trusted proxy)")
+
+	f.Bool("node.sequencer.compress-addresses", false, "replace transaction destinations registered in the ArbOS address table with their index")
 	f.Int64("node.sequencer.create-batch-block-interval", 270, "block interval at which to create new batches")
 	f.Int64("node.sequencer.continue-batch-posting-block-interval", 2, "block interval to post the next batch after posting a partial one")
 	f.Int64("node.sequencer.delayed-messages-target-delay", 12, "delay before sequencing delayed messages")
@@ -864,6 +977,11 @@ func resolveDirectoryNames(out *Config, wallet *Wallet) error {
 		out.Core.Database.SavePath = path.Join(out.Persistent.Chain, out.Core.Database.SavePath)
 	}
 
+	// Make index directory relative to chain directory if not already absolute
+	if !filepath.IsAbs(out.Node.Index.Path) {
+		out.Node.Index.Path = path.Join(out.Persistent.Chain, out.Node.Index.Path)
+	}
+
 	if len(out.Rollup.Machine.Filename) == 0 {
 		// Machine not provided, so use default chain specific machine
 		out.Rollup.Machine.Filename = path.Join(out.Persistent.Chain, "arbos.mexe")
@@ -989,6 +1107,10 @@ func AddHealthcheckOptions(f *flag.FlagSet) {
 }
 
 func beginCommonParse(f *flag.FlagSet) (*koanf.Koanf, error) {
+	return beginCommonParseArgs(f, os.Args[1:])
+}
+
+func beginCommonParseArgs(f *flag.FlagSet, args []string) (*koanf.Koanf, error) {
 	f.Bool("conf.dump", false, "print out currently active configuration file")
 	f.String("conf.env-prefix", "", "environment variables with given prefix will be loaded as configuration values")
 	f.String("conf.file", "", "name of configuration file")
@@ -1015,7 +1137,7 @@ func beginCommonParse(f *flag.FlagSet) (*koanf.Koanf, error) {
 
 	f.Bool("pprof-enable", false, "enable profiling server")
 
-	err := f.Parse(os.Args[1:])
+	err := f.Parse(args)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +37,9 @@ type ArbOutputLookup interface {
 	GetMessages(startIndex, count *big.Int) ([]inbox.InboxMessage, error)
 
 	GetSequencerBatchItems(startIndex *big.Int) ([]inbox.SequencerBatchItem, error)
+	// GetSequencerBatchItemsLimited returns at most maxCount batch items,
+	// starting with the one containing message startIndex
+	GetSequencerBatchItemsLimited(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error)
 
 	GetDelayedMessageCount() (*big.Int, error)
 	GetTotalDelayedMessagesSequenced() (*big.Int, error)
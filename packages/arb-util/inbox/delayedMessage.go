@@ -20,6 +20,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/pkg/errors"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
@@ -49,3 +50,20 @@ func (m DelayedMessage) ToBytesWithSeqNum() []byte {
 	data = append(data, m.Message...)
 	return data
 }
+
+func NewDelayedMessageFromData(data []byte) (DelayedMessage, error) {
+	if len(data) < 32*2 {
+		return DelayedMessage{}, errors.New("Not enough data for delayed message")
+	}
+	msg := DelayedMessage{}
+
+	msg.DelayedSequenceNumber = new(big.Int).SetBytes(data[:32])
+	data = data[32:]
+
+	copy(msg.DelayedAccumulator[:], data[:32])
+	data = data[32:]
+
+	msg.Message = data
+
+	return msg, nil
+}
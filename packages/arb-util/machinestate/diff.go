@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"fmt"
+)
+
+// Difference is a part of a machine which changed. Before is nil for items
+// pushed onto a stack, and After is nil for items popped from one.
+type Difference struct {
+	Path   string `json:"path"`
+	Before *Node  `json:"before"`
+	After  *Node  `json:"after"`
+}
+
+// Diff returns the smallest parts of two machines which differ. Tuples of
+// the same length are compared item by item, and stacks are compared from the
+// bottom so that pushing or popping doesn't shift the items below. Paths
+// index into the after machine, except for popped items. Parts which couldn't
+// be resolved in either machine are reported whole.
+func Diff(before, after *Tree) []Difference {
+	var diffs []Difference
+	for _, name := range []string{"stack", "auxstack", "register", "static"} {
+		diffs = diffNodes(diffs, name, before.roots()[name], after.roots()[name])
+	}
+	return diffs
+}
+
+func diffNodes(diffs []Difference, path string, before, after *Node) []Difference {
+	if before.Hash == after.Hash {
+		return diffs
+	}
+	if before.Type == NodeStack && after.Type == NodeStack && before.Rest == nil && after.Rest == nil {
+		return diffStacks(diffs, path, before, after)
+	}
+	if before.Type == NodeTuple && after.Type == NodeTuple && len(before.Items) == len(after.Items) {
+		for i := range before.Items {
+			diffs = diffNodes(diffs, fmt.Sprintf("%v/%v", path, i), before.Items[i], after.Items[i])
+		}
+		return diffs
+	}
+	if before.Type == NodeCodePoint && after.Type == NodeCodePoint &&
+		before.Immediate != nil && after.Immediate != nil &&
+		*before.Opcode == *after.Opcode && *before.NextHash == *after.NextHash {
+		return diffNodes(diffs, path+"/0", before.Immediate, after.Immediate)
+	}
+	return append(diffs, Difference{Path: path, Before: before, After: after})
+}
+
+func diffStacks(diffs []Difference, path string, before, after *Node) []Difference {
+	// Items are listed from the top, so align the bottoms of the stacks
+	beforeOffset := 0
+	afterOffset := 0
+	if len(before.Items) > len(after.Items) {
+		beforeOffset = len(before.Items) - len(after.Items)
+	} else {
+		afterOffset = len(after.Items) - len(before.Items)
+	}
+	for i := 0; i < beforeOffset; i++ {
+		diffs = append(diffs, Difference{Path: fmt.Sprintf("%v/%v", path, i), Before: before.Items[i]})
+	}
+	for i := 0; i < afterOffset; i++ {
+		diffs = append(diffs, Difference{Path: fmt.Sprintf("%v/%v", path, i), After: after.Items[i]})
+	}
+	for i := afterOffset; i < len(after.Items); i++ {
+		diffs = diffNodes(diffs, fmt.Sprintf("%v/%v", path, i), before.Items[i-afterOffset+beforeOffset], after.Items[i])
+	}
+	return diffs
+}
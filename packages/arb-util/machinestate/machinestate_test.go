@@ -0,0 +1,182 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func marshalPreImage(data []byte, preImage value.HashPreImage) []byte {
+	inner := preImage.GetInnerHash()
+	data = append(data, inner[:]...)
+	return append(data, hashing.Uint256(big.NewInt(preImage.Size()))...)
+}
+
+// marshalState builds the output of MarshalState for a machine with the
+// given parts, with codepoints replaced by hashes
+func marshalState(stack, auxStack, register *value.TupleValue, staticBuf *value.Buffer) []byte {
+	pc := common.Hash{1}
+	data := append([]byte{}, pc[:]...)
+	data = marshalPreImage(data, stack.GetPreImage())
+	data = marshalPreImage(data, auxStack.GetPreImage())
+	data = append(data, value.TypeCodeHashPreImage)
+	data = marshalPreImage(data, register.GetPreImage())
+	// The static value is a codepoint with a buffer immediate
+	data = append(data, value.TypeCodeCodePoint, 1, 0x3b, value.TypeCodeBuffer)
+	root := staticBuf.RootHash()
+	data = append(data, root[:]...)
+	nextHash := common.Hash{2}
+	data = append(data, nextHash[:]...)
+	data = append(data, hashing.Uint256(big.NewInt(1000))...)
+	errPC := common.Hash{3}
+	return append(data, errPC[:]...)
+}
+
+func makeStack(items ...value.Value) *value.TupleValue {
+	stack := value.NewEmptyTuple()
+	for i := len(items) - 1; i >= 0; i-- {
+		stack = value.NewTuple2(items[i], stack)
+	}
+	return stack
+}
+
+func decodeAndResolve(t *testing.T, data []byte, loader MapLoader) *Tree {
+	t.Helper()
+	state, err := DecodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Resolve(loader); err != nil {
+		t.Fatal(err)
+	}
+	return NewTree(state)
+}
+
+func TestDecodeAndResolve(t *testing.T) {
+	stack := makeStack(value.NewInt64Value(5), value.NewInt64Value(6))
+	register := value.NewTuple2(value.NewInt64Value(1), value.NewTuple2(value.NewInt64Value(2), value.NewInt64Value(3)))
+	staticBuf := value.NewBuffer([]byte{1, 2, 3})
+	data := marshalState(stack, value.NewEmptyTuple(), register, staticBuf)
+
+	state, err := DecodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stack.Hash() != stack.Hash() || state.Register.Hash() != register.Hash() {
+		t.Fatal("decoded stubs have the wrong hashes")
+	}
+	if state.ArbGasRemaining.Cmp(big.NewInt(1000)) != 0 {
+		t.Error("wrong gas remaining", state.ArbGasRemaining)
+	}
+
+	// Without a loader, only the hashes are known
+	tree := NewTree(state)
+	if tree.Register.Type != NodeUnresolved || tree.Stack.Rest == nil {
+		t.Error("unloaded values weren't left unresolved")
+	}
+
+	loader := make(MapLoader)
+	loader.Add(stack)
+	loader.Add(register)
+	loader.Add(staticBuf)
+	tree = decodeAndResolve(t, data, loader)
+	checks := []struct {
+		path     string
+		expected value.Value
+	}{
+		{"stack/1", value.NewInt64Value(6)},
+		{"register/1/0", value.NewInt64Value(2)},
+		{"static/0", staticBuf},
+	}
+	for _, check := range checks {
+		node, err := tree.Lookup(check.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Hash != check.expected.Hash().ToEthHash() {
+			t.Errorf("wrong value at %v", check.path)
+		}
+	}
+	if _, err := tree.Lookup("register/1/0/0"); err == nil {
+		t.Error("looked up item of an int")
+	}
+	if _, err := tree.Lookup("stack/2"); err == nil {
+		t.Error("looked up item past the bottom of the stack")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	staticBuf := value.NewBuffer([]byte{1, 2, 3})
+	beforeStack := makeStack(value.NewInt64Value(5), value.NewInt64Value(6))
+	afterStack := makeStack(value.NewInt64Value(4), value.NewInt64Value(5), value.NewInt64Value(7))
+	beforeRegister := value.NewTuple2(value.NewInt64Value(1), value.NewTuple2(value.NewInt64Value(2), value.NewInt64Value(3)))
+	afterRegister := value.NewTuple2(value.NewInt64Value(1), value.NewTuple2(value.NewInt64Value(2), value.NewInt64Value(8)))
+
+	loader := make(MapLoader)
+	for _, val := range []value.Value{beforeStack, afterStack, beforeRegister, afterRegister, staticBuf} {
+		loader.Add(val)
+	}
+	before := decodeAndResolve(t, marshalState(beforeStack, value.NewEmptyTuple(), beforeRegister, staticBuf), loader)
+	after := decodeAndResolve(t, marshalState(afterStack, value.NewEmptyTuple(), afterRegister, staticBuf), loader)
+
+	diffs := Diff(before, after)
+	expected := []string{"stack/0", "stack/2", "register/1/1"}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %v differences but got %+v", len(expected), diffs)
+	}
+	for i, diff := range diffs {
+		if diff.Path != expected[i] {
+			t.Errorf("difference %v is at %v instead of %v", i, diff.Path, expected[i])
+		}
+	}
+	if diffs[0].Before != nil || diffs[0].After.Hash != value.NewInt64Value(4).Hash().ToEthHash() {
+		t.Error("pushed item wasn't reported as added")
+	}
+}
+
+func TestResolveRoot(t *testing.T) {
+	stack := makeStack(value.NewInt64Value(5))
+	register := value.NewTuple2(value.NewInt64Value(1), value.NewInt64Value(2))
+	staticBuf := value.NewBuffer([]byte{1, 2, 3})
+	loader := make(MapLoader)
+	loader.Add(stack)
+	loader.Add(register)
+	loader.Add(staticBuf)
+
+	state, err := DecodeState(marshalState(stack, value.NewEmptyTuple(), register, staticBuf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.ResolveRoot(loader, "stack"); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewTree(state)
+	if tree.Stack.Rest != nil || len(tree.Stack.Items) != 1 {
+		t.Error("stack wasn't resolved")
+	}
+	if tree.Register.Type != NodeUnresolved || tree.Static.Immediate.Type != NodeUnresolved {
+		t.Error("parts other than the stack were resolved")
+	}
+	if err := state.ResolveRoot(loader, "pc"); err == nil {
+		t.Error("expected error for unknown root")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// ValueLoader looks up values by hash. It returns a
+// *machine.ValueNotFoundError if the value isn't available.
+type ValueLoader interface {
+	GetValue(hash common.Hash) (value.Value, error)
+}
+
+// MapLoader is a ValueLoader backed by an in-memory set of values
+type MapLoader map[common.Hash]value.Value
+
+func (m MapLoader) GetValue(hash common.Hash) (value.Value, error) {
+	val, ok := m[hash]
+	if !ok {
+		return nil, &machine.ValueNotFoundError{HashValue: hash}
+	}
+	return val, nil
+}
+
+// Add stores val and every value inside it
+func (m MapLoader) Add(val value.Value) {
+	m[val.Hash()] = val
+	switch val := val.(type) {
+	case *value.TupleValue:
+		for _, item := range val.Contents() {
+			m.Add(item)
+		}
+	case value.CodePointValue:
+		if op, ok := val.Op.(value.ImmediateOperation); ok {
+			m.Add(op.Val)
+		}
+	}
+}
+
+// Resolve replaces the stubs in val with the values they stand for, wherever
+// loader has them. Stubs which can't be found are left in place.
+func Resolve(val value.Value, loader ValueLoader) (value.Value, error) {
+	switch val := val.(type) {
+	case value.HashPreImage, BufferStub:
+		if empty := value.NewEmptyTuple(); val.Hash() == empty.Hash() {
+			return empty, nil
+		}
+		loaded, err := loader.GetValue(val.Hash())
+		if _, ok := err.(*machine.ValueNotFoundError); ok {
+			return val, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if loaded.Hash() != val.Hash() {
+			return nil, errors.Errorf("loaded value has hash %v instead of %v", loaded.Hash(), val.Hash())
+		}
+		return Resolve(loaded, loader)
+	case *value.TupleValue:
+		items := make([]value.Value, 0, val.Len())
+		for _, item := range val.Contents() {
+			resolved, err := Resolve(item, loader)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, resolved)
+		}
+		return value.NewTupleFromSlice(items)
+	case value.CodePointValue:
+		op, ok := val.Op.(value.ImmediateOperation)
+		if !ok {
+			return val, nil
+		}
+		imm, err := Resolve(op.Val, loader)
+		if err != nil {
+			return nil, err
+		}
+		return value.CodePointValue{
+			Op:       value.ImmediateOperation{Op: op.Op, Val: imm},
+			NextHash: val.NextHash,
+		}, nil
+	default:
+		return val, nil
+	}
+}
+
+// Resolve replaces the stubs in each part of the machine wherever loader has
+// them
+func (s *State) Resolve(loader ValueLoader) error {
+	return s.ResolveRoot(loader, "")
+}
+
+// ResolveRoot resolves only the part of the machine named by root, which is
+// one of the roots accepted by Tree.Lookup, or every part if root is empty.
+// Loading a tuple loads everything inside it, so resolving the register
+// loads the whole ArbOS state.
+func (s *State) ResolveRoot(loader ValueLoader, root string) error {
+	parts := []struct {
+		name string
+		val  *value.Value
+	}{
+		{"stack", &s.Stack},
+		{"auxstack", &s.AuxStack},
+		{"register", &s.Register},
+		{"static", &s.Static},
+	}
+	found := false
+	for _, part := range parts {
+		if root != "" && root != part.name {
+			continue
+		}
+		found = true
+		resolved, err := Resolve(*part.val, loader)
+		if err != nil {
+			return errors.Wrapf(err, "error resolving %v", part.name)
+		}
+		*part.val = resolved
+	}
+	if !found {
+		return errors.Errorf("unknown root %v, expected stack, auxstack, register or static", root)
+	}
+	return nil
+}
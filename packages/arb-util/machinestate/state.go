@@ -0,0 +1,174 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package machinestate decodes the output of Machine.MarshalState so that the
+// contents of a machine can be browsed and compared without the C++ tooling
+package machinestate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// BufferStub is a buffer of which only the merkle root is known, which is how
+// buffers are encoded in marshaled machine state
+type BufferStub struct {
+	Root common.Hash
+}
+
+func (b BufferStub) TypeCode() uint8 {
+	return value.TypeCodeBuffer
+}
+
+func (b BufferStub) Equal(val value.Value) bool {
+	return b.Hash() == val.Hash()
+}
+
+func (b BufferStub) Size() int64 {
+	return 1
+}
+
+func (b BufferStub) String() string {
+	return fmt.Sprintf("BufferStub(%v)", b.Root)
+}
+
+func (b BufferStub) Hash() common.Hash {
+	return value.BufferHash(b.Root)
+}
+
+// State is a decoded machine. The stacks start out as HashPreImage stubs, as
+// do any tuples in the register and static value, until they're resolved.
+type State struct {
+	PCHash          common.Hash
+	Stack           value.Value
+	AuxStack        value.Value
+	Register        value.Value
+	Static          value.Value
+	ArbGasRemaining *big.Int
+	ErrPCHash       common.Hash
+}
+
+func readHash(r io.Reader) (common.Hash, error) {
+	var h common.Hash
+	_, err := io.ReadFull(r, h[:])
+	return h, err
+}
+
+func readPreImage(r io.Reader) (value.HashPreImage, error) {
+	inner, err := readHash(r)
+	if err != nil {
+		return value.HashPreImage{}, err
+	}
+	size, err := readHash(r)
+	if err != nil {
+		return value.HashPreImage{}, err
+	}
+	return value.NewPreImage(inner, new(big.Int).SetBytes(size[:]).Int64()), nil
+}
+
+// readProofValue reads a value in the encoding used by one step proofs, which
+// is the same as the standard one except that buffers are given by their root
+func readProofValue(r io.Reader) (value.Value, error) {
+	typeCode := make([]byte, 1)
+	if _, err := io.ReadFull(r, typeCode); err != nil {
+		return nil, err
+	}
+	switch tipe := typeCode[0]; {
+	case tipe == value.TypeCodeInt:
+		return value.NewIntValueFromReader(r)
+	case tipe == value.TypeCodeHashPreImage:
+		return readPreImage(r)
+	case tipe == value.TypeCodeBuffer:
+		root, err := readHash(r)
+		if err != nil {
+			return nil, err
+		}
+		return BufferStub{Root: root}, nil
+	case tipe == value.TypeCodeCodePoint:
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		var op value.Operation = value.BasicOperation{Op: value.Opcode(header[1])}
+		if header[0] == 1 {
+			imm, err := readProofValue(r)
+			if err != nil {
+				return nil, err
+			}
+			op = value.ImmediateOperation{Op: value.Opcode(header[1]), Val: imm}
+		} else if header[0] != 0 {
+			return nil, errors.New("immediate count must be 0 or 1")
+		}
+		nextHash, err := readHash(r)
+		if err != nil {
+			return nil, err
+		}
+		return value.CodePointValue{Op: op, NextHash: nextHash}, nil
+	case tipe >= value.TypeCodeTuple && tipe <= value.TypeCodeTuple+value.MaxTupleSize:
+		items := make([]value.Value, 0, tipe-value.TypeCodeTuple)
+		for i := uint8(0); i < tipe-value.TypeCodeTuple; i++ {
+			item, err := readProofValue(r)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return value.NewTupleFromSlice(items)
+	default:
+		return nil, errors.Errorf("invalid value type %v", tipe)
+	}
+}
+
+// DecodeState parses the output of Machine.MarshalState
+func DecodeState(data []byte) (*State, error) {
+	r := bytes.NewReader(data)
+	var s State
+	var err error
+	if s.PCHash, err = readHash(r); err != nil {
+		return nil, errors.Wrap(err, "error reading pc")
+	}
+	if s.Stack, err = readPreImage(r); err != nil {
+		return nil, errors.Wrap(err, "error reading stack")
+	}
+	if s.AuxStack, err = readPreImage(r); err != nil {
+		return nil, errors.Wrap(err, "error reading aux stack")
+	}
+	if s.Register, err = readProofValue(r); err != nil {
+		return nil, errors.Wrap(err, "error reading register")
+	}
+	if s.Static, err = readProofValue(r); err != nil {
+		return nil, errors.Wrap(err, "error reading static")
+	}
+	gas, err := readHash(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading gas")
+	}
+	s.ArbGasRemaining = new(big.Int).SetBytes(gas[:])
+	if s.ErrPCHash, err = readHash(r); err != nil {
+		return nil, errors.Wrap(err, "error reading error codepoint")
+	}
+	if r.Len() != 0 {
+		return nil, errors.Errorf("%v unexpected bytes after machine state", r.Len())
+	}
+	return &s, nil
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machinestate
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// Node types
+const (
+	NodeInt       = "int"
+	NodeTuple     = "tuple"
+	NodeBuffer    = "buffer"
+	NodeCodePoint = "codepoint"
+	// NodeStack is an AVM stack, which is a chain of 2-tuples, flattened
+	// into a list with the top of the stack first
+	NodeStack = "stack"
+	// NodeUnresolved is a tuple or buffer of which only the hash is known
+	NodeUnresolved = "unresolved"
+)
+
+// Node is the JSON form of a value in a machine
+type Node struct {
+	Type string         `json:"type"`
+	Hash ethcommon.Hash `json:"hash"`
+
+	Int    *hexutil.Big  `json:"int,omitempty"`
+	Items  []*Node       `json:"items,omitempty"`
+	Buffer hexutil.Bytes `json:"buffer,omitempty"`
+
+	// Codepoints have an opcode and immediate, unless they were loaded as a
+	// stub, in which case they only have their pc
+	Opcode    *uint8          `json:"opcode,omitempty"`
+	Immediate *Node           `json:"immediate,omitempty"`
+	NextHash  *ethcommon.Hash `json:"nextHash,omitempty"`
+	PC        *uint64         `json:"pc,omitempty"`
+
+	// Unresolved tuples give their size. For unresolved stacks, Rest holds
+	// the part of the stack below Items which couldn't be resolved.
+	Size *int64 `json:"size,omitempty"`
+	Rest *Node  `json:"rest,omitempty"`
+}
+
+// NewNode converts val into a node
+func NewNode(val value.Value) *Node {
+	n := &Node{Hash: val.Hash().ToEthHash()}
+	switch val := val.(type) {
+	case value.IntValue:
+		n.Type = NodeInt
+		n.Int = (*hexutil.Big)(val.BigInt())
+	case *value.TupleValue:
+		n.Type = NodeTuple
+		n.Items = make([]*Node, 0, val.Len())
+		for _, item := range val.Contents() {
+			n.Items = append(n.Items, NewNode(item))
+		}
+	case *value.Buffer:
+		n.Type = NodeBuffer
+		n.Buffer = val.Data()
+	case value.CodePointValue:
+		n.Type = NodeCodePoint
+		op := uint8(val.Op.GetOp())
+		n.Opcode = &op
+		if immOp, ok := val.Op.(value.ImmediateOperation); ok {
+			n.Immediate = NewNode(immOp.Val)
+		}
+		nextHash := val.NextHash.ToEthHash()
+		n.NextHash = &nextHash
+	case value.CodePointStub:
+		n.Type = NodeCodePoint
+		pc := val.PC
+		n.PC = &pc
+	case value.HashPreImage:
+		n.Type = NodeUnresolved
+		size := val.Size()
+		n.Size = &size
+	default:
+		n.Type = NodeUnresolved
+	}
+	return n
+}
+
+// NewStackNode converts an AVM stack into a node listing its items
+func NewStackNode(stack value.Value) *Node {
+	n := &Node{Type: NodeStack, Hash: stack.Hash().ToEthHash(), Items: []*Node{}}
+	for {
+		tup, ok := stack.(*value.TupleValue)
+		if !ok || tup.Len() != 2 {
+			break
+		}
+		n.Items = append(n.Items, NewNode(tup.Contents()[0]))
+		stack = tup.Contents()[1]
+	}
+	if tup, ok := stack.(*value.TupleValue); !ok || tup.Len() != 0 {
+		n.Rest = NewNode(stack)
+	}
+	return n
+}
+
+// Tree is the JSON form of a machine
+type Tree struct {
+	PCHash          ethcommon.Hash `json:"pc"`
+	Stack           *Node          `json:"stack"`
+	AuxStack        *Node          `json:"auxstack"`
+	Register        *Node          `json:"register"`
+	Static          *Node          `json:"static"`
+	ArbGasRemaining *hexutil.Big   `json:"arbGasRemaining"`
+	ErrPCHash       ethcommon.Hash `json:"errpc"`
+}
+
+func NewTree(s *State) *Tree {
+	return &Tree{
+		PCHash:          s.PCHash.ToEthHash(),
+		Stack:           NewStackNode(s.Stack),
+		AuxStack:        NewStackNode(s.AuxStack),
+		Register:        NewNode(s.Register),
+		Static:          NewNode(s.Static),
+		ArbGasRemaining: (*hexutil.Big)(new(big.Int).Set(s.ArbGasRemaining)),
+		ErrPCHash:       s.ErrPCHash.ToEthHash(),
+	}
+}
+
+func (t *Tree) roots() map[string]*Node {
+	return map[string]*Node{
+		"stack":    t.Stack,
+		"auxstack": t.AuxStack,
+		"register": t.Register,
+		"static":   t.Static,
+	}
+}
+
+// Lookup returns the node at path, which is one of stack, auxstack, register
+// or static followed by item indexes separated by slashes. For example
+// "register/2/0" is the first item of the third item of the register, and
+// "stack/0" is the top of the stack. An immediate is reached with index 0 of
+// its codepoint.
+func (t *Tree) Lookup(path string) (*Node, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	node, ok := t.roots()[parts[0]]
+	if !ok {
+		return nil, errors.Errorf("unknown root %v, expected stack, auxstack, register or static", parts[0])
+	}
+	for i, part := range parts[1:] {
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Errorf("invalid index %v", part)
+		}
+		prefix := strings.Join(parts[:i+1], "/")
+		if node.Type == NodeCodePoint {
+			if node.Immediate == nil || index != 0 {
+				return nil, errors.Errorf("%v has no immediate %v", prefix, index)
+			}
+			node = node.Immediate
+			continue
+		}
+		if node.Type != NodeTuple && node.Type != NodeStack {
+			return nil, errors.Errorf("%v is %v, not a tuple", prefix, node.Type)
+		}
+		if index < 0 || index >= len(node.Items) {
+			if node.Rest != nil {
+				return nil, errors.Errorf("%v/%v is past the resolved part of the stack", prefix, index)
+			}
+			return nil, errors.Errorf("%v has no item %v", prefix, index)
+		}
+		node = node.Items[index]
+	}
+	return node, nil
+}
@@ -17,19 +17,54 @@
 package monitor
 
 import (
-	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
-	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"sync"
 	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 )
 
 var logger = arblog.Logger.With().Str("component", "monitor").Logger()
 
 var GlobalMonitor = NewMonitor()
 
+// Latency of each transaction lifecycle stage transition in milliseconds
+var (
+	ReceivedToBatchedHistogram   = newLatencyHistogram("received_to_batched")
+	BatchedToSubmittedHistogram  = newLatencyHistogram("batched_to_submitted")
+	ReceivedToSubmittedHistogram = newLatencyHistogram("received_to_submitted")
+	SubmittedToAcceptedHistogram = newLatencyHistogram("submitted_to_accepted")
+	AcceptedToReadHistogram      = newLatencyHistogram("accepted_to_read")
+	SubmittedToReadHistogram     = newLatencyHistogram("submitted_to_read")
+	ReadToLogHistogram           = newLatencyHistogram("read_to_log")
+	ReceivedToLogHistogram       = newLatencyHistogram("received_to_log")
+)
+
+func newLatencyHistogram(name string) metrics.Histogram {
+	return metrics.NewRegisteredHistogram("arbitrum/txlifecycle/"+name, nil, metrics.NewExpDecaySample(1028, 0.015))
+}
+
+func observe(histogram metrics.Histogram, from *time.Time, to time.Time) {
+	// Stages observed out of order, such as a sequencer executing a
+	// transaction before posting its batch, aren't transitions
+	if from == nil || to.Before(*from) {
+		return
+	}
+	histogram.Update(to.Sub(*from).Milliseconds())
+}
+
+// How long lifecycle information is retained after it is first recorded.
+// Entries live for between one and two periods.
+const retentionPeriod = time.Hour
+
 type txInfo struct {
 	gotFromUserTime *time.Time
+	batchedTime     *time.Time
 	batch           *common.Hash
+	gotLogTime      *time.Time
 }
 
 func (tx *txInfo) LoggedAllValues() bool {
@@ -48,35 +83,71 @@ func (b *batchInfo) LoggedAllValues() bool {
 		b.readBatch != nil
 }
 
+// TransactionLifecycle holds the times a transaction passed through each
+// stage on its way from the user to being executed. Stages which haven't
+// happened yet, or weren't observed by this node, are nil.
+type TransactionLifecycle struct {
+	Received        *time.Time      `json:"received"`
+	IncludedInBatch *time.Time      `json:"includedInBatch"`
+	BatchTxHash     *ethcommon.Hash `json:"batchTxHash"`
+	BatchSubmitted  *time.Time      `json:"batchSubmitted"`
+	BatchAccepted   *time.Time      `json:"batchAccepted"`
+	BatchRead       *time.Time      `json:"batchRead"`
+	Executed        *time.Time      `json:"executed"`
+}
+
 type Monitor struct {
 	sync.Mutex
-	txInfo    map[common.Hash]*txInfo
-	batchInfo map[common.Hash]*batchInfo
-	lastClear time.Time
+	txInfo        map[common.Hash]*txInfo
+	batchInfo     map[common.Hash]*batchInfo
+	prevTxInfo    map[common.Hash]*txInfo
+	prevBatchInfo map[common.Hash]*batchInfo
+	lastClear     time.Time
 }
 
 func NewMonitor() *Monitor {
 	return &Monitor{
-		txInfo:    make(map[common.Hash]*txInfo),
-		batchInfo: make(map[common.Hash]*batchInfo),
-		lastClear: time.Now(),
+		txInfo:        make(map[common.Hash]*txInfo),
+		batchInfo:     make(map[common.Hash]*batchInfo),
+		prevTxInfo:    make(map[common.Hash]*txInfo),
+		prevBatchInfo: make(map[common.Hash]*batchInfo),
+		lastClear:     time.Now(),
 	}
 }
 
 func (m *Monitor) maybeClear() {
-	// Clear periodically to avoid too much memory bloat
-	if time.Since(m.lastClear) > time.Hour {
+	// Rotate periodically to avoid too much memory bloat while still keeping
+	// recent entries available to lookups
+	if time.Since(m.lastClear) > retentionPeriod {
+		m.prevTxInfo = m.txInfo
+		m.prevBatchInfo = m.batchInfo
 		m.txInfo = make(map[common.Hash]*txInfo)
 		m.batchInfo = make(map[common.Hash]*batchInfo)
 		m.lastClear = time.Now()
 	}
 }
 
+func (m *Monitor) getTx(txHash common.Hash) (*txInfo, bool) {
+	if tx, ok := m.txInfo[txHash]; ok {
+		return tx, true
+	}
+	tx, ok := m.prevTxInfo[txHash]
+	return tx, ok
+}
+
+func (m *Monitor) getBatch(batchHash common.Hash) (*batchInfo, bool) {
+	if batch, ok := m.batchInfo[batchHash]; ok {
+		return batch, true
+	}
+	batch, ok := m.prevBatchInfo[batchHash]
+	return batch, ok
+}
+
 func (m *Monitor) GotTransactionFromUser(txHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 	m.maybeClear()
-	_, ok := m.txInfo[txHash]
+	_, ok := m.getTx(txHash)
 	if ok {
 		// Already got from user
 		return
@@ -87,22 +158,51 @@ func (m *Monitor) GotTransactionFromUser(txHash common.Hash) {
 	}
 }
 
+// Sequenced records that the sequencer added the transaction to the inbox,
+// which happens before the batch containing it is submitted to L1
+func (m *Monitor) Sequenced(txHash common.Hash) {
+	m.Lock()
+	defer m.Unlock()
+	m.maybeClear()
+	tx, ok := m.getTx(txHash)
+	if !ok || tx.batchedTime != nil {
+		return
+	}
+	currentTime := time.Now()
+	tx.batchedTime = &currentTime
+	observe(ReceivedToBatchedHistogram, tx.gotFromUserTime, currentTime)
+}
+
+// IncludedInBatch records the L1 transaction that submitted the batch
+// containing the transaction. If the transaction wasn't already sequenced,
+// this is also when it was batched.
 func (m *Monitor) IncludedInBatch(txHash common.Hash, batchHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 	m.maybeClear()
-	tx, ok := m.txInfo[txHash]
+	tx, ok := m.getTx(txHash)
 	if !ok {
 		return
 	}
 	tx.batch = &batchHash
+	if tx.batchedTime == nil {
+		currentTime := time.Now()
+		tx.batchedTime = &currentTime
+		observe(ReceivedToBatchedHistogram, tx.gotFromUserTime, currentTime)
+	}
+	batch, ok := m.getBatch(batchHash)
+	if !ok {
+		return
+	}
+	observe(BatchedToSubmittedHistogram, tx.batchedTime, *batch.submittedTime)
+	observe(ReceivedToSubmittedHistogram, tx.gotFromUserTime, *batch.submittedTime)
 }
 
 func (m *Monitor) SubmittedBatch(txHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 	m.maybeClear()
-	_, ok := m.batchInfo[txHash]
+	_, ok := m.getBatch(txHash)
 	if ok {
 		return
 	}
@@ -116,43 +216,50 @@ func (m *Monitor) BatchAccepted(txHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 	m.maybeClear()
-	batch, ok := m.batchInfo[txHash]
+	batch, ok := m.getBatch(txHash)
 	if !ok {
 		return
 	}
 	currentTime := time.Now()
 	batch.includedInBlockTime = &currentTime
+	observe(SubmittedToAcceptedHistogram, batch.submittedTime, currentTime)
 }
 
 func (m *Monitor) ReaderGotBatch(txHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 	m.maybeClear()
-	batch, ok := m.batchInfo[txHash]
+	batch, ok := m.getBatch(txHash)
 	if !ok {
 		return
 	}
 	currentTime := time.Now()
 	batch.readBatch = &currentTime
+	observe(AcceptedToReadHistogram, batch.includedInBlockTime, currentTime)
+	observe(SubmittedToReadHistogram, batch.submittedTime, currentTime)
 }
 
 func (m *Monitor) GotLog(txHash common.Hash) {
 	m.Lock()
 	defer m.Unlock()
 
-	tx, ok := m.txInfo[txHash]
-	if !ok {
+	tx, ok := m.getTx(txHash)
+	if !ok || tx.gotLogTime != nil {
 		return
 	}
+	currentTime := time.Now()
+	tx.gotLogTime = &currentTime
+	observe(ReceivedToLogHistogram, tx.gotFromUserTime, currentTime)
 
 	if !tx.LoggedAllValues() {
 		return
 	}
 
-	batch, ok := m.batchInfo[*tx.batch]
+	batch, ok := m.getBatch(*tx.batch)
 	if !ok {
 		return
 	}
+	observe(ReadToLogHistogram, batch.readBatch, currentTime)
 
 	if !batch.LoggedAllValues() {
 		return
@@ -161,9 +268,7 @@ func (m *Monitor) GotLog(txHash common.Hash) {
 	timeToSubmit := batch.submittedTime.Sub(*tx.gotFromUserTime)
 	timeFromSubmissionToInclusion := batch.includedInBlockTime.Sub(*batch.submittedTime)
 	timeFromSubmissionToReading := batch.readBatch.Sub(*batch.submittedTime)
-	timeFromReadingToLog := time.Now().Sub(*batch.readBatch)
-
-	delete(m.txInfo, txHash)
+	timeFromReadingToLog := currentTime.Sub(*batch.readBatch)
 
 	logger.Info().
 		Dur("timeToSubmit", timeToSubmit).
@@ -174,3 +279,32 @@ func (m *Monitor) GotLog(txHash common.Hash) {
 
 	m.maybeClear()
 }
+
+// GetTransactionLifecycle returns the recorded lifecycle of the given
+// transaction, or nil if this node hasn't seen it recently
+func (m *Monitor) GetTransactionLifecycle(txHash common.Hash) *TransactionLifecycle {
+	m.Lock()
+	defer m.Unlock()
+
+	tx, ok := m.getTx(txHash)
+	if !ok {
+		return nil
+	}
+	ret := &TransactionLifecycle{
+		Received:        tx.gotFromUserTime,
+		IncludedInBatch: tx.batchedTime,
+		Executed:        tx.gotLogTime,
+	}
+	if tx.batch == nil {
+		return ret
+	}
+	batchTxHash := tx.batch.ToEthHash()
+	ret.BatchTxHash = &batchTxHash
+	batch, ok := m.getBatch(*tx.batch)
+	if ok {
+		ret.BatchSubmitted = batch.submittedTime
+		ret.BatchAccepted = batch.includedInBlockTime
+		ret.BatchRead = batch.readBatch
+	}
+	return ret
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestTransactionLifecycle(t *testing.T) {
+	m := NewMonitor()
+	txHash := common.RandHash()
+	batchHash := common.RandHash()
+
+	if m.GetTransactionLifecycle(txHash) != nil {
+		t.Fatal("expected no lifecycle for unknown tx")
+	}
+
+	m.GotTransactionFromUser(txHash)
+	m.IncludedInBatch(txHash, batchHash)
+	m.SubmittedBatch(batchHash)
+	m.BatchAccepted(batchHash)
+	m.ReaderGotBatch(batchHash)
+	m.GotLog(txHash)
+
+	lifecycle := m.GetTransactionLifecycle(txHash)
+	if lifecycle == nil {
+		t.Fatal("expected lifecycle after tx was executed")
+	}
+	if lifecycle.Received == nil || lifecycle.IncludedInBatch == nil || lifecycle.BatchSubmitted == nil ||
+		lifecycle.BatchAccepted == nil || lifecycle.BatchRead == nil || lifecycle.Executed == nil {
+		t.Fatal("expected all stages to be recorded", lifecycle)
+	}
+	if *lifecycle.BatchTxHash != batchHash.ToEthHash() {
+		t.Error("wrong batch hash")
+	}
+	if lifecycle.Executed.Before(*lifecycle.Received) {
+		t.Error("executed before received")
+	}
+
+	// Entries survive one rotation but not two
+	m.lastClear = time.Now().Add(-2 * retentionPeriod)
+	m.GotTransactionFromUser(common.RandHash())
+	if m.GetTransactionLifecycle(txHash) == nil {
+		t.Error("expected lifecycle to survive first rotation")
+	}
+	m.lastClear = time.Now().Add(-2 * retentionPeriod)
+	m.GotTransactionFromUser(common.RandHash())
+	if m.GetTransactionLifecycle(txHash) != nil {
+		t.Error("expected lifecycle to be dropped after second rotation")
+	}
+}
+
+func TestSequencerTransactionLifecycle(t *testing.T) {
+	m := NewMonitor()
+	txHash := common.RandHash()
+	batchHash := common.RandHash()
+
+	// A sequencer executes transactions before posting their batch
+	m.GotTransactionFromUser(txHash)
+	m.Sequenced(txHash)
+	m.GotLog(txHash)
+	lifecycle := m.GetTransactionLifecycle(txHash)
+	if lifecycle.IncludedInBatch == nil || lifecycle.Executed == nil || lifecycle.BatchTxHash != nil {
+		t.Fatal("expected sequenced and executed tx without batch", lifecycle)
+	}
+	sequencedTime := *lifecycle.IncludedInBatch
+
+	m.SubmittedBatch(batchHash)
+	m.IncludedInBatch(txHash, batchHash)
+	m.BatchAccepted(batchHash)
+	m.ReaderGotBatch(batchHash)
+
+	lifecycle = m.GetTransactionLifecycle(txHash)
+	if lifecycle.BatchSubmitted == nil || lifecycle.BatchAccepted == nil || lifecycle.BatchRead == nil {
+		t.Fatal("expected all batch stages to be recorded", lifecycle)
+	}
+	if *lifecycle.BatchTxHash != batchHash.ToEthHash() {
+		t.Error("wrong batch hash")
+	}
+	if !lifecycle.IncludedInBatch.Equal(sequencedTime) {
+		t.Error("batch inclusion overwrote sequencing time")
+	}
+}
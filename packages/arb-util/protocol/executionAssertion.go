@@ -73,10 +73,10 @@ func parseSends(sendData []byte, sendCount uint64) ([][]byte, error) {
 }
 
 func BytesArrayToVals(data []byte, valCount uint64) ([]value.Value, error) {
-	rd := bytes.NewReader(data)
+	dec := value.NewDecoder(bytes.NewReader(data), value.DefaultDecoderConfig)
 	vals := make([]value.Value, 0, valCount)
 	for i := uint64(0); i < valCount; i++ {
-		val, err := value.UnmarshalValue(rd)
+		val, err := dec.Decode()
 		if err != nil {
 			return nil, err
 		}
@@ -5,10 +5,37 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 )
 
+// BufferLeafSize is the number of bytes in each leaf of a buffer's merkle tree
+const BufferLeafSize = 32
+
+// bufferHashPrefix distinguishes buffer hashes from the hashes of other values
+const bufferHashPrefix = 123
+
+// zeroBufferHashes[i] is the root of an all zero buffer tree of depth i
+var zeroBufferHashes [64]common.Hash
+
+func init() {
+	zeroBufferHashes[0] = hashing.SoliditySHA3(make([]byte, BufferLeafSize))
+	for i := 1; i < len(zeroBufferHashes); i++ {
+		zeroBufferHashes[i] = hashing.SoliditySHA3(zeroBufferHashes[i-1][:], zeroBufferHashes[i-1][:])
+	}
+}
+
+// Buffer is a byte array which is hashed as a merkle tree of 32 byte leaves.
+// The tree used is the smallest one which holds the data without its
+// trailing zeros, so trailing zeros never affect the hash.
 type Buffer struct {
 	data []byte
+
+	// Lazily computed merkle root, since buffers are immutable
+	root atomic.Value
 }
 
 func NewBufferFromReader(rd io.Reader) (*Buffer, error) {
@@ -51,3 +78,115 @@ func (b *Buffer) String() string {
 func (b *Buffer) Data() []byte {
 	return b.data
 }
+
+// Depth returns the depth of the buffer's merkle tree
+func (b *Buffer) Depth() uint64 {
+	length := uint64(len(b.trimmedData()))
+	depth := uint64(0)
+	for uint64(BufferLeafSize)<<depth < length {
+		depth++
+	}
+	return depth
+}
+
+// RootHash returns the root of the buffer's merkle tree
+func (b *Buffer) RootHash() common.Hash {
+	if cached, ok := b.root.Load().(common.Hash); ok {
+		return cached
+	}
+	root := subtreeHash(b.trimmedData(), 0, b.Depth())
+	b.root.Store(root)
+	return root
+}
+
+func (b *Buffer) Hash() common.Hash {
+	return BufferHash(b.RootHash())
+}
+
+// BufferHash returns the value hash of a buffer with the given merkle root
+func BufferHash(root common.Hash) common.Hash {
+	return hashing.SoliditySHA3(
+		hashing.Uint256(big.NewInt(bufferHashPrefix)),
+		hashing.Bytes32(root),
+	)
+}
+
+// MakeProof returns a merkle proof of the leaf containing loc. The proof is
+// the leaf's contents followed by the sibling hashes from the leaf up to the
+// root. If loc is past the end of the tree, the proof is instead for loc
+// modulo the tree's size, which proves the size of the buffer.
+func (b *Buffer) MakeProof(loc uint64) []byte {
+	data := b.trimmedData()
+	depth := b.Depth()
+	if size := uint64(BufferLeafSize) << depth; loc >= size {
+		loc %= size
+	}
+	siblings := make([]common.Hash, 0, depth)
+	offset := uint64(0)
+	for d := depth; d > 0; d-- {
+		half := uint64(BufferLeafSize) << (d - 1)
+		if loc >= offset+half {
+			siblings = append(siblings, subtreeHash(data, offset, d-1))
+			offset += half
+		} else {
+			siblings = append(siblings, subtreeHash(data, offset+half, d-1))
+		}
+	}
+	proof := make([]byte, 0, BufferLeafSize+32*len(siblings))
+	proof = append(proof, leafData(data, offset)...)
+	for i := len(siblings) - 1; i >= 0; i-- {
+		proof = append(proof, siblings[i][:]...)
+	}
+	return proof
+}
+
+// MakeNormalizationProof returns the depth of the buffer's tree along with
+// the roots of its two subtrees, or the buffer's own root if it's a single
+// leaf
+func (b *Buffer) MakeNormalizationProof() []byte {
+	data := b.trimmedData()
+	depth := b.Depth()
+	var left, right common.Hash
+	if depth > 0 {
+		left = subtreeHash(data, 0, depth-1)
+		right = subtreeHash(data, uint64(BufferLeafSize)<<(depth-1), depth-1)
+	} else {
+		left = b.RootHash()
+	}
+	proof := make([]byte, 0, 96)
+	proof = append(proof, hashing.Uint256(new(big.Int).SetUint64(depth))...)
+	proof = append(proof, left[:]...)
+	proof = append(proof, right[:]...)
+	return proof
+}
+
+func (b *Buffer) trimmedData() []byte {
+	end := len(b.data)
+	for end > 0 && b.data[end-1] == 0 {
+		end--
+	}
+	return b.data[:end]
+}
+
+func leafData(data []byte, offset uint64) []byte {
+	leaf := make([]byte, BufferLeafSize)
+	if offset < uint64(len(data)) {
+		copy(leaf, data[offset:])
+	}
+	return leaf
+}
+
+// subtreeHash returns the root of the subtree of the given depth which
+// starts at offset
+func subtreeHash(data []byte, offset uint64, depth uint64) common.Hash {
+	if offset >= uint64(len(data)) {
+		return zeroBufferHashes[depth]
+	}
+	if depth == 0 {
+		return hashing.SoliditySHA3(leafData(data, offset))
+	}
+	half := uint64(BufferLeafSize) << (depth - 1)
+	left := subtreeHash(data, offset, depth-1)
+	right := subtreeHash(data, offset+half, depth-1)
+	return hashing.SoliditySHA3(left[:], right[:])
+}
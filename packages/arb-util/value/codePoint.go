@@ -23,6 +23,7 @@ import (
 	"io"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 )
 
 type Opcode uint8
@@ -151,6 +152,24 @@ func (cv CodePointValue) Equal(val Value) bool {
 	return cv.NextHash == o.NextHash && cv.Op.Equals(o.Op)
 }
 
+func (cv CodePointValue) Hash() common.Hash {
+	switch op := cv.Op.(type) {
+	case ImmediateOperation:
+		return hashing.SoliditySHA3(
+			hashing.Uint8(TypeCodeCodePoint),
+			hashing.Uint8(uint8(op.Op)),
+			hashing.Bytes32(op.Val.Hash()),
+			hashing.Bytes32(cv.NextHash),
+		)
+	default:
+		return hashing.SoliditySHA3(
+			hashing.Uint8(TypeCodeCodePoint),
+			hashing.Uint8(uint8(cv.Op.GetOp())),
+			hashing.Bytes32(cv.NextHash),
+		)
+	}
+}
+
 func (cv CodePointValue) Size() int64 {
 	return 1
 }
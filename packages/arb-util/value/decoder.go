@@ -0,0 +1,460 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// DecoderConfig limits the resources used to decode a single value. A zero
+// limit means no limit.
+type DecoderConfig struct {
+	// MaxDepth is the maximum nesting of tuples and codepoint immediates
+	MaxDepth int
+	// MaxSize is the maximum number of bytes in the serialized value
+	MaxSize int64
+	// MaxBufferLength is the maximum length of each buffer in the value
+	MaxBufferLength uint64
+}
+
+// DefaultDecoderConfig is large enough for any value produced by ArbOS. Log
+// stacks nest one level per EVM log, so the depth limit is high.
+var DefaultDecoderConfig = DecoderConfig{
+	MaxDepth:        1 << 20,
+	MaxSize:         1 << 28,
+	MaxBufferLength: 1 << 25,
+}
+
+type DepthLimitError struct {
+	Limit int
+}
+
+func (e DepthLimitError) Error() string {
+	return fmt.Sprintf("value nested more than %v levels deep", e.Limit)
+}
+
+type SizeLimitError struct {
+	Limit int64
+}
+
+func (e SizeLimitError) Error() string {
+	return fmt.Sprintf("value larger than %v bytes", e.Limit)
+}
+
+type BufferLengthError struct {
+	Length uint64
+	Limit  uint64
+}
+
+func (e BufferLengthError) Error() string {
+	return fmt.Sprintf("buffer of length %v is longer than the limit of %v", e.Length, e.Limit)
+}
+
+// Visitor receives the parts of a value as it is decoded, so that values can
+// be consumed without building the whole tree. Returning an error stops the
+// decoding.
+type Visitor interface {
+	VisitInt(val IntValue) error
+	VisitBuffer(data []byte) error
+	VisitHashPreImage(val HashPreImage) error
+	VisitCodePoint(val CodePointValue) error
+	VisitCodePointStub(val CodePointStub) error
+	// VisitTuple is called before the items of a tuple are decoded, and
+	// returns the visitor for its items, which must not be nil
+	VisitTuple(size int) (TupleVisitor, error)
+}
+
+// TupleVisitor receives the items of a tuple in order
+type TupleVisitor interface {
+	// Item returns the visitor for the item at index i, or nil to skip it
+	Item(i int) (Visitor, error)
+	// End is called after every item has been visited
+	End() error
+}
+
+// Decoder reads values from a stream within the limits of its config. Unlike
+// UnmarshalValue, it decodes nested tuples without recursion, so deep values
+// can't exhaust the stack.
+type Decoder struct {
+	r         io.Reader
+	config    DecoderConfig
+	bytesRead int64
+}
+
+func NewDecoder(r io.Reader, config DecoderConfig) *Decoder {
+	return &Decoder{r: r, config: config}
+}
+
+// UnmarshalValueWithConfig decodes a single value from r within the limits of
+// config
+func UnmarshalValueWithConfig(r io.Reader, config DecoderConfig) (Value, error) {
+	return NewDecoder(r, config).Decode()
+}
+
+// Decode reads the next value from the stream
+func (d *Decoder) Decode() (Value, error) {
+	var ret Value
+	err := d.Visit(NewValueBuilder(func(val Value) error {
+		ret = val
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// MarshalValue writes val to w in the serialization read by Decoder
+func MarshalValue(val Value, w io.Writer) error {
+	if _, err := w.Write([]byte{val.TypeCode()}); err != nil {
+		return err
+	}
+	switch val := val.(type) {
+	case IntValue:
+		return val.Marshal(w)
+	case *TupleValue:
+		for _, item := range val.Contents() {
+			if err := MarshalValue(item, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Buffer:
+		if err := binary.Write(w, binary.BigEndian, uint64(len(val.Data()))); err != nil {
+			return err
+		}
+		_, err := w.Write(val.Data())
+		return err
+	case HashPreImage:
+		if _, err := w.Write(val.hashImage[:]); err != nil {
+			return err
+		}
+		return NewInt64Value(val.size).Marshal(w)
+	case CodePointStub:
+		return val.Marshal(w)
+	case CodePointValue:
+		if op, ok := val.Op.(ImmediateOperation); ok {
+			if _, err := w.Write([]byte{1, byte(op.Op)}); err != nil {
+				return err
+			}
+			if err := MarshalValue(op.Val, w); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte{0, byte(val.Op.GetOp())}); err != nil {
+			return err
+		}
+		_, err := w.Write(val.NextHash[:])
+		return err
+	default:
+		return fmt.Errorf("can't marshal value of type %T", val)
+	}
+}
+
+type decodeFrame struct {
+	tuple TupleVisitor
+	size  int
+	next  int
+}
+
+// Visit reads the next value from the stream, passing its parts to v
+func (d *Decoder) Visit(v Visitor) error {
+	d.bytesRead = 0
+	var frames []decodeFrame
+	for {
+		tuple, size, err := d.visitValue(v)
+		if err != nil {
+			return err
+		}
+		if tuple != nil {
+			if d.config.MaxDepth > 0 && len(frames) >= d.config.MaxDepth {
+				return DepthLimitError{Limit: d.config.MaxDepth}
+			}
+			frames = append(frames, decodeFrame{tuple: tuple, size: size})
+		}
+
+		// Find the next item to decode, ending any finished tuples
+		v = nil
+		for v == nil {
+			if len(frames) == 0 {
+				return nil
+			}
+			top := &frames[len(frames)-1]
+			if top.next < top.size {
+				v, err = top.tuple.Item(top.next)
+				if err != nil {
+					return err
+				}
+				if v == nil {
+					v = Discard
+				}
+				top.next++
+				continue
+			}
+			if err := top.tuple.End(); err != nil {
+				return err
+			}
+			frames = frames[:len(frames)-1]
+		}
+	}
+}
+
+// visitValue reads a value from the stream. Leaf values are passed to v
+// immediately, while for tuples and codepoints with immediates the visitor
+// for their contents is returned.
+func (d *Decoder) visitValue(v Visitor) (TupleVisitor, int, error) {
+	var tipe [1]byte
+	if err := d.readFull(tipe[:]); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case tipe[0] == TypeCodeInt:
+		val, err := d.readInt()
+		if err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, v.VisitInt(val)
+	case tipe[0] == TypeCodeCodePoint:
+		return d.visitCodePoint(v)
+	case tipe[0] == TypeCodeHashPreImage:
+		var hash common.Hash
+		if err := d.readFull(hash[:]); err != nil {
+			return nil, 0, err
+		}
+		size, err := d.readInt()
+		if err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, v.VisitHashPreImage(NewPreImage(hash, size.BigInt().Int64()))
+	case tipe[0] <= TypeCodeTuple+MaxTupleSize:
+		size := int(tipe[0] - TypeCodeTuple)
+		tuple, err := v.VisitTuple(size)
+		return tuple, size, err
+	case tipe[0] == TypeCodeBuffer:
+		data, err := d.readBuffer()
+		if err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, v.VisitBuffer(data)
+	case tipe[0] == TypeCodeCodePointStub:
+		var data [8 + 32]byte
+		if err := d.readFull(data[:]); err != nil {
+			return nil, 0, err
+		}
+		stub := CodePointStub{PC: binary.BigEndian.Uint64(data[:8])}
+		copy(stub.hash[:], data[8:])
+		return nil, 0, v.VisitCodePointStub(stub)
+	default:
+		return nil, 0, UnmarshalError{"Unmarshal: invalid value type"}
+	}
+}
+
+func (d *Decoder) visitCodePoint(v Visitor) (TupleVisitor, int, error) {
+	var header [2]byte
+	if err := d.readFull(header[:]); err != nil {
+		return nil, 0, err
+	}
+	immediateCount, op := header[0], Opcode(header[1])
+	switch immediateCount {
+	case 0:
+		var nextHash common.Hash
+		if err := d.readFull(nextHash[:]); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, v.VisitCodePoint(CodePointValue{Op: BasicOperation{Op: op}, NextHash: nextHash})
+	case 1:
+		// The immediate is decoded like the single item of a tuple, and the
+		// next hash follows it
+		return &immediateFrame{d: d, op: op, visitor: v}, 1, nil
+	default:
+		return nil, 0, UnmarshalError{"immediate count must be 0 or 1"}
+	}
+}
+
+type immediateFrame struct {
+	d       *Decoder
+	op      Opcode
+	imm     Value
+	visitor Visitor
+}
+
+func (f *immediateFrame) Item(int) (Visitor, error) {
+	return NewValueBuilder(func(val Value) error {
+		f.imm = val
+		return nil
+	}), nil
+}
+
+func (f *immediateFrame) End() error {
+	var nextHash common.Hash
+	if err := f.d.readFull(nextHash[:]); err != nil {
+		return err
+	}
+	return f.visitor.VisitCodePoint(CodePointValue{
+		Op:       ImmediateOperation{Op: f.op, Val: f.imm},
+		NextHash: nextHash,
+	})
+}
+
+func (d *Decoder) readFull(data []byte) error {
+	if err := d.reserve(uint64(len(data))); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		if err == io.EOF && d.bytesRead > int64(len(data)) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// reserve counts size bytes against the size limit before they're read, so
+// that nothing is allocated for values over the limit
+func (d *Decoder) reserve(size uint64) error {
+	if d.config.MaxSize > 0 && (size > uint64(d.config.MaxSize) || d.bytesRead+int64(size) > d.config.MaxSize) {
+		return SizeLimitError{Limit: d.config.MaxSize}
+	}
+	d.bytesRead += int64(size)
+	return nil
+}
+
+func (d *Decoder) readInt() (IntValue, error) {
+	var data [32]byte
+	if err := d.readFull(data[:]); err != nil {
+		return IntValue{}, err
+	}
+	return NewIntValue(new(big.Int).SetBytes(data[:])), nil
+}
+
+func (d *Decoder) readBuffer() ([]byte, error) {
+	var lengthData [8]byte
+	if err := d.readFull(lengthData[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint64(lengthData[:])
+	if d.config.MaxBufferLength > 0 && length > d.config.MaxBufferLength {
+		return nil, BufferLengthError{Length: length, Limit: d.config.MaxBufferLength}
+	}
+	if err := d.reserve(length); err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+type valueBuilder struct {
+	done func(Value) error
+}
+
+// NewValueBuilder returns a visitor which builds the value it visits and
+// passes it to done
+func NewValueBuilder(done func(Value) error) Visitor {
+	return valueBuilder{done: done}
+}
+
+func (b valueBuilder) VisitInt(val IntValue) error {
+	return b.done(val)
+}
+
+func (b valueBuilder) VisitBuffer(data []byte) error {
+	return b.done(NewBuffer(data))
+}
+
+func (b valueBuilder) VisitHashPreImage(val HashPreImage) error {
+	return b.done(val)
+}
+
+func (b valueBuilder) VisitCodePoint(val CodePointValue) error {
+	return b.done(val)
+}
+
+func (b valueBuilder) VisitCodePointStub(val CodePointStub) error {
+	return b.done(val)
+}
+
+func (b valueBuilder) VisitTuple(size int) (TupleVisitor, error) {
+	return &tupleBuilder{items: make([]Value, size), done: b.done}, nil
+}
+
+type tupleBuilder struct {
+	items []Value
+	done  func(Value) error
+}
+
+func (t *tupleBuilder) Item(i int) (Visitor, error) {
+	return NewValueBuilder(func(val Value) error {
+		t.items[i] = val
+		return nil
+	}), nil
+}
+
+func (t *tupleBuilder) End() error {
+	tup, err := NewTupleFromSlice(t.items)
+	if err != nil {
+		return err
+	}
+	return t.done(tup)
+}
+
+type discardVisitor struct{}
+
+// Discard is a visitor which ignores the value it visits. The value is still
+// read and checked against the decoder's limits.
+var Discard Visitor = discardVisitor{}
+
+func (discardVisitor) VisitInt(IntValue) error {
+	return nil
+}
+
+func (discardVisitor) VisitBuffer([]byte) error {
+	return nil
+}
+
+func (discardVisitor) VisitHashPreImage(HashPreImage) error {
+	return nil
+}
+
+func (discardVisitor) VisitCodePoint(CodePointValue) error {
+	return nil
+}
+
+func (discardVisitor) VisitCodePointStub(CodePointStub) error {
+	return nil
+}
+
+func (discardVisitor) VisitTuple(int) (TupleVisitor, error) {
+	return discardVisitor{}, nil
+}
+
+func (discardVisitor) Item(int) (Visitor, error) {
+	return discardVisitor{}, nil
+}
+
+func (discardVisitor) End() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func marshalled(val Value) []byte {
+	var buf bytes.Buffer
+	_ = MarshalValue(val, &buf)
+	return buf.Bytes()
+}
+
+func nestedStack(depth int) *TupleValue {
+	stack := NewEmptyTuple()
+	for i := 0; i < depth; i++ {
+		stack = NewTuple2(NewInt64Value(int64(i)), stack)
+	}
+	return stack
+}
+
+func TestDecoderMatchesUnmarshal(t *testing.T) {
+	imm := NewTuple2(NewBuffer([]byte{1, 2, 3}), NewInt64Value(7))
+	val, err := NewTupleFromSlice([]Value{
+		NewInt64Value(5),
+		NewEmptyTuple(),
+		CodePointValue{Op: ImmediateOperation{Op: 0x34, Val: imm}},
+		CodePointValue{Op: BasicOperation{Op: 0x01}},
+		nestedStack(10),
+		NewPreImage(common.Hash{1}, 3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := marshalled(val)
+
+	expected, err := UnmarshalValue(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalValueWithConfig(bytes.NewReader(data), DefaultDecoderConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(expected) || decoded.Hash() != val.Hash() {
+		t.Errorf("decoded %v but expected %v", decoded, expected)
+	}
+}
+
+func TestDecoderStream(t *testing.T) {
+	var data []byte
+	for i := 0; i < 3; i++ {
+		data = append(data, marshalled(NewInt64Value(int64(i)))...)
+	}
+	dec := NewDecoder(bytes.NewReader(data), DecoderConfig{MaxSize: 33})
+	for i := 0; i < 3; i++ {
+		val, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !val.Equal(NewInt64Value(int64(i))) {
+			t.Error("wrong value", val)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Error("expected EOF after last value but got", err)
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	hugeBuffer := []byte{TypeCodeBuffer, 0, 0, 1, 0, 0, 0, 0, 0}
+	truncated := marshalled(nestedStack(3))
+	truncated = truncated[:len(truncated)-5]
+
+	tests := []struct {
+		name   string
+		data   []byte
+		config DecoderConfig
+		check  func(err error) bool
+	}{
+		{"depth", marshalled(nestedStack(100)), DecoderConfig{MaxDepth: 50}, func(err error) bool {
+			_, ok := err.(DepthLimitError)
+			return ok
+		}},
+		{"immediate depth", marshalled(CodePointValue{Op: ImmediateOperation{Val: nestedStack(2)}}), DecoderConfig{MaxDepth: 2}, func(err error) bool {
+			_, ok := err.(DepthLimitError)
+			return ok
+		}},
+		{"size", marshalled(nestedStack(100)), DecoderConfig{MaxSize: 1000}, func(err error) bool {
+			_, ok := err.(SizeLimitError)
+			return ok
+		}},
+		{"buffer length", hugeBuffer, DefaultDecoderConfig, func(err error) bool {
+			_, ok := err.(BufferLengthError)
+			return ok
+		}},
+		{"buffer size", hugeBuffer, DecoderConfig{MaxSize: 1 << 20}, func(err error) bool {
+			_, ok := err.(SizeLimitError)
+			return ok
+		}},
+		{"truncated", truncated, DefaultDecoderConfig, func(err error) bool {
+			return err == io.ErrUnexpectedEOF
+		}},
+		{"type", []byte{50}, DefaultDecoderConfig, func(err error) bool {
+			_, ok := err.(UnmarshalError)
+			return ok
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnmarshalValueWithConfig(bytes.NewReader(test.data), test.config)
+			if !test.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+
+	if _, err := UnmarshalValueWithConfig(bytes.NewReader(marshalled(nestedStack(100))), DecoderConfig{MaxDepth: 101}); err != nil {
+		t.Error("value within limit failed to decode:", err)
+	}
+}
+
+type intCounter struct {
+	discardVisitor
+	count *int
+}
+
+func (c intCounter) VisitInt(IntValue) error {
+	*c.count++
+	return nil
+}
+
+func (c intCounter) VisitTuple(int) (TupleVisitor, error) {
+	return c, nil
+}
+
+func (c intCounter) Item(i int) (Visitor, error) {
+	if i == 0 {
+		return c, nil
+	}
+	// Skip everything but the first item of each tuple
+	return nil, nil
+}
+
+func TestVisitor(t *testing.T) {
+	val := NewTuple2(NewTuple2(NewInt64Value(1), NewInt64Value(2)), nestedStack(5))
+	count := 0
+	if err := NewDecoder(bytes.NewReader(marshalled(val)), DefaultDecoderConfig).Visit(intCounter{count: &count}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("visited %v ints instead of 1", count)
+	}
+}
@@ -18,8 +18,11 @@ package value
 
 import (
 	"fmt"
-	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"io"
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 )
 
 type HashPreImage struct {
@@ -55,6 +58,14 @@ func (hp HashPreImage) GetInnerHash() common.Hash {
 	return hp.hashImage
 }
 
+func (hp HashPreImage) Hash() common.Hash {
+	return hashing.SoliditySHA3(
+		hashing.Uint8(TypeCodeTuple),
+		hashing.Bytes32(hp.hashImage),
+		hashing.Uint256(big.NewInt(hp.size)),
+	)
+}
+
 func (hp HashPreImage) TypeCode() uint8 {
 	return TypeCodeHashPreImage
 }
@@ -19,8 +19,13 @@ package value
 import (
 	"bytes"
 	"fmt"
-	"github.com/pkg/errors"
 	"io"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 )
 
 const MaxTupleSize = 8
@@ -29,17 +34,20 @@ type TupleValue struct {
 	contentsArr [MaxTupleSize]Value
 	itemCount   int8
 	size        int64
+
+	// Lazily computed HashPreImage, since tuples are immutable
+	preImage atomic.Value
 }
 
 func NewEmptyTuple() *TupleValue {
-	return &TupleValue{[MaxTupleSize]Value{}, 0, 1}
+	return &TupleValue{size: 1}
 }
 
 func NewTupleOfSizeWithContents(contents [MaxTupleSize]Value, size int8) (*TupleValue, error) {
 	if !IsValidTupleSizeI64(int64(size)) {
 		return nil, errors.New("requested empty tuple size is too big")
 	}
-	ret := &TupleValue{contents, size, 0}
+	ret := &TupleValue{contentsArr: contents, itemCount: size}
 	ret.size = ret.internalSize()
 	return ret, nil
 }
@@ -56,7 +64,7 @@ func NewTupleFromSlice(slice []Value) (*TupleValue, error) {
 }
 
 func NewTuple2(value1 Value, value2 Value) *TupleValue {
-	ret := &TupleValue{[MaxTupleSize]Value{value1, value2}, 2, 0}
+	ret := &TupleValue{contentsArr: [MaxTupleSize]Value{value1, value2}, itemCount: 2}
 	ret.size = ret.internalSize()
 	return ret
 }
@@ -125,6 +133,27 @@ func (tv *TupleValue) Size() int64 {
 	return tv.size
 }
 
+// GetPreImage returns the hash of the tuple's contents along with its size,
+// which is all that's needed to compute the tuple's hash
+func (tv *TupleValue) GetPreImage() HashPreImage {
+	if cached, ok := tv.preImage.Load().(HashPreImage); ok {
+		return cached
+	}
+	data := make([]byte, 0, 1+32*int(tv.itemCount))
+	data = append(data, byte(tv.itemCount))
+	for _, v := range tv.Contents() {
+		h := v.Hash()
+		data = append(data, h[:]...)
+	}
+	preImage := NewPreImage(hashing.SoliditySHA3(data), tv.size)
+	tv.preImage.Store(preImage)
+	return preImage
+}
+
+func (tv *TupleValue) Hash() common.Hash {
+	return tv.GetPreImage().Hash()
+}
+
 func (tv *TupleValue) String() string {
 	var buf bytes.Buffer
 	buf.WriteString("Tuple(")